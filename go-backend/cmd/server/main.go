@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/GezzyDax/timelith/go-backend/internal/api"
 	"github.com/GezzyDax/timelith/go-backend/internal/config"
@@ -38,8 +39,9 @@ func main() {
 		fmt.Printf("⚠️  Configuration not fully loaded: %v\n", err)
 		fmt.Println("📋 Running in setup mode...")
 		cfg = &config.Config{
-			ServerPort:  "8080",
-			Environment: "production",
+			ServerPort:      "8080",
+			Environment:     "production",
+			ShutdownTimeout: config.DefaultShutdownTimeout,
 		}
 	}
 
@@ -127,24 +129,33 @@ func main() {
 		if err != nil {
 			logger.Log.Warn("Failed to initialize session manager", zap.Error(err))
 		} else {
+			sessionManager.SetPoolProxyLookup(db.FindAccountPoolProxy)
 			logger.Log.Info("Telegram session manager initialized")
-			defer sessionManager.Close()
 		}
 	}
 
-	// Setup API router with settings service
-	app := api.SetupRouter(cfg, db, settingsService, sessionManager)
-
 	// Initialize other services only if setup is complete and session manager is ready
 	var sched *scheduler.Scheduler
 	if sessionManager != nil {
-		sched = scheduler.NewScheduler(db, sessionManager)
+		queue, err := scheduler.NewQueue(cfg, db)
+		if err != nil {
+			logger.Log.Fatal("Failed to initialize dispatcher queue",
+				zap.String("backend", cfg.QueueBackend),
+				zap.Error(err))
+		}
+		logger.Log.Info("Dispatcher queue initialized", zap.String("backend", cfg.QueueBackend))
+		sched = scheduler.NewScheduler(db, sessionManager, queue)
+	}
+
+	// Setup API router with settings service and scheduler so schedule changes apply immediately
+	app := api.SetupRouter(cfg, db, settingsService, sessionManager, sched)
+
+	if sched != nil {
 		ctx := context.Background()
 
 		if err := sched.Start(ctx); err != nil {
 			logger.Log.Error("Failed to start scheduler", zap.Error(err))
 		} else {
-			defer sched.Stop()
 			logger.Log.Info("Scheduler started")
 		}
 	}
@@ -164,11 +175,32 @@ func main() {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 
-	logger.Log.Info("Shutting down server...")
+	logger.Log.Info("Shutting down server...",
+		zap.Duration("timeout", cfg.ShutdownTimeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
 
-	if err := app.Shutdown(); err != nil {
+	// Drain sending first; the API keeps answering health checks meanwhile and refuses new runs
+	if sched != nil {
+		if err := sched.Shutdown(shutdownCtx); err != nil {
+			logger.Log.Warn("Scheduler shutdown incomplete", zap.Error(err))
+		} else {
+			logger.Log.Info("Scheduler stopped")
+		}
+	}
+
+	// Requests in flight get a few seconds of their own, even when draining used up the timeout
+	serverCtx, cancelServer := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelServer()
+	if err := app.ShutdownWithContext(serverCtx); err != nil {
 		logger.Log.Error("Server shutdown error", zap.Error(err))
 	}
 
+	// Handlers may still use Telegram clients, so they go after the HTTP server
+	if sessionManager != nil {
+		sessionManager.Close()
+	}
+
 	logger.Log.Info("Server shutdown complete")
 }
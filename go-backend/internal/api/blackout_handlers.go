@@ -0,0 +1,194 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/GezzyDax/timelith/go-backend/internal/scheduler"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// BlackoutWindowRequest creates or replaces a blackout window
+type BlackoutWindowRequest struct {
+	Name       string          `json:"name"`
+	Scope      string          `json:"scope"`
+	ScheduleID *uuid.UUID      `json:"schedule_id"`
+	ChannelID  *uuid.UUID      `json:"channel_id"`
+	Kind       string          `json:"kind"`
+	Weekdays   []int           `json:"weekdays"`
+	StartTime  string          `json:"start_time"`
+	EndTime    string          `json:"end_time"`
+	Timezone   string          `json:"timezone"`
+	StartsAt   models.NullTime `json:"starts_at"`
+	EndsAt     models.NullTime `json:"ends_at"`
+	Policy     string          `json:"policy"`
+}
+
+// toWindow builds a window from the request. Scope defaults to global, policy to skip and
+// kind to absolute when starts_at is given, recurring otherwise.
+func (r *BlackoutWindowRequest) toWindow() *models.BlackoutWindow {
+	window := &models.BlackoutWindow{
+		Name:       r.Name,
+		Scope:      r.Scope,
+		ScheduleID: r.ScheduleID,
+		ChannelID:  r.ChannelID,
+		Kind:       r.Kind,
+		Weekdays:   models.IntList(r.Weekdays),
+		Timezone:   r.Timezone,
+		StartsAt:   r.StartsAt,
+		EndsAt:     r.EndsAt,
+		Policy:     r.Policy,
+	}
+	if window.Scope == "" {
+		window.Scope = "global"
+	}
+	if window.Policy == "" {
+		window.Policy = "skip"
+	}
+	if window.Timezone == "" {
+		window.Timezone = "UTC"
+	}
+	if window.Weekdays == nil {
+		window.Weekdays = models.IntList{}
+	}
+	if window.Kind == "" {
+		window.Kind = "recurring"
+		if r.StartsAt.Valid {
+			window.Kind = "absolute"
+		}
+	}
+	if r.StartTime != "" {
+		window.StartTime = models.NewNullString(r.StartTime)
+	}
+	if r.EndTime != "" {
+		window.EndTime = models.NewNullString(r.EndTime)
+	}
+	return window
+}
+
+// validateBlackoutWindow checks the window and that the schedule or channel it is attached to exists
+func (h *Handler) validateBlackoutWindow(window *models.BlackoutWindow) error {
+	if err := scheduler.ValidateBlackoutWindow(window); err != nil {
+		return err
+	}
+	if window.ScheduleID != nil {
+		if _, err := h.db.GetSchedule(*window.ScheduleID); err != nil {
+			return fmt.Errorf("schedule %s not found", window.ScheduleID)
+		}
+	}
+	if window.ChannelID != nil {
+		if _, err := h.db.GetChannel(*window.ChannelID); err != nil {
+			return fmt.Errorf("channel %s not found", window.ChannelID)
+		}
+	}
+	return nil
+}
+
+// ListBlackoutWindows returns blackout windows, optionally filtered by scope, schedule_id or channel_id
+func (h *Handler) ListBlackoutWindows(c *fiber.Ctx) error {
+	windows, err := h.db.ListBlackoutWindows()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	scope := c.Query("scope")
+	scheduleID := c.Query("schedule_id")
+	channelID := c.Query("channel_id")
+
+	filtered := make([]models.BlackoutWindow, 0, len(windows))
+	for _, window := range windows {
+		if scope != "" && window.Scope != scope {
+			continue
+		}
+		if scheduleID != "" && (window.ScheduleID == nil || window.ScheduleID.String() != scheduleID) {
+			continue
+		}
+		if channelID != "" && (window.ChannelID == nil || window.ChannelID.String() != channelID) {
+			continue
+		}
+		filtered = append(filtered, window)
+	}
+
+	return c.JSON(filtered)
+}
+
+func (h *Handler) GetBlackoutWindow(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	window, err := h.db.GetBlackoutWindow(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Blackout window not found"})
+	}
+
+	return c.JSON(window)
+}
+
+func (h *Handler) CreateBlackoutWindow(c *fiber.Ctx) error {
+	var req BlackoutWindowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	window := req.toWindow()
+	if err := h.validateBlackoutWindow(window); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.db.CreateBlackoutWindow(window); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(window)
+}
+
+func (h *Handler) UpdateBlackoutWindow(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	existing, err := h.db.GetBlackoutWindow(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Blackout window not found"})
+	}
+
+	var req BlackoutWindowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	window := req.toWindow()
+	window.ID = existing.ID
+	window.CreatedAt = existing.CreatedAt
+	if err := h.validateBlackoutWindow(window); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.db.UpdateBlackoutWindow(window); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	updated, err := h.db.GetBlackoutWindow(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(updated)
+}
+
+func (h *Handler) DeleteBlackoutWindow(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	if err := h.db.DeleteBlackoutWindow(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(204)
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/calendar"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// calendarHorizonYears is how far ahead yearly recurring events are expanded on import
+const calendarHorizonYears = 5
+
+// maxCalendarFileSize bounds an imported .ics file
+const maxCalendarFileSize = 4 << 20
+
+// HolidayCalendarRequest imports a calendar from a local path. Uploads send the same
+// fields as multipart form values next to the "file" part.
+type HolidayCalendarRequest struct {
+	Name string `json:"name" form:"name"`
+	Path string `json:"path" form:"path"`
+}
+
+// calendarImport is a parsed .ics file and where it came from
+type calendarImport struct {
+	name   string
+	source string // upload, path
+	path   string
+	parsed *calendar.Calendar
+}
+
+// parseCalendarImport reads the calendar from the uploaded "file" or from the requested path.
+// When neither is given, fallbackPath is read again. Without a name in the request the
+// calendar is called defaultName, its own name or the file name, whichever is set first.
+func (h *Handler) parseCalendarImport(c *fiber.Ctx, defaultName, fallbackPath string) (*calendarImport, error) {
+	var req HolidayCalendarRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return nil, fmt.Errorf("invalid request")
+		}
+	}
+
+	imp := &calendarImport{name: strings.TrimSpace(req.Name)}
+	var reader io.Reader
+	var fileName string
+
+	if file, err := c.FormFile("file"); err == nil {
+		if file.Size > maxCalendarFileSize {
+			return nil, fmt.Errorf("calendar file exceeds %d bytes", maxCalendarFileSize)
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upload: %v", err)
+		}
+		defer f.Close()
+		reader, fileName, imp.source = f, file.Filename, "upload"
+	} else {
+		path := req.Path
+		if path == "" {
+			path = fallbackPath
+		}
+		if path == "" {
+			return nil, fmt.Errorf("either a file upload or a path is required")
+		}
+
+		resolved, err := h.resolveCalendarPath(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("calendar file not found: %s", path)
+		}
+		if info.Size() > maxCalendarFileSize {
+			return nil, fmt.Errorf("calendar file exceeds %d bytes", maxCalendarFileSize)
+		}
+		f, err := os.Open(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open calendar file: %v", err)
+		}
+		defer f.Close()
+		reader, fileName, imp.source, imp.path = f, resolved, "path", path
+	}
+
+	until := time.Now().AddDate(calendarHorizonYears, 0, 0)
+	parsed, err := calendar.Parse(reader, until)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar: %v", err)
+	}
+	if len(parsed.Days) == 0 {
+		return nil, fmt.Errorf("calendar has no events")
+	}
+	imp.parsed = parsed
+
+	if imp.name == "" {
+		imp.name = defaultName
+	}
+	if imp.name == "" {
+		imp.name = parsed.Name
+	}
+	if imp.name == "" {
+		imp.name = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	}
+	return imp, nil
+}
+
+// resolveCalendarPath turns a path relative to CALENDAR_DIR into a file path and rejects
+// paths that lead outside of it
+func (h *Handler) resolveCalendarPath(path string) (string, error) {
+	base, err := filepath.Abs(h.calendarDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid calendar directory: %v", err)
+	}
+
+	resolved := path
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(base, resolved)
+	}
+	resolved = filepath.Clean(resolved)
+
+	rel, err := filepath.Rel(base, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path must be inside the calendar directory %s", h.calendarDir)
+	}
+	return resolved, nil
+}
+
+func (imp *calendarImport) days() []models.CalendarDay {
+	days := make([]models.CalendarDay, 0, len(imp.parsed.Days))
+	for _, day := range imp.parsed.Days {
+		days = append(days, models.CalendarDay{Day: day.Date, Summary: day.Summary})
+	}
+	return days
+}
+
+// validateScheduleCalendars checks that the calendars a schedule references exist and that
+// none is both included and excluded
+func (h *Handler) validateScheduleCalendars(schedule *models.Schedule) error {
+	for _, id := range schedule.IncludeCalendarIDs {
+		if schedule.ExcludeCalendarIDs.Contains(id) {
+			return fmt.Errorf("calendar %s is both included and excluded", id)
+		}
+	}
+	for _, ids := range []models.UUIDList{schedule.IncludeCalendarIDs, schedule.ExcludeCalendarIDs} {
+		for _, id := range ids {
+			if _, err := h.db.GetHolidayCalendar(id); err != nil {
+				return fmt.Errorf("calendar %s not found", id)
+			}
+		}
+	}
+	return nil
+}
+
+func (h *Handler) ListHolidayCalendars(c *fiber.Ctx) error {
+	calendars, err := h.db.ListHolidayCalendars()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(calendars)
+}
+
+// GetHolidayCalendar returns a calendar and its dates
+func (h *Handler) GetHolidayCalendar(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	cal, err := h.db.GetHolidayCalendar(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Calendar not found"})
+	}
+
+	days, err := h.db.ListCalendarDays(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"calendar": cal,
+		"days":     days,
+	})
+}
+
+// ImportHolidayCalendar creates a calendar from an uploaded .ics file or a file in CALENDAR_DIR
+func (h *Handler) ImportHolidayCalendar(c *fiber.Ctx) error {
+	imp, err := h.parseCalendarImport(c, "", "")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if imp.name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Calendar name is required"})
+	}
+	if _, err := h.db.GetHolidayCalendarByName(imp.name); err == nil {
+		return c.Status(409).JSON(fiber.Map{"error": fmt.Sprintf("Calendar %q already exists", imp.name)})
+	}
+
+	cal := &models.HolidayCalendar{Name: imp.name, Source: imp.source}
+	if imp.path != "" {
+		cal.Path = models.NewNullString(imp.path)
+	}
+	if err := h.db.CreateHolidayCalendar(cal, imp.days()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"calendar":       cal,
+		"skipped_events": imp.parsed.Skipped,
+	})
+}
+
+// ReimportHolidayCalendar replaces the dates of a calendar from a new upload or path.
+// Without either, a calendar imported from a path is read from that path again.
+func (h *Handler) ReimportHolidayCalendar(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	cal, err := h.db.GetHolidayCalendar(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Calendar not found"})
+	}
+
+	imp, err := h.parseCalendarImport(c, cal.Name, cal.Path.String)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if imp.name != cal.Name {
+		if existing, err := h.db.GetHolidayCalendarByName(imp.name); err == nil && existing.ID != cal.ID {
+			return c.Status(409).JSON(fiber.Map{"error": fmt.Sprintf("Calendar %q already exists", imp.name)})
+		}
+	}
+
+	cal.Name = imp.name
+	cal.Source = imp.source
+	cal.Path = models.NullString{}
+	if imp.path != "" {
+		cal.Path = models.NewNullString(imp.path)
+	}
+	if err := h.db.UpdateHolidayCalendar(cal, imp.days()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if h.scheduler != nil {
+		h.scheduler.InvalidateCalendar(cal.ID)
+	}
+
+	return c.JSON(fiber.Map{
+		"calendar":       cal,
+		"skipped_events": imp.parsed.Skipped,
+	})
+}
+
+// DeleteHolidayCalendar removes a calendar that no schedule references
+func (h *Handler) DeleteHolidayCalendar(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	schedules, err := h.db.ListSchedulesUsingCalendar(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(schedules) > 0 {
+		names := make([]string, 0, len(schedules))
+		for _, schedule := range schedules {
+			names = append(names, schedule.Name)
+		}
+		return c.Status(409).JSON(fiber.Map{
+			"error":     "Calendar is used by schedules",
+			"schedules": names,
+		})
+	}
+
+	if err := h.db.DeleteHolidayCalendar(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if h.scheduler != nil {
+		h.scheduler.InvalidateCalendar(id)
+	}
+
+	return c.SendStatus(204)
+}
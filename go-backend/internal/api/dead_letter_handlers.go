@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/scheduler"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// maxDeadLetterBatch bounds how many dead jobs one bulk request retries or discards
+const maxDeadLetterBatch = 1000
+
+// DeadLetterBatchRequest selects dead jobs by ID, by schedule or all of them
+type DeadLetterBatchRequest struct {
+	IDs        []uuid.UUID `json:"ids"`
+	ScheduleID *uuid.UUID  `json:"schedule_id"`
+	All        bool        `json:"all"`
+}
+
+// deadLetterIDs resolves a bulk request to job IDs
+func (h *Handler) deadLetterIDs(c *fiber.Ctx, req *DeadLetterBatchRequest) ([]uuid.UUID, error) {
+	if len(req.IDs) > 0 {
+		if len(req.IDs) > maxDeadLetterBatch {
+			return nil, fmt.Errorf("at most %d ids per request", maxDeadLetterBatch)
+		}
+		return req.IDs, nil
+	}
+	if req.ScheduleID == nil && !req.All {
+		return nil, fmt.Errorf("ids, schedule_id or all is required")
+	}
+
+	letters, err := h.scheduler.DeadLetters(c.UserContext(), req.ScheduleID, maxDeadLetterBatch)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(letters))
+	for _, letter := range letters {
+		ids = append(ids, letter.Job.ID)
+	}
+	return ids, nil
+}
+
+// ListDeadLetters returns deliveries that exhausted their attempts, newest first.
+// ?schedule_id= narrows the list to one schedule.
+func (h *Handler) ListDeadLetters(c *fiber.Ctx) error {
+	if h.scheduler == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "Scheduler is not running"})
+	}
+
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > maxDeadLetterBatch {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("limit must be between 1 and %d", maxDeadLetterBatch)})
+	}
+
+	var scheduleID *uuid.UUID
+	if value := c.Query("schedule_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid schedule_id"})
+		}
+		scheduleID = &id
+	}
+
+	letters, err := h.scheduler.DeadLetters(c.UserContext(), scheduleID, limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(letters)
+}
+
+// RetryDeadLetter queues one dead job again with fresh attempts
+func (h *Handler) RetryDeadLetter(c *fiber.Ctx) error {
+	if h.scheduler == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "Scheduler is not running"})
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	err = h.scheduler.RetryDeadLetter(c.UserContext(), id)
+	if errors.Is(err, scheduler.ErrNotDead) {
+		return c.Status(404).JSON(fiber.Map{"error": "Dead letter not found"})
+	}
+	if errors.Is(err, scheduler.ErrShuttingDown) {
+		return c.Status(503).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(204)
+}
+
+// DiscardDeadLetter deletes one dead job
+func (h *Handler) DiscardDeadLetter(c *fiber.Ctx) error {
+	if h.scheduler == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "Scheduler is not running"})
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	err = h.scheduler.DiscardDeadLetter(c.UserContext(), id)
+	if errors.Is(err, scheduler.ErrNotDead) {
+		return c.Status(404).JSON(fiber.Map{"error": "Dead letter not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(204)
+}
+
+// RetryDeadLetters queues the selected dead jobs again
+func (h *Handler) RetryDeadLetters(c *fiber.Ctx) error {
+	return h.batchDeadLetters(c, "retried", h.scheduler.RetryDeadLetter)
+}
+
+// DiscardDeadLetters deletes the selected dead jobs
+func (h *Handler) DiscardDeadLetters(c *fiber.Ctx) error {
+	return h.batchDeadLetters(c, "discarded", h.scheduler.DiscardDeadLetter)
+}
+
+// batchDeadLetters applies op to every selected job and reports per-job failures
+func (h *Handler) batchDeadLetters(c *fiber.Ctx, done string, op func(ctx context.Context, id uuid.UUID) error) error {
+	if h.scheduler == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "Scheduler is not running"})
+	}
+
+	var req DeadLetterBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	ids, err := h.deadLetterIDs(c, &req)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	count := 0
+	failures := fiber.Map{}
+	for _, id := range ids {
+		if err := op(c.UserContext(), id); err != nil {
+			failures[id.String()] = err.Error()
+			continue
+		}
+		count++
+	}
+
+	return c.JSON(fiber.Map{
+		done:     count,
+		"errors": failures,
+	})
+}
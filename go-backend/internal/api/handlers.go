@@ -10,6 +10,7 @@ import (
 	"github.com/GezzyDax/timelith/go-backend/internal/auth"
 	"github.com/GezzyDax/timelith/go-backend/internal/database"
 	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/GezzyDax/timelith/go-backend/internal/scheduler"
 	"github.com/GezzyDax/timelith/go-backend/internal/telegram"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -18,10 +19,12 @@ import (
 type Handler struct {
 	db             *database.DB
 	sessionManager *telegram.SessionManager
+	scheduler      *scheduler.Scheduler
+	calendarDir    string
 }
 
-func NewHandler(db *database.DB, sessionManager *telegram.SessionManager) *Handler {
-	return &Handler{db: db, sessionManager: sessionManager}
+func NewHandler(db *database.DB, sessionManager *telegram.SessionManager, sched *scheduler.Scheduler, calendarDir string) *Handler {
+	return &Handler{db: db, sessionManager: sessionManager, scheduler: sched, calendarDir: calendarDir}
 }
 
 func (h *Handler) requireSessionManager(c *fiber.Ctx) bool {
@@ -37,10 +40,19 @@ func (h *Handler) requireSessionManager(c *fiber.Ctx) bool {
 
 // Health check
 func (h *Handler) HealthCheck(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"status": "ok",
 		"time":   time.Now().Unix(),
-	})
+	}
+	if h.scheduler != nil {
+		response["scheduler"] = h.scheduler.LeaderStatus()
+		if h.scheduler.ShuttingDown() {
+			// Lets load balancers stop routing here while the dispatcher drains
+			response["status"] = "shutting_down"
+			return c.Status(503).JSON(response)
+		}
+	}
+	return c.JSON(response)
 }
 
 // Auth handlers
@@ -125,6 +137,21 @@ func (h *Handler) ListAccounts(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+
+	now := time.Now()
+	usage, err := h.db.ListAccountUsage(now)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	byAccount := make(map[uuid.UUID]models.AccountUsage, len(usage))
+	for _, u := range usage {
+		byAccount[u.AccountID] = u
+	}
+	for i := range accounts {
+		u := byAccount[accounts[i].ID]
+		accounts[i].Usage = &u
+		accounts[i].Warmup = accounts[i].WarmupAt(now)
+	}
 	return c.JSON(accounts)
 }
 
@@ -139,11 +166,18 @@ func (h *Handler) GetAccount(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Account not found"})
 	}
 
+	now := time.Now()
+	if account.Usage, err = h.db.GetAccountUsage(id, now); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	account.Warmup = account.WarmupAt(now)
+
 	return c.JSON(account)
 }
 
 type CreateAccountRequest struct {
-	Phone string `json:"phone"`
+	Phone  string         `json:"phone"`
+	Warmup *WarmupRequest `json:"warmup"` // Warm-up program for the new account
 }
 
 type VerifyAccountCodeRequest struct {
@@ -167,6 +201,11 @@ func (h *Handler) CreateAccount(c *fiber.Ctx) error {
 	if req.Phone == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "Phone is required"})
 	}
+	if req.Warmup != nil {
+		if err := req.Warmup.validate(); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
 
 	ctx := context.Background()
 
@@ -184,6 +223,12 @@ func (h *Handler) CreateAccount(c *fiber.Ctx) error {
 		if err := h.db.CreateAccount(account); err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
+		if req.Warmup != nil {
+			req.Warmup.applyTo(account, time.Now())
+			if err := h.db.UpdateAccountWarmup(account); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
 	} else if account.Status == "active" {
 		return c.Status(400).JSON(fiber.Map{"error": "Account already active"})
 	}
@@ -380,9 +425,52 @@ func (h *Handler) GetTemplate(c *fiber.Ctx) error {
 }
 
 type CreateTemplateRequest struct {
-	Name      string   `json:"name"`
-	Content   string   `json:"content"`
-	Variables []string `json:"variables"`
+	Name              string              `json:"name"`
+	Content           string              `json:"content"`
+	Variables         []string            `json:"variables"`
+	MediaType         models.NullString   `json:"media_type"`
+	MediaPayload      models.MediaPayload `json:"media_payload"`
+	CopyFromChatID    models.NullString   `json:"copy_from_chat_id"`
+	CopyFromMessageID models.NullInt64    `json:"copy_from_message_id"`
+	CopyMode          models.NullString   `json:"copy_mode"`
+	DropMediaCaptions bool                `json:"drop_media_captions"`
+	CopyAlbum         bool                `json:"copy_album"`
+}
+
+func (r *CreateTemplateRequest) validate() error {
+	switch r.CopyMode.String {
+	case "", "forward", "copy", "resend":
+	default:
+		return fmt.Errorf("copy_mode must be one of forward, copy, resend")
+	}
+
+	if r.CopyFromChatID.Valid != r.CopyFromMessageID.Valid {
+		return fmt.Errorf("copy_from_chat_id and copy_from_message_id must be set together")
+	}
+
+	switch r.MediaType.String {
+	case "", "photo", "video", "document", "album", "poll", "location", "contact", "sticker":
+	default:
+		return fmt.Errorf("unsupported media_type: %s", r.MediaType.String)
+	}
+
+	return r.MediaPayload.Validate(r.MediaType.String)
+}
+
+func (r *CreateTemplateRequest) toTemplate(id uuid.UUID) *models.Template {
+	return &models.Template{
+		ID:                id,
+		Name:              r.Name,
+		Content:           r.Content,
+		Variables:         r.Variables,
+		MediaType:         r.MediaType,
+		MediaPayload:      r.MediaPayload,
+		CopyFromChatID:    r.CopyFromChatID,
+		CopyFromMessageID: r.CopyFromMessageID,
+		CopyMode:          r.CopyMode,
+		DropMediaCaptions: r.DropMediaCaptions,
+		CopyAlbum:         r.CopyAlbum,
+	}
 }
 
 func (h *Handler) CreateTemplate(c *fiber.Ctx) error {
@@ -391,12 +479,12 @@ func (h *Handler) CreateTemplate(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	template := &models.Template{
-		Name:      req.Name,
-		Content:   req.Content,
-		Variables: req.Variables,
+	if err := req.validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	template := req.toTemplate(uuid.Nil)
+
 	if err := h.db.CreateTemplate(template); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -415,13 +503,12 @@ func (h *Handler) UpdateTemplate(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	template := &models.Template{
-		ID:        id,
-		Name:      req.Name,
-		Content:   req.Content,
-		Variables: req.Variables,
+	if err := req.validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	template := req.toTemplate(id)
+
 	if err := h.db.UpdateTemplate(template); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -554,12 +641,294 @@ func (h *Handler) GetSchedule(c *fiber.Ctx) error {
 }
 
 type CreateScheduleRequest struct {
-	Name       string      `json:"name"`
-	AccountID  uuid.UUID   `json:"account_id"`
-	TemplateID uuid.UUID   `json:"template_id"`
-	ChannelIDs []uuid.UUID `json:"channel_ids"`
-	CronExpr   string      `json:"cron_expr"`
-	Timezone   string      `json:"timezone"`
+	Name                  string                           `json:"name"`
+	AccountID             *uuid.UUID                       `json:"account_id"`
+	AccountPoolID         *uuid.UUID                       `json:"account_pool_id"`
+	FallbackAccountIDs    []uuid.UUID                      `json:"fallback_account_ids"`
+	TemplateID            uuid.UUID                        `json:"template_id"`
+	ChannelIDs            []uuid.UUID                      `json:"channel_ids"`
+	CronExpr              string                           `json:"cron_expr"`
+	Timezone              string                           `json:"timezone"`
+	DeliveryMode          string                           `json:"delivery_mode"`
+	ScheduleAheadHours    int                              `json:"schedule_ahead_hours"`
+	MisfirePolicy         string                           `json:"misfire_policy"`
+	MisfireMaxRuns        int                              `json:"misfire_max_runs"`
+	MisfireGraceMinutes   int                              `json:"misfire_grace_minutes"`
+	DayFilter             string                           `json:"day_filter"`
+	CustomDays            []int                            `json:"custom_days"`
+	IncludeCalendarIDs    []uuid.UUID                      `json:"include_calendar_ids"`
+	ExcludeCalendarIDs    []uuid.UUID                      `json:"exclude_calendar_ids"`
+	Kind                  string                           `json:"kind"`
+	OnceAt                models.NullTime                  `json:"once_at"`
+	IntervalSeconds       int                              `json:"interval_seconds"`
+	IntervalAnchor        models.NullTime                  `json:"interval_anchor"`
+	StartsAt              models.Optional[models.NullTime] `json:"starts_at"` // null clears it on update
+	EndsAt                models.Optional[models.NullTime] `json:"ends_at"`   // null clears it on update
+	MaxRuns               models.Optional[int]             `json:"max_runs"`  // null or 0 clears it on update
+	DelayMinSeconds       *int                             `json:"delay_min_seconds"`
+	DelayMaxSeconds       *int                             `json:"delay_max_seconds"`
+	DelayDistribution     string                           `json:"delay_distribution"`
+	TypingAction          *bool                            `json:"typing_action"`
+	ShuffleChannels       *bool                            `json:"shuffle_channels"`
+	RetryMaxAttempts      int                              `json:"retry_max_attempts"`
+	RetryBaseDelaySeconds int                              `json:"retry_base_delay_seconds"`
+	RetryMaxDelaySeconds  int                              `json:"retry_max_delay_seconds"`
+	LoadBalance           *bool                            `json:"load_balance"`
+	RotationStrategy      string                           `json:"rotation_strategy"`
+}
+
+// maxScheduleAheadHours is Telegram's limit of one year for scheduled messages
+const maxScheduleAheadHours = 365 * 24
+
+// maxMisfireRuns bounds how many missed runs a run_all catch-up may send
+const maxMisfireRuns = 1000
+
+// maxDelaySeconds bounds the pause between two sends of a run
+const maxDelaySeconds = 3600
+
+// maxRetryAttempts bounds the send attempts of one delivery
+const maxRetryAttempts = 20
+
+// maxRetryDelaySeconds bounds the backoff between two attempts
+const maxRetryDelaySeconds = 24 * 3600
+
+func (r *CreateScheduleRequest) validate() error {
+	if r.AccountID != nil && r.AccountPoolID != nil {
+		return fmt.Errorf("account_id and account_pool_id are mutually exclusive")
+	}
+
+	switch r.DeliveryMode {
+	case "", "live", "telegram":
+	default:
+		return fmt.Errorf("delivery_mode must be one of live, telegram")
+	}
+
+	if r.ScheduleAheadHours < 0 || r.ScheduleAheadHours > maxScheduleAheadHours {
+		return fmt.Errorf("schedule_ahead_hours must be between 0 and %d", maxScheduleAheadHours)
+	}
+
+	switch r.MisfirePolicy {
+	case "", "skip", "run_once", "run_all", "run_if_recent":
+	default:
+		return fmt.Errorf("misfire_policy must be one of skip, run_once, run_all, run_if_recent")
+	}
+
+	if r.MisfireMaxRuns < 0 || r.MisfireMaxRuns > maxMisfireRuns {
+		return fmt.Errorf("misfire_max_runs must be between 0 (default) and %d", maxMisfireRuns)
+	}
+
+	if r.MisfireGraceMinutes < 0 {
+		return fmt.Errorf("misfire_grace_minutes must not be negative")
+	}
+
+	if r.DelayMinSeconds != nil && (*r.DelayMinSeconds < 0 || *r.DelayMinSeconds > maxDelaySeconds) {
+		return fmt.Errorf("delay_min_seconds must be between 0 and %d", maxDelaySeconds)
+	}
+	if r.DelayMaxSeconds != nil && (*r.DelayMaxSeconds < 0 || *r.DelayMaxSeconds > maxDelaySeconds) {
+		return fmt.Errorf("delay_max_seconds must be between 0 and %d", maxDelaySeconds)
+	}
+
+	if r.RetryMaxAttempts < 0 || r.RetryMaxAttempts > maxRetryAttempts {
+		return fmt.Errorf("retry_max_attempts must be between 0 (default) and %d", maxRetryAttempts)
+	}
+	if r.RetryBaseDelaySeconds < 0 || r.RetryBaseDelaySeconds > maxRetryDelaySeconds {
+		return fmt.Errorf("retry_base_delay_seconds must be between 0 (default) and %d", maxRetryDelaySeconds)
+	}
+	if r.RetryMaxDelaySeconds < 0 || r.RetryMaxDelaySeconds > maxRetryDelaySeconds {
+		return fmt.Errorf("retry_max_delay_seconds must be between 0 (default) and %d", maxRetryDelaySeconds)
+	}
+
+	if r.RotationStrategy != "" && !scheduler.ValidRotationStrategy(r.RotationStrategy) {
+		return fmt.Errorf("rotation_strategy must be one of least_used, least_recently_used, round_robin, weighted, sticky, random")
+	}
+
+	switch r.DelayDistribution {
+	case "", "uniform", "normal":
+	default:
+		return fmt.Errorf("delay_distribution must be one of uniform, normal")
+	}
+
+	switch r.DayFilter {
+	case "", "all", "weekdays", "weekends":
+	case "custom":
+		if len(r.CustomDays) == 0 {
+			return fmt.Errorf("custom_days is required for the custom day filter")
+		}
+		for _, day := range r.CustomDays {
+			if day < 0 || day > 6 {
+				return fmt.Errorf("custom_days must be between 0 (Sunday) and 6 (Saturday)")
+			}
+		}
+	default:
+		return fmt.Errorf("day_filter must be one of all, weekdays, weekends, custom")
+	}
+
+	switch r.Kind {
+	case "":
+	case "cron":
+		if r.CronExpr == "" {
+			return fmt.Errorf("cron_expr is required for cron schedules")
+		}
+	case "once":
+		if !r.OnceAt.Valid {
+			return fmt.Errorf("once_at is required for once schedules")
+		}
+		if !r.OnceAt.Time.After(time.Now()) {
+			return fmt.Errorf("once_at must be in the future")
+		}
+	case "interval":
+		if r.IntervalSeconds <= 0 {
+			return fmt.Errorf("interval_seconds must be positive for interval schedules")
+		}
+	default:
+		return fmt.Errorf("kind must be one of cron, once, interval")
+	}
+
+	if r.MaxRuns.Value < 0 {
+		return fmt.Errorf("max_runs must not be negative")
+	}
+
+	return nil
+}
+
+// toSchedule builds a new active schedule from the request with defaults for omitted settings
+func (r *CreateScheduleRequest) toSchedule() *models.Schedule {
+	schedule := &models.Schedule{
+		Name:                  r.Name,
+		TemplateID:            r.TemplateID,
+		ChannelIDs:            formatUUIDs(r.ChannelIDs),
+		CronExpr:              r.CronExpr,
+		Kind:                  "cron",
+		Timezone:              "UTC",
+		Status:                "active",
+		DeliveryMode:          "live",
+		ScheduleAheadHours:    24,
+		MisfirePolicy:         "skip",
+		MisfireMaxRuns:        10,
+		MisfireGraceMinutes:   15,
+		DelayDistribution:     "uniform",
+		RetryMaxAttempts:      4,
+		RetryBaseDelaySeconds: 2,
+		RetryMaxDelaySeconds:  300,
+		RotationStrategy:      scheduler.DefaultRotationStrategy,
+		IncludeCalendarIDs:    models.UUIDList{},
+		ExcludeCalendarIDs:    models.UUIDList{},
+		FallbackAccountIDs:    models.UUIDList{},
+	}
+	r.applyTo(schedule)
+
+	// Interval runs count from creation unless an anchor or start is given
+	if schedule.Kind == "interval" && !schedule.IntervalAnchor.Valid && !schedule.StartsAt.Valid {
+		schedule.IntervalAnchor = models.NewNullTime(time.Now().Truncate(time.Second))
+	}
+	return schedule
+}
+
+// applyTo copies the settings that were provided onto the schedule, so omitted ones keep
+// their current value. Giving an account or a pool replaces the one the schedule sent
+// through before.
+func (r *CreateScheduleRequest) applyTo(schedule *models.Schedule) {
+	if r.Name != "" {
+		schedule.Name = r.Name
+	}
+	if len(r.ChannelIDs) > 0 {
+		schedule.ChannelIDs = formatUUIDs(r.ChannelIDs)
+	}
+	if r.CronExpr != "" {
+		schedule.CronExpr = r.CronExpr
+	}
+	if r.AccountID != nil {
+		schedule.AccountID = r.AccountID
+		schedule.AccountPoolID = nil
+	}
+	if r.AccountPoolID != nil {
+		schedule.AccountPoolID = r.AccountPoolID
+		schedule.AccountID = nil
+	}
+	if r.FallbackAccountIDs != nil {
+		schedule.FallbackAccountIDs = models.UUIDList(r.FallbackAccountIDs)
+	}
+	if r.Timezone != "" {
+		schedule.Timezone = r.Timezone
+	}
+	if r.DeliveryMode != "" {
+		schedule.DeliveryMode = r.DeliveryMode
+	}
+	if r.ScheduleAheadHours > 0 {
+		schedule.ScheduleAheadHours = r.ScheduleAheadHours
+	}
+	if r.MisfirePolicy != "" {
+		schedule.MisfirePolicy = r.MisfirePolicy
+	}
+	if r.MisfireMaxRuns > 0 {
+		schedule.MisfireMaxRuns = r.MisfireMaxRuns
+	}
+	if r.MisfireGraceMinutes > 0 {
+		schedule.MisfireGraceMinutes = r.MisfireGraceMinutes
+	}
+	if r.DayFilter != "" {
+		schedule.DayFilter = models.NewNullString(r.DayFilter)
+	}
+	if r.CustomDays != nil {
+		schedule.CustomDays = r.CustomDays
+	}
+	if r.IncludeCalendarIDs != nil {
+		schedule.IncludeCalendarIDs = models.UUIDList(r.IncludeCalendarIDs)
+	}
+	if r.ExcludeCalendarIDs != nil {
+		schedule.ExcludeCalendarIDs = models.UUIDList(r.ExcludeCalendarIDs)
+	}
+	if r.Kind != "" {
+		schedule.Kind = r.Kind
+	}
+	if r.OnceAt.Valid {
+		schedule.OnceAt = r.OnceAt
+	}
+	if r.IntervalSeconds > 0 {
+		schedule.IntervalSeconds = r.IntervalSeconds
+	}
+	if r.IntervalAnchor.Valid {
+		schedule.IntervalAnchor = r.IntervalAnchor
+	}
+	if r.StartsAt.Set {
+		schedule.StartsAt = r.StartsAt.Value
+	}
+	if r.EndsAt.Set {
+		schedule.EndsAt = r.EndsAt.Value
+	}
+	if r.MaxRuns.Set {
+		schedule.MaxRuns = r.MaxRuns.Value
+	}
+	if r.DelayMinSeconds != nil {
+		schedule.DelayMinSeconds = *r.DelayMinSeconds
+	}
+	if r.DelayMaxSeconds != nil {
+		schedule.DelayMaxSeconds = *r.DelayMaxSeconds
+	}
+	if r.DelayDistribution != "" {
+		schedule.DelayDistribution = r.DelayDistribution
+	}
+	if r.TypingAction != nil {
+		schedule.TypingAction = *r.TypingAction
+	}
+	if r.ShuffleChannels != nil {
+		schedule.ShuffleChannels = *r.ShuffleChannels
+	}
+	if r.RetryMaxAttempts > 0 {
+		schedule.RetryMaxAttempts = r.RetryMaxAttempts
+	}
+	if r.RetryBaseDelaySeconds > 0 {
+		schedule.RetryBaseDelaySeconds = r.RetryBaseDelaySeconds
+	}
+	if r.RetryMaxDelaySeconds > 0 {
+		schedule.RetryMaxDelaySeconds = r.RetryMaxDelaySeconds
+	}
+	if r.LoadBalance != nil {
+		schedule.LoadBalance = *r.LoadBalance
+	}
+	if r.RotationStrategy != "" {
+		schedule.RotationStrategy = r.RotationStrategy
+	}
 }
 
 func formatUUIDs(ids []uuid.UUID) []string {
@@ -574,6 +943,20 @@ func formatUUIDs(ids []uuid.UUID) []string {
 	return formatted
 }
 
+// syncScheduler registers or removes the schedule's cron entry to match its status
+func (h *Handler) syncScheduler(schedule *models.Schedule) error {
+	if h.scheduler == nil {
+		return nil
+	}
+
+	if schedule.Status != "active" {
+		h.scheduler.RemoveSchedule(schedule.ID)
+		return nil
+	}
+
+	return h.scheduler.AddSchedule(schedule)
+}
+
 func (h *Handler) CreateSchedule(c *fiber.Ctx) error {
 	var req CreateScheduleRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -584,20 +967,29 @@ func (h *Handler) CreateSchedule(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "At least one channel_id is required"})
 	}
 
-	schedule := &models.Schedule{
-		Name:       req.Name,
-		AccountID:  req.AccountID,
-		TemplateID: req.TemplateID,
-		ChannelIDs: formatUUIDs(req.ChannelIDs),
-		CronExpr:   req.CronExpr,
-		Timezone:   req.Timezone,
-		Status:     "active",
+	if err := req.validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	schedule := req.toSchedule()
+	if err := scheduler.ValidateSchedule(schedule); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.validateScheduleCalendars(schedule); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.validateScheduleAccounts(schedule); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	if err := h.db.CreateSchedule(schedule); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if err := h.syncScheduler(schedule); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf("Schedule saved but not registered: %v", err)})
+	}
+
 	return c.Status(201).JSON(schedule)
 }
 
@@ -618,17 +1010,30 @@ func (h *Handler) UpdateSchedule(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	schedule.Name = req.Name
-	if len(req.ChannelIDs) > 0 {
-		schedule.ChannelIDs = formatUUIDs(req.ChannelIDs)
+	if err := req.validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.applyTo(schedule)
+
+	if err := scheduler.ValidateSchedule(schedule); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.validateScheduleCalendars(schedule); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.validateScheduleAccounts(schedule); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-	schedule.CronExpr = req.CronExpr
-	schedule.Timezone = req.Timezone
 
 	if err := h.db.UpdateSchedule(schedule); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if err := h.syncScheduler(schedule); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf("Schedule saved but not registered: %v", err)})
+	}
+
 	return c.JSON(schedule)
 }
 
@@ -647,17 +1052,30 @@ func (h *Handler) UpdateScheduleStatus(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
+	switch req.Status {
+	case "active", "paused", "completed":
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "status must be one of active, paused, completed"})
+	}
+
 	schedule, err := h.db.GetSchedule(id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Schedule not found"})
 	}
 
 	schedule.Status = req.Status
+	if schedule.Status != "active" {
+		schedule.NextRunAt = models.NullTime{}
+	}
 
 	if err := h.db.UpdateSchedule(schedule); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if err := h.syncScheduler(schedule); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf("Schedule saved but not registered: %v", err)})
+	}
+
 	return c.JSON(schedule)
 }
 
@@ -671,11 +1089,119 @@ func (h *Handler) DeleteSchedule(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if h.scheduler != nil {
+		h.scheduler.RemoveSchedule(id)
+	}
+
 	return c.SendStatus(204)
 }
 
 // Job log handlers
 
+// RunScheduleRequest selects what a manual run of a schedule does
+type RunScheduleRequest struct {
+	ChannelIDs []uuid.UUID `json:"channel_ids"` // Subset of the schedule's channels; all when empty
+	DryRun     bool        `json:"dry_run"`
+}
+
+// RunSchedule runs a schedule immediately or, with dry_run, reports what a run would send
+func (h *Handler) RunSchedule(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	var req RunScheduleRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+	}
+	if c.QueryBool("dry_run") {
+		req.DryRun = true
+	}
+
+	if h.scheduler == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "Scheduler is not running"})
+	}
+
+	schedule, err := h.db.GetSchedule(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Schedule not found"})
+	}
+
+	report, err := h.scheduler.RunNow(c.UserContext(), schedule, scheduler.RunOptions{
+		ChannelIDs: req.ChannelIDs,
+		DryRun:     req.DryRun,
+	})
+	if errors.Is(err, scheduler.ErrShuttingDown) {
+		return c.Status(503).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(report)
+}
+
+// maxPreviewCount is the largest number of effective runs a preview returns
+const maxPreviewCount = 100
+
+// PreviewSchedule lists the next fire times of a saved schedule
+func (h *Handler) PreviewSchedule(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	schedule, err := h.db.GetSchedule(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Schedule not found"})
+	}
+
+	return h.previewSchedule(c, schedule, c.QueryInt("count", 10))
+}
+
+// PreviewUnsavedSchedule lists the next fire times of a schedule described in the request body
+func (h *Handler) PreviewUnsavedSchedule(c *fiber.Ctx) error {
+	var req CreateScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	schedule := req.toSchedule()
+	if err := h.validateScheduleCalendars(schedule); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return h.previewSchedule(c, schedule, c.QueryInt("count", 10))
+}
+
+func (h *Handler) previewSchedule(c *fiber.Ctx, schedule *models.Schedule, count int) error {
+	if h.scheduler == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "Scheduler is not running"})
+	}
+
+	if count < 1 || count > maxPreviewCount {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("count must be between 1 and %d", maxPreviewCount)})
+	}
+
+	runs, err := h.scheduler.Preview(schedule, time.Now(), count)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"schedule_id": schedule.ID,
+		"timezone":    schedule.Timezone,
+		"runs":        runs,
+	})
+}
+
 func (h *Handler) GetScheduleLogs(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
@@ -690,6 +1216,47 @@ func (h *Handler) GetScheduleLogs(c *fiber.Ctx) error {
 	return c.JSON(logs)
 }
 
+func (h *Handler) GetScheduleRuns(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	runs, err := h.db.ListScheduleRuns(id, 50)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(runs)
+}
+
+// GetScheduleRun returns a run of a schedule with the outcome of each of its deliveries
+func (h *Handler) GetScheduleRun(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+	runID, err := uuid.Parse(c.Params("runId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid run ID"})
+	}
+
+	run, err := h.db.GetScheduleRun(runID)
+	if err != nil || run.ScheduleID != id {
+		return c.Status(404).JSON(fiber.Map{"error": "Run not found"})
+	}
+
+	deliveries, err := h.db.GetRunDeliveries(runID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"run":        run,
+		"deliveries": deliveries,
+	})
+}
+
 func (h *Handler) GetAllLogs(c *fiber.Ctx) error {
 	logs, err := h.db.GetAllJobLogs(100)
 	if err != nil {
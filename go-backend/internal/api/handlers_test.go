@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/GezzyDax/timelith/go-backend/internal/scheduler"
+)
+
+func TestScheduleUpdateAppliesPresentFields(t *testing.T) {
+	endsAt := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	schedule := &models.Schedule{
+		Name:     "daily",
+		CronExpr: "0 9 * * *",
+		StartsAt: models.NewNullTime(endsAt.AddDate(0, -1, 0)),
+		EndsAt:   models.NewNullTime(endsAt),
+		MaxRuns:  5,
+	}
+
+	var req CreateScheduleRequest
+	if err := json.Unmarshal([]byte(`{"ends_at": null, "max_runs": null}`), &req); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if err := req.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	req.applyTo(schedule)
+
+	if schedule.Name != "daily" || schedule.CronExpr != "0 9 * * *" {
+		t.Errorf("omitted fields changed: name %q, cron %q", schedule.Name, schedule.CronExpr)
+	}
+	if !schedule.StartsAt.Valid {
+		t.Error("omitted starts_at was cleared")
+	}
+	if schedule.EndsAt.Valid {
+		t.Error("ends_at null did not clear it")
+	}
+	if schedule.MaxRuns != 0 {
+		t.Errorf("max_runs null did not clear it, got %d", schedule.MaxRuns)
+	}
+}
+
+func TestScheduleRetryPolicyBounds(t *testing.T) {
+	defaults := CreateScheduleRequest{}
+	if err := defaults.validate(); err != nil {
+		t.Errorf("zero retry settings select the defaults, got %v", err)
+	}
+
+	tooMany := CreateScheduleRequest{RetryMaxAttempts: maxRetryAttempts + 1}
+	err := tooMany.validate()
+	if err == nil || err.Error() != "retry_max_attempts must be between 0 (default) and 20" {
+		t.Errorf("unexpected error for too many attempts: %v", err)
+	}
+}
+
+func TestScheduleUpdateValidatesMergedSettings(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"delivery_mode": "telegram"}`:         "max_runs is not supported with telegram delivery",
+		`{"delay_min_seconds": 60}`:             "delay_min_seconds must not exceed delay_max_seconds",
+		`{"starts_at": "2027-01-01T00:00:00Z"}`: "ends_at must be after starts_at",
+	} {
+		schedule := &models.Schedule{
+			CronExpr:             "0 0 9 * * *",
+			Timezone:             "UTC",
+			DeliveryMode:         "live",
+			EndsAt:               models.NewNullTime(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)),
+			MaxRuns:              5,
+			DelayMaxSeconds:      30,
+			RetryMaxDelaySeconds: 300,
+		}
+
+		var req CreateScheduleRequest
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			t.Fatalf("decode %s: %v", body, err)
+		}
+		if err := req.validate(); err != nil {
+			t.Fatalf("validate %s: %v", body, err)
+		}
+		req.applyTo(schedule)
+
+		err := scheduler.ValidateSchedule(schedule)
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: got %v, want %q", body, err, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// PauseRequest pauses sending globally or for one account or channel
+type PauseRequest struct {
+	Scope    string     `json:"scope"`
+	TargetID *uuid.UUID `json:"target_id"`
+	Reason   string     `json:"reason"`
+}
+
+// validatePause checks the scope and that the paused account or channel exists
+func (h *Handler) validatePause(req *PauseRequest) error {
+	switch req.Scope {
+	case "global":
+		if req.TargetID != nil {
+			return fmt.Errorf("target_id is not allowed for the global pause")
+		}
+	case "account":
+		if req.TargetID == nil {
+			return fmt.Errorf("target_id is required for account pauses")
+		}
+		if _, err := h.db.GetAccount(*req.TargetID); err != nil {
+			return fmt.Errorf("account %s not found", req.TargetID)
+		}
+	case "channel":
+		if req.TargetID == nil {
+			return fmt.Errorf("target_id is required for channel pauses")
+		}
+		if _, err := h.db.GetChannel(*req.TargetID); err != nil {
+			return fmt.Errorf("channel %s not found", req.TargetID)
+		}
+	default:
+		return fmt.Errorf("scope must be one of global, account, channel")
+	}
+	return nil
+}
+
+// pauseEvent starts an audit record attributed to the authenticated user
+func pauseEvent(c *fiber.Ctx, action, reason string) *models.PauseEvent {
+	event := &models.PauseEvent{Action: action}
+	if reason != "" {
+		event.Reason = models.NewNullString(reason)
+	}
+	if userID, err := userIDFromContext(c); err == nil {
+		event.UserID = &userID
+	}
+	if username, ok := c.Locals("username").(string); ok && username != "" {
+		event.Username = models.NewNullString(username)
+	}
+	return event
+}
+
+// ListPauses returns the active pauses
+func (h *Handler) ListPauses(c *fiber.Ctx) error {
+	pauses, err := h.db.ListPauses()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(pauses)
+}
+
+// CreatePause stops sending. Pausing what is already paused returns the existing pause.
+func (h *Handler) CreatePause(c *fiber.Ctx) error {
+	var req PauseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if req.Scope == "" {
+		req.Scope = "global"
+	}
+
+	if err := h.validatePause(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	pause := &models.Pause{Scope: req.Scope, TargetID: req.TargetID}
+	if req.Reason != "" {
+		pause.Reason = models.NewNullString(req.Reason)
+	}
+	event := pauseEvent(c, "pause", req.Reason)
+	event.Scope = pause.Scope
+	event.TargetID = pause.TargetID
+	pause.PausedBy = event.UserID
+
+	created, err := h.db.CreatePause(pause, event)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !created {
+		existing, err := h.db.FindPause(req.Scope, req.TargetID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(existing)
+	}
+
+	if h.scheduler != nil {
+		h.scheduler.ApplyPauses()
+	}
+
+	return c.Status(201).JSON(pause)
+}
+
+// DeletePause lifts a pause; parked jobs go out within the recheck interval.
+// An optional ?reason= is recorded in the audit trail.
+func (h *Handler) DeletePause(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	pause, err := h.db.DeletePause(id, pauseEvent(c, "resume", c.Query("reason")))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(404).JSON(fiber.Map{"error": "Pause not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if h.scheduler != nil {
+		h.scheduler.ApplyPauses()
+	}
+
+	return c.JSON(pause)
+}
+
+// GetPauseEvents returns the audit trail of pauses, newest first
+func (h *Handler) GetPauseEvents(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 1000 {
+		return c.Status(400).JSON(fiber.Map{"error": "limit must be between 1 and 1000"})
+	}
+
+	events, err := h.db.ListPauseEvents(limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(events)
+}
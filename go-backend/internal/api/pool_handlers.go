@@ -0,0 +1,257 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/encryption"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// AccountPoolRequest creates or replaces an account pool. The proxy password is kept when
+// omitted on update.
+type AccountPoolRequest struct {
+	Name          string      `json:"name"`
+	Description   string      `json:"description"`
+	DailyLimit    int         `json:"daily_limit"`
+	ProxyEnabled  bool        `json:"proxy_enabled"`
+	ProxyHost     string      `json:"proxy_host"`
+	ProxyPort     int         `json:"proxy_port"`
+	ProxyUsername string      `json:"proxy_username"`
+	ProxyPassword *string     `json:"proxy_password"`
+	AccountIDs    []uuid.UUID `json:"account_ids"` // Initial accounts, on create only
+}
+
+// AccountPoolMembersRequest adds accounts to a pool
+type AccountPoolMembersRequest struct {
+	AccountIDs []uuid.UUID `json:"account_ids"`
+}
+
+func (r *AccountPoolRequest) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.DailyLimit < 0 {
+		return fmt.Errorf("daily_limit must not be negative")
+	}
+	if r.ProxyEnabled {
+		if r.ProxyHost == "" {
+			return fmt.Errorf("proxy_host is required when the proxy is enabled")
+		}
+		if r.ProxyPort < 1 || r.ProxyPort > 65535 {
+			return fmt.Errorf("proxy_port must be between 1 and 65535")
+		}
+	}
+	return nil
+}
+
+// applyTo copies the request onto the pool, encrypting the proxy password
+func (r *AccountPoolRequest) applyTo(pool *models.AccountPool) error {
+	pool.Name = r.Name
+	pool.Description = optionalString(r.Description)
+	pool.DailyLimit = r.DailyLimit
+	pool.ProxyEnabled = r.ProxyEnabled
+	pool.ProxyHost = optionalString(r.ProxyHost)
+	pool.ProxyPort = models.NullInt64{}
+	if r.ProxyPort > 0 {
+		pool.ProxyPort = models.NewNullInt64(int64(r.ProxyPort))
+	}
+	pool.ProxyUsername = optionalString(r.ProxyUsername)
+	if r.ProxyPassword != nil {
+		pool.ProxyPassword = models.NullString{}
+		if *r.ProxyPassword != "" {
+			encrypted, err := encryption.Encrypt(*r.ProxyPassword)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt proxy password: %w", err)
+			}
+			pool.ProxyPassword = models.NewNullString(encrypted)
+		}
+	}
+	return nil
+}
+
+func optionalString(value string) models.NullString {
+	if value == "" {
+		return models.NullString{}
+	}
+	return models.NewNullString(value)
+}
+
+// validatePoolAccounts checks that every account to add exists
+func (h *Handler) validatePoolAccounts(accountIDs []uuid.UUID) error {
+	for _, id := range accountIDs {
+		if _, err := h.db.GetAccount(id); err != nil {
+			return fmt.Errorf("account %s not found", id)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) ListAccountPools(c *fiber.Ctx) error {
+	pools, err := h.db.ListAccountPools()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(pools)
+}
+
+func (h *Handler) GetAccountPool(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	pool, err := h.db.GetAccountPool(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Account pool not found"})
+	}
+
+	return c.JSON(pool)
+}
+
+func (h *Handler) CreateAccountPool(c *fiber.Ctx) error {
+	var req AccountPoolRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.validatePoolAccounts(req.AccountIDs); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	pool := &models.AccountPool{}
+	if err := req.applyTo(pool); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.db.CreateAccountPool(pool); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.db.AddAccountPoolMembers(pool.ID, req.AccountIDs); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	created, err := h.db.GetAccountPool(pool.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(created)
+}
+
+func (h *Handler) UpdateAccountPool(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	pool, err := h.db.GetAccountPool(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Account pool not found"})
+	}
+
+	var req AccountPoolRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := req.applyTo(pool); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.db.UpdateAccountPool(pool); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	updated, err := h.db.GetAccountPool(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(updated)
+}
+
+// DeleteAccountPool removes a pool that no schedule sends through
+func (h *Handler) DeleteAccountPool(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	count, err := h.db.CountPoolSchedules(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if count > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": fmt.Sprintf("Account pool is used by %d schedule(s)", count)})
+	}
+
+	if err := h.db.DeleteAccountPool(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(204)
+}
+
+// AddAccountPoolMembers puts accounts into the pool
+func (h *Handler) AddAccountPoolMembers(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	var req AccountPoolMembersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if len(req.AccountIDs) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "At least one account_id is required"})
+	}
+
+	if _, err := h.db.GetAccountPool(id); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Account pool not found"})
+	}
+	if err := h.validatePoolAccounts(req.AccountIDs); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.db.AddAccountPoolMembers(id, req.AccountIDs); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	pool, err := h.db.GetAccountPool(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(pool)
+}
+
+// RemoveAccountPoolMember takes an account out of the pool
+func (h *Handler) RemoveAccountPoolMember(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+	accountID, err := uuid.Parse(c.Params("accountId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid account ID"})
+	}
+
+	removed, err := h.db.RemoveAccountPoolMember(id, accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !removed {
+		return c.Status(404).JSON(fiber.Map{"error": "Account is not in the pool"})
+	}
+
+	return c.SendStatus(204)
+}
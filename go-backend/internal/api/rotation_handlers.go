@@ -0,0 +1,237 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// UpdateAccountRequest changes the sending settings of an account; omitted settings are kept
+type UpdateAccountRequest struct {
+	RotationWeight    *int           `json:"rotation_weight"`
+	HourlyQuota       *int           `json:"hourly_quota"`
+	DailyQuota        *int           `json:"daily_quota"`
+	DailyNewChatQuota *int           `json:"daily_new_chat_quota"`
+	Warmup            *WarmupRequest `json:"warmup"`
+}
+
+// WarmupRequest starts, changes or stops the warm-up program of an account. A running
+// program keeps its start date unless restart is set.
+type WarmupRequest struct {
+	Enabled     bool   `json:"enabled"`
+	Restart     bool   `json:"restart"`
+	Days        int    `json:"days"`
+	Curve       string `json:"curve"`
+	StartLimit  int    `json:"start_limit"`
+	TargetLimit int    `json:"target_limit"`
+	Activity    bool   `json:"activity"`
+}
+
+// maxWarmupDays bounds the length of a warm-up program
+const maxWarmupDays = 90
+
+func (r *WarmupRequest) validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.Curve == "" {
+		r.Curve = models.WarmupCurveLinear
+	}
+	if !models.ValidWarmupCurve(r.Curve) {
+		return fmt.Errorf("warmup curve must be linear, quadratic or square_root")
+	}
+	if r.Days < 1 || r.Days > maxWarmupDays {
+		return fmt.Errorf("warmup days must be between 1 and %d", maxWarmupDays)
+	}
+	if r.StartLimit < 1 {
+		return fmt.Errorf("warmup start_limit must be at least 1")
+	}
+	if r.TargetLimit < r.StartLimit {
+		return fmt.Errorf("warmup target_limit must not be below start_limit")
+	}
+	return nil
+}
+
+// applyTo copies the program onto the account, starting it at the given time when it is
+// not running yet
+func (r *WarmupRequest) applyTo(account *models.Account, now time.Time) {
+	if !r.Enabled {
+		account.WarmupStartedAt = models.NullTime{}
+		account.WarmupActivity = false
+		account.WarmupNextActivityAt = models.NullTime{}
+		return
+	}
+	if r.Restart || account.WarmupAt(now) == nil {
+		account.WarmupStartedAt = models.NewNullTime(now)
+	}
+	account.WarmupDays = r.Days
+	account.WarmupCurve = r.Curve
+	account.WarmupStartLimit = r.StartLimit
+	account.WarmupTargetLimit = r.TargetLimit
+	account.WarmupActivity = r.Activity
+}
+
+// AccountChannelRequest marks whether an account can post in a channel, e.g. after it joined
+type AccountChannelRequest struct {
+	Member bool `json:"member"`
+}
+
+// maxRotationWeight bounds the weight of an account under weighted rotation
+const maxRotationWeight = 1000
+
+// maxFallbackAccounts bounds the fallback list of a schedule
+const maxFallbackAccounts = 10
+
+// validateScheduleAccounts checks that the schedule sends through an existing account or pool
+// and that its fallbacks are distinct existing accounts other than its own
+func (h *Handler) validateScheduleAccounts(schedule *models.Schedule) error {
+	if len(schedule.FallbackAccountIDs) > maxFallbackAccounts {
+		return fmt.Errorf("at most %d fallback accounts are allowed", maxFallbackAccounts)
+	}
+	seen := make(map[uuid.UUID]bool, len(schedule.FallbackAccountIDs))
+	for _, id := range schedule.FallbackAccountIDs {
+		switch {
+		case seen[id]:
+			return fmt.Errorf("fallback account %s is listed twice", id)
+		case schedule.AccountID != nil && *schedule.AccountID == id:
+			return fmt.Errorf("fallback account %s is the schedule's own account", id)
+		}
+		seen[id] = true
+		if _, err := h.db.GetAccount(id); err != nil {
+			return fmt.Errorf("fallback account %s not found", id)
+		}
+	}
+
+	switch {
+	case schedule.AccountPoolID != nil:
+		if _, err := h.db.GetAccountPool(*schedule.AccountPoolID); err != nil {
+			return fmt.Errorf("account pool %s not found", schedule.AccountPoolID)
+		}
+	case schedule.AccountID != nil:
+		if _, err := h.db.GetAccount(*schedule.AccountID); err != nil {
+			return fmt.Errorf("account %s not found", schedule.AccountID)
+		}
+	default:
+		return fmt.Errorf("account_id or account_pool_id is required")
+	}
+	return nil
+}
+
+// UpdateAccount changes the rotation weight, send quotas and warm-up program of an account
+func (h *Handler) UpdateAccount(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	var req UpdateAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	account, err := h.db.GetAccount(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Account not found"})
+	}
+
+	if req.RotationWeight != nil {
+		if *req.RotationWeight < 0 || *req.RotationWeight > maxRotationWeight {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("rotation_weight must be between 0 and %d", maxRotationWeight)})
+		}
+		account.RotationWeight = *req.RotationWeight
+	}
+	quotas := []struct {
+		name  string
+		value *int
+		field *int
+	}{
+		{"hourly_quota", req.HourlyQuota, &account.HourlyQuota},
+		{"daily_quota", req.DailyQuota, &account.DailyQuota},
+		{"daily_new_chat_quota", req.DailyNewChatQuota, &account.DailyNewChatQuota},
+	}
+	for _, quota := range quotas {
+		if quota.value == nil {
+			continue
+		}
+		if *quota.value < 0 {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("%s must not be negative", quota.name)})
+		}
+		*quota.field = *quota.value
+	}
+	if req.Warmup != nil {
+		if err := req.Warmup.validate(); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if err := h.db.UpdateAccountSettings(account); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	now := time.Now()
+	if req.Warmup != nil {
+		req.Warmup.applyTo(account, now)
+		if err := h.db.UpdateAccountWarmup(account); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if account.Usage, err = h.db.GetAccountUsage(id, now); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	account.Warmup = account.WarmupAt(now)
+
+	return c.JSON(account)
+}
+
+// GetAccountChannels lists the channels the account was seen posting in or failing to
+func (h *Handler) GetAccountChannels(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	records, err := h.db.ListAccountChannels(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(records)
+}
+
+// SetAccountChannel overrides what rotation knows about the account in a channel
+func (h *Handler) SetAccountChannel(c *fiber.Ctx) error {
+	accountID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+	channelID, err := uuid.Parse(c.Params("channelId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid channel ID"})
+	}
+
+	var req AccountChannelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if _, err := h.db.GetAccount(accountID); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Account not found"})
+	}
+	if _, err := h.db.GetChannel(channelID); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Channel not found"})
+	}
+
+	reason := ""
+	if !req.Member {
+		reason = "marked manually"
+	}
+	if err := h.db.SetAccountChannel(accountID, channelID, req.Member, reason); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(204)
+}
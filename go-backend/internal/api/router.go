@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/GezzyDax/timelith/go-backend/internal/config"
 	"github.com/GezzyDax/timelith/go-backend/internal/database"
+	"github.com/GezzyDax/timelith/go-backend/internal/scheduler"
 	"github.com/GezzyDax/timelith/go-backend/internal/settings"
 	"github.com/GezzyDax/timelith/go-backend/internal/telegram"
 	"github.com/gofiber/fiber/v2"
@@ -11,7 +12,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
-func SetupRouter(cfg *config.Config, db *database.DB, settingsService *settings.Service, sessionManager *telegram.SessionManager) *fiber.App {
+func SetupRouter(cfg *config.Config, db *database.DB, settingsService *settings.Service, sessionManager *telegram.SessionManager, sched *scheduler.Scheduler) *fiber.App {
 	app := fiber.New(fiber.Config{
 		AppName: "Timelith API v1.0",
 	})
@@ -22,7 +23,7 @@ func SetupRouter(cfg *config.Config, db *database.DB, settingsService *settings.
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "http://localhost:3000, http://localhost:8080, http://127.0.0.1:3000, http://127.0.0.1:8080",
 		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-API-Key",
-		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
+		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 		AllowCredentials: true,
 	}))
 
@@ -35,7 +36,7 @@ func SetupRouter(cfg *config.Config, db *database.DB, settingsService *settings.
 		return c.Next()
 	})
 
-	handler := NewHandler(db, sessionManager)
+	handler := NewHandler(db, sessionManager, sched, cfg.CalendarDir)
 	setupHandler := NewSetupHandler(db, settingsService)
 	settingsHandler := NewSettingsHandler(settingsService)
 	usersHandler := NewUsersHandler(db)
@@ -69,10 +70,23 @@ func SetupRouter(cfg *config.Config, db *database.DB, settingsService *settings.
 	accounts.Get("/", handler.ListAccounts)
 	accounts.Post("/", handler.CreateAccount)
 	accounts.Get("/:id", handler.GetAccount)
+	accounts.Patch("/:id", handler.UpdateAccount)
+	accounts.Get("/:id/channels", handler.GetAccountChannels)
+	accounts.Put("/:id/channels/:channelId", handler.SetAccountChannel)
 	accounts.Post("/:id/verify-code", handler.VerifyAccountCode)
 	accounts.Post("/:id/verify-password", handler.VerifyAccountPassword)
 	accounts.Delete("/:id", handler.DeleteAccount)
 
+	// Account pools
+	accountPools := protected.Group("/account-pools")
+	accountPools.Get("/", handler.ListAccountPools)
+	accountPools.Post("/", handler.CreateAccountPool)
+	accountPools.Get("/:id", handler.GetAccountPool)
+	accountPools.Put("/:id", handler.UpdateAccountPool)
+	accountPools.Delete("/:id", handler.DeleteAccountPool)
+	accountPools.Post("/:id/accounts", handler.AddAccountPoolMembers)
+	accountPools.Delete("/:id/accounts/:accountId", handler.RemoveAccountPoolMember)
+
 	// Templates
 	templates := protected.Group("/templates")
 	templates.Get("/", handler.ListTemplates)
@@ -93,11 +107,48 @@ func SetupRouter(cfg *config.Config, db *database.DB, settingsService *settings.
 	schedules := protected.Group("/schedules")
 	schedules.Get("/", handler.ListSchedules)
 	schedules.Post("/", handler.CreateSchedule)
+	schedules.Post("/preview", handler.PreviewUnsavedSchedule)
 	schedules.Get("/:id", handler.GetSchedule)
 	schedules.Put("/:id", handler.UpdateSchedule)
+	schedules.Patch("/:id", handler.UpdateSchedule)
 	schedules.Patch("/:id/status", handler.UpdateScheduleStatus)
 	schedules.Delete("/:id", handler.DeleteSchedule)
 	schedules.Get("/:id/logs", handler.GetScheduleLogs)
+	schedules.Get("/:id/runs", handler.GetScheduleRuns)
+	schedules.Get("/:id/runs/:runId", handler.GetScheduleRun)
+	schedules.Get("/:id/preview", handler.PreviewSchedule)
+	schedules.Post("/:id/run", handler.RunSchedule)
+
+	// Blackout windows
+	blackouts := protected.Group("/blackouts")
+	blackouts.Get("/", handler.ListBlackoutWindows)
+	blackouts.Post("/", handler.CreateBlackoutWindow)
+	blackouts.Get("/:id", handler.GetBlackoutWindow)
+	blackouts.Put("/:id", handler.UpdateBlackoutWindow)
+	blackouts.Delete("/:id", handler.DeleteBlackoutWindow)
+
+	// Deliveries that exhausted their retries
+	deadLetters := protected.Group("/dead-letters")
+	deadLetters.Get("/", handler.ListDeadLetters)
+	deadLetters.Post("/retry", handler.RetryDeadLetters)
+	deadLetters.Post("/discard", handler.DiscardDeadLetters)
+	deadLetters.Post("/:id/retry", handler.RetryDeadLetter)
+	deadLetters.Delete("/:id", handler.DiscardDeadLetter)
+
+	// Sending pauses
+	pauses := protected.Group("/pauses")
+	pauses.Get("/", handler.ListPauses)
+	pauses.Post("/", handler.CreatePause)
+	pauses.Get("/events", handler.GetPauseEvents)
+	pauses.Delete("/:id", handler.DeletePause)
+
+	// Holiday calendars
+	calendars := protected.Group("/calendars")
+	calendars.Get("/", handler.ListHolidayCalendars)
+	calendars.Post("/", handler.ImportHolidayCalendar)
+	calendars.Get("/:id", handler.GetHolidayCalendar)
+	calendars.Put("/:id", handler.ReimportHolidayCalendar)
+	calendars.Delete("/:id", handler.DeleteHolidayCalendar)
 
 	// Logs
 	logs := protected.Group("/logs")
@@ -0,0 +1,450 @@
+// Package calendar reads holiday calendars in iCalendar (.ics) format
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxEventDays bounds how many days a single multi-day event may cover
+	maxEventDays = 366
+	// maxDays bounds the size of an imported calendar
+	maxDays = 100000
+	// maxLineLength bounds a single unfolded content line
+	maxLineLength = 1 << 20
+)
+
+// Calendar is the set of dates covered by the events of an .ics file
+type Calendar struct {
+	Name    string   // X-WR-CALNAME, if present
+	Days    []Day    // Sorted by date, one entry per date
+	Skipped []string // Events that could not be expanded, with the reason
+}
+
+// Day is a date covered by at least one event. Date is midnight UTC of that date.
+type Day struct {
+	Date    time.Time
+	Summary string
+}
+
+type property struct {
+	name  string
+	value string
+}
+
+type event struct {
+	summary string
+	start   *property
+	end     *property
+	rrule   string
+	exdates []string
+	status  string
+}
+
+// Parse reads an .ics file and returns the dates covered by its events. Dates are taken as
+// written in the file, without timezone conversion. Yearly recurring events are expanded
+// up to until; other recurrence rules are reported in Skipped.
+func Parse(r io.Reader, until time.Time) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &Calendar{}
+	days := make(map[time.Time][]string)
+
+	var stack []string
+	var ev *event
+	sawCalendar := false
+	for _, line := range lines {
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.value)
+			stack = append(stack, component)
+			if component == "VCALENDAR" {
+				sawCalendar = true
+			}
+			if component == "VEVENT" && len(stack) == 2 {
+				ev = &event{}
+			}
+			continue
+		case "END":
+			component := strings.ToUpper(prop.value)
+			if len(stack) == 0 || stack[len(stack)-1] != component {
+				return nil, fmt.Errorf("unexpected END:%s", prop.value)
+			}
+			stack = stack[:len(stack)-1]
+			if component == "VEVENT" && ev != nil && len(stack) == 1 {
+				if err := ev.expand(days, until); err != nil {
+					cal.Skipped = append(cal.Skipped, fmt.Sprintf("%s: %v", ev.label(), err))
+				}
+				ev = nil
+			}
+			continue
+		}
+
+		switch {
+		case len(stack) == 1 && stack[0] == "VCALENDAR" && prop.name == "X-WR-CALNAME":
+			cal.Name = unescape(prop.value)
+		case ev != nil && len(stack) == 2:
+			switch prop.name {
+			case "SUMMARY":
+				ev.summary = unescape(prop.value)
+			case "DTSTART":
+				p := prop
+				ev.start = &p
+			case "DTEND":
+				p := prop
+				ev.end = &p
+			case "RRULE":
+				ev.rrule = prop.value
+			case "EXDATE":
+				ev.exdates = append(ev.exdates, strings.Split(prop.value, ",")...)
+			case "STATUS":
+				ev.status = strings.ToUpper(prop.value)
+			}
+		}
+
+		if len(days) > maxDays {
+			return nil, fmt.Errorf("calendar covers more than %d days", maxDays)
+		}
+	}
+
+	if !sawCalendar {
+		return nil, fmt.Errorf("not an iCalendar file: VCALENDAR not found")
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("unterminated %s", stack[len(stack)-1])
+	}
+
+	cal.Days = make([]Day, 0, len(days))
+	for date, summaries := range days {
+		cal.Days = append(cal.Days, Day{Date: date, Summary: strings.Join(summaries, "; ")})
+	}
+	sort.Slice(cal.Days, func(i, j int) bool { return cal.Days[i].Date.Before(cal.Days[j].Date) })
+	return cal, nil
+}
+
+// unfold joins continuation lines, which start with a space or tab, to the line before them
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// parseLine splits a content line into its name and value; parameters are dropped. Colons
+// and semicolons inside quoted parameter values are not separators.
+func parseLine(line string) (property, error) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("malformed line %q", truncate(line))
+	}
+
+	name, _, _ := strings.Cut(line[:colon], ";")
+	return property{name: strings.ToUpper(name), value: line[colon+1:]}, nil
+}
+
+func unescape(value string) string {
+	replacer := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return strings.TrimSpace(replacer.Replace(value))
+}
+
+func truncate(s string) string {
+	if len(s) > 60 {
+		return s[:60] + "..."
+	}
+	return s
+}
+
+func (e *event) label() string {
+	if e.summary != "" {
+		return fmt.Sprintf("event %q", e.summary)
+	}
+	if e.start != nil {
+		return fmt.Sprintf("event at %s", e.start.value)
+	}
+	return "event"
+}
+
+// expand adds every date the event covers to days
+func (e *event) expand(days map[time.Time][]string, until time.Time) error {
+	if e.status == "CANCELLED" {
+		return nil
+	}
+	if e.start == nil {
+		return fmt.Errorf("DTSTART is missing")
+	}
+
+	start, err := parseDate(e.start.value)
+	if err != nil {
+		return err
+	}
+	length, err := e.length(start)
+	if err != nil {
+		return err
+	}
+
+	occurrences := []time.Time{start}
+	if e.rrule != "" {
+		if occurrences, err = expandRule(e.rrule, start, until); err != nil {
+			return err
+		}
+	}
+
+	excluded := make(map[time.Time]bool, len(e.exdates))
+	for _, value := range e.exdates {
+		if date, err := parseDate(value); err == nil {
+			excluded[date] = true
+		}
+	}
+
+	for _, occurrence := range occurrences {
+		if excluded[occurrence] {
+			continue
+		}
+		for i := 0; i < length; i++ {
+			date := occurrence.AddDate(0, 0, i)
+			days[date] = appendUnique(days[date], e.summary)
+		}
+	}
+	return nil
+}
+
+// length returns how many days the event covers. DTEND is exclusive for all-day events;
+// a timed event covers every date it touches.
+func (e *event) length(start time.Time) (int, error) {
+	if e.end == nil {
+		return 1, nil
+	}
+
+	end, err := parseDate(e.end.value)
+	if err != nil {
+		return 0, err
+	}
+	if isDateTime(e.end.value) && !strings.HasPrefix(e.end.value[9:], "000000") {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	length := int(end.Sub(start).Hours() / 24)
+	if length < 1 {
+		return 1, nil
+	}
+	if length > maxEventDays {
+		return 0, fmt.Errorf("event is longer than %d days", maxEventDays)
+	}
+	return length, nil
+}
+
+// expandRule lists the start dates of a yearly recurring event up to until. Supported are
+// FREQ=YEARLY with INTERVAL, COUNT, UNTIL and either a fixed date or a single BYDAY
+// weekday (e.g. -1MO) within a single BYMONTH.
+func expandRule(rule string, start, until time.Time) ([]time.Time, error) {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+
+	if parts["FREQ"] != "YEARLY" {
+		return nil, fmt.Errorf("unsupported recurrence FREQ=%s", parts["FREQ"])
+	}
+	for key := range parts {
+		switch key {
+		case "FREQ", "INTERVAL", "COUNT", "UNTIL", "BYMONTH", "BYMONTHDAY", "BYDAY", "WKST":
+		default:
+			return nil, fmt.Errorf("unsupported recurrence part %s", key)
+		}
+	}
+
+	interval := 1
+	if value, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid INTERVAL %q", value)
+		}
+		interval = n
+	}
+
+	count := 0
+	if value, ok := parts["COUNT"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid COUNT %q", value)
+		}
+		count = n
+	}
+
+	if value, ok := parts["UNTIL"]; ok {
+		date, err := parseDate(value)
+		if err != nil {
+			return nil, err
+		}
+		if date.Before(until) {
+			until = date
+		}
+	}
+
+	month := start.Month()
+	if value, ok := parts["BYMONTH"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 12 {
+			return nil, fmt.Errorf("unsupported BYMONTH %q", value)
+		}
+		month = time.Month(n)
+	}
+
+	day := start.Day()
+	if value, ok := parts["BYMONTHDAY"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 31 {
+			return nil, fmt.Errorf("unsupported BYMONTHDAY %q", value)
+		}
+		day = n
+	}
+
+	var nth int
+	var weekday time.Weekday
+	if value, ok := parts["BYDAY"]; ok {
+		var err error
+		if nth, weekday, err = parseByDay(value); err != nil {
+			return nil, err
+		}
+	}
+
+	var dates []time.Time
+	for year := start.Year(); ; year += interval {
+		if time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).After(until) {
+			break
+		}
+
+		var date time.Time
+		if nth != 0 {
+			var ok bool
+			if date, ok = nthWeekday(year, month, weekday, nth); !ok {
+				continue
+			}
+		} else {
+			date = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+			if date.Day() != day {
+				// Feb 29 and similar dates only occur in some years
+				continue
+			}
+		}
+		if date.After(until) {
+			break
+		}
+		if date.Before(start) {
+			continue
+		}
+		dates = append(dates, date)
+		if count > 0 && len(dates) == count {
+			break
+		}
+	}
+	return dates, nil
+}
+
+// parseByDay parses a single BYDAY entry with an ordinal, e.g. 1MO or -1FR
+func parseByDay(value string) (int, time.Weekday, error) {
+	if strings.Contains(value, ",") || len(value) < 3 {
+		return 0, 0, fmt.Errorf("unsupported BYDAY %q", value)
+	}
+
+	weekdays := map[string]time.Weekday{
+		"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+		"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+	}
+	weekday, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return 0, 0, fmt.Errorf("unsupported BYDAY %q", value)
+	}
+	nth, err := strconv.Atoi(value[:len(value)-2])
+	if err != nil || nth == 0 || nth < -5 || nth > 5 {
+		return 0, 0, fmt.Errorf("unsupported BYDAY %q", value)
+	}
+	return nth, weekday, nil
+}
+
+// nthWeekday returns the nth weekday of the month, counting from the end when nth is
+// negative. It reports false when the month has fewer such weekdays.
+func nthWeekday(year int, month time.Month, weekday time.Weekday, nth int) (time.Time, bool) {
+	var date time.Time
+	if nth > 0 {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+		date = first.AddDate(0, 0, offset+(nth-1)*7)
+	} else {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+		offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+		date = last.AddDate(0, 0, -offset+(nth+1)*7)
+	}
+	return date, date.Month() == month
+}
+
+// parseDate reads the date part of a DATE (20250101) or DATE-TIME (20250101T090000Z) value
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
+
+func isDateTime(value string) bool {
+	return len(value) >= 15 && value[8] == 'T'
+}
+
+func appendUnique(list []string, value string) []string {
+	if value == "" {
+		return list
+	}
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultShutdownTimeout is how long shutdown waits for in-flight sends by default
+const DefaultShutdownTimeout = 30 * time.Second
+
 type Config struct {
 	// Server
 	ServerPort string
@@ -19,6 +23,12 @@ type Config struct {
 	// Redis
 	RedisURL string
 
+	// Dispatcher queue backend: postgres or redis
+	QueueBackend string
+
+	// Directory that holiday calendars may be imported from by path
+	CalendarDir string
+
 	// Telegram
 	TelegramAppID   int
 	TelegramAppHash string
@@ -29,6 +39,9 @@ type Config struct {
 
 	// Environment
 	Environment string
+
+	// How long shutdown waits for in-flight sends before interrupting them
+	ShutdownTimeout time.Duration
 }
 
 func Load() (*Config, error) {
@@ -40,6 +53,8 @@ func Load() (*Config, error) {
 		APIKey:          getEnv("API_KEY", ""),
 		DatabaseURL:     getEnv("DATABASE_URL", ""),
 		RedisURL:        getEnv("REDIS_URL", "redis://localhost:6379"),
+		QueueBackend:    getEnv("QUEUE_BACKEND", "postgres"),
+		CalendarDir:     getEnv("CALENDAR_DIR", "calendars"),
 		TelegramAppHash: getEnv("TELEGRAM_APP_HASH", ""),
 		JWTSecret:       getEnv("JWT_SECRET", ""),
 		EncryptionKey:   getEnv("ENCRYPTION_KEY", ""),
@@ -54,6 +69,13 @@ func Load() (*Config, error) {
 	}
 	cfg.TelegramAppID = appID
 
+	// Parse ShutdownTimeout (seconds)
+	shutdownSeconds, err := strconv.Atoi(getEnv("SHUTDOWN_TIMEOUT", strconv.Itoa(int(DefaultShutdownTimeout.Seconds()))))
+	if err != nil || shutdownSeconds <= 0 {
+		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: must be a positive number of seconds")
+	}
+	cfg.ShutdownTimeout = time.Duration(shutdownSeconds) * time.Second
+
 	// Validate required fields
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL is required")
@@ -143,6 +143,248 @@ func (db *DB) RunMigrations() error {
 		// Add indexes for new columns
 		`CREATE INDEX IF NOT EXISTS idx_accounts_messages_sent ON accounts(messages_sent)`,
 		`CREATE INDEX IF NOT EXISTS idx_accounts_last_used ON accounts(last_used_at)`,
+		// Add copy options for templates sourced from existing messages
+		`ALTER TABLE templates ADD COLUMN IF NOT EXISTS copy_mode VARCHAR(50) DEFAULT 'forward'`,
+		`ALTER TABLE templates ADD COLUMN IF NOT EXISTS drop_media_captions BOOLEAN DEFAULT false`,
+		`ALTER TABLE templates ADD COLUMN IF NOT EXISTS copy_album BOOLEAN DEFAULT false`,
+		// Add structured payloads for poll, location, contact and sticker templates
+		`ALTER TABLE templates ADD COLUMN IF NOT EXISTS media_payload JSONB DEFAULT '{}'`,
+		// Add Telegram scheduled-message delivery mode
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS delivery_mode VARCHAR(50) DEFAULT 'live'`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS schedule_ahead_hours INTEGER DEFAULT 24`,
+		// Catch-up policy for runs missed while the backend was down
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS misfire_policy VARCHAR(50) DEFAULT 'skip'`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS misfire_max_runs INTEGER DEFAULT 10`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS misfire_grace_minutes INTEGER DEFAULT 15`,
+		// One-time and interval schedules, run windows and run limits
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS kind VARCHAR(50) DEFAULT 'cron'`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS once_at TIMESTAMP`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS interval_seconds INTEGER DEFAULT 0`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS interval_anchor TIMESTAMP`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS starts_at TIMESTAMP`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS ends_at TIMESTAMP`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS max_runs INTEGER DEFAULT 0`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS run_count INTEGER DEFAULT 0`,
+		`ALTER TABLE schedules ALTER COLUMN cron_expr SET DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS native_scheduled_messages (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			schedule_id UUID REFERENCES schedules(id) ON DELETE SET NULL,
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+			run_at TIMESTAMP NOT NULL,
+			send_at TIMESTAMP NOT NULL,
+			telegram_message_ids JSONB DEFAULT '[]',
+			fingerprint VARCHAR(64) NOT NULL,
+			status VARCHAR(50) NOT NULL DEFAULT 'scheduled',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_native_scheduled_schedule ON native_scheduled_messages(schedule_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_native_scheduled_status ON native_scheduled_messages(status, send_at)`,
+		// Persistent dispatcher queue
+		`CREATE TABLE IF NOT EXISTS dispatch_jobs (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			schedule_id UUID NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			template_id UUID NOT NULL REFERENCES templates(id) ON DELETE CASCADE,
+			channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+			message TEXT NOT NULL DEFAULT '',
+			status VARCHAR(50) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			run_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			locked_by VARCHAR(255),
+			locked_until TIMESTAMP,
+			last_error TEXT,
+			finished_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_dispatch_jobs_claim ON dispatch_jobs(status, run_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_dispatch_jobs_locked ON dispatch_jobs(locked_until) WHERE status = 'running'`,
+		// Idempotent deliveries
+		`CREATE TABLE IF NOT EXISTS deliveries (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			idempotency_key VARCHAR(64) NOT NULL UNIQUE,
+			schedule_id UUID NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+			channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+			account_id UUID REFERENCES accounts(id) ON DELETE SET NULL,
+			run_at TIMESTAMP NOT NULL,
+			status VARCHAR(50) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			telegram_message_ids JSONB NOT NULL DEFAULT '[]',
+			error TEXT,
+			sent_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_deliveries_schedule ON deliveries(schedule_id, run_at)`,
+		`ALTER TABLE dispatch_jobs ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(64) NOT NULL DEFAULT ''`,
+		// Leader election lease for the cron scheduler
+		`CREATE TABLE IF NOT EXISTS scheduler_leases (
+			name VARCHAR(100) PRIMARY KEY,
+			holder VARCHAR(255) NOT NULL,
+			acquired_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			renewed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMP NOT NULL
+		)`,
+		// Schedule runs and per-delivery outcomes
+		`CREATE TABLE IF NOT EXISTS schedule_runs (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			schedule_id UUID NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+			trigger VARCHAR(20) NOT NULL,
+			run_at TIMESTAMP NOT NULL,
+			started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			finished_at TIMESTAMP,
+			total INTEGER NOT NULL DEFAULT 0,
+			queued INTEGER,
+			skipped INTEGER NOT NULL DEFAULT 0,
+			sent INTEGER NOT NULL DEFAULT 0,
+			failed INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_schedule_runs_schedule ON schedule_runs(schedule_id, started_at DESC)`,
+		`ALTER TABLE deliveries ADD COLUMN IF NOT EXISTS run_id UUID REFERENCES schedule_runs(id) ON DELETE SET NULL`,
+		`ALTER TABLE deliveries ADD COLUMN IF NOT EXISTS error_category VARCHAR(32)`,
+		`ALTER TABLE deliveries ADD COLUMN IF NOT EXISTS latency_ms INTEGER`,
+		`CREATE INDEX IF NOT EXISTS idx_deliveries_run ON deliveries(run_id)`,
+		// Pacing between the sends of a run
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS delay_distribution VARCHAR(20) NOT NULL DEFAULT 'uniform'`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS typing_action BOOLEAN NOT NULL DEFAULT false`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS shuffle_channels BOOLEAN NOT NULL DEFAULT false`,
+		`ALTER TABLE dispatch_jobs ADD COLUMN IF NOT EXISTS next_job_id UUID`,
+		// Blackout windows
+		`CREATE TABLE IF NOT EXISTS blackout_windows (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name VARCHAR(255) NOT NULL,
+			scope VARCHAR(20) NOT NULL,
+			schedule_id UUID REFERENCES schedules(id) ON DELETE CASCADE,
+			channel_id UUID REFERENCES channels(id) ON DELETE CASCADE,
+			kind VARCHAR(20) NOT NULL,
+			weekdays JSONB NOT NULL DEFAULT '[]',
+			start_time VARCHAR(5),
+			end_time VARCHAR(5),
+			timezone VARCHAR(100) NOT NULL DEFAULT 'UTC',
+			starts_at TIMESTAMP,
+			ends_at TIMESTAMP,
+			policy VARCHAR(20) NOT NULL DEFAULT 'skip',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		// Holiday calendars
+		`CREATE TABLE IF NOT EXISTS holiday_calendars (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name VARCHAR(255) UNIQUE NOT NULL,
+			source VARCHAR(20) NOT NULL,
+			path TEXT,
+			day_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS calendar_days (
+			calendar_id UUID NOT NULL REFERENCES holiday_calendars(id) ON DELETE CASCADE,
+			day DATE NOT NULL,
+			summary TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (calendar_id, day)
+		)`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS include_calendar_ids JSONB NOT NULL DEFAULT '[]'`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS exclude_calendar_ids JSONB NOT NULL DEFAULT '[]'`,
+		// Per-schedule retry policy
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS retry_max_attempts INTEGER NOT NULL DEFAULT 4`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS retry_base_delay_seconds INTEGER NOT NULL DEFAULT 2`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS retry_max_delay_seconds INTEGER NOT NULL DEFAULT 300`,
+		`CREATE INDEX IF NOT EXISTS idx_dispatch_jobs_dead ON dispatch_jobs(finished_at DESC) WHERE status = 'dead'`,
+		// Sending pauses and their audit trail
+		`CREATE TABLE IF NOT EXISTS pauses (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			scope VARCHAR(20) NOT NULL,
+			target_id UUID,
+			reason TEXT,
+			paused_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			paused_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_pauses_target
+			ON pauses(scope, COALESCE(target_id, '00000000-0000-0000-0000-000000000000'))`,
+		`CREATE TABLE IF NOT EXISTS pause_events (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			action VARCHAR(20) NOT NULL,
+			scope VARCHAR(20) NOT NULL,
+			target_id UUID,
+			reason TEXT,
+			user_id UUID,
+			username VARCHAR(255),
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_pause_events_created ON pause_events(created_at DESC)`,
+		// Account rotation strategies
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS rotation_strategy VARCHAR(30) NOT NULL DEFAULT 'least_used'`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS rotation_weight INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS restricted_until TIMESTAMP`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS restriction_reason TEXT`,
+		`CREATE TABLE IF NOT EXISTS account_channels (
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+			member BOOLEAN NOT NULL,
+			reason TEXT,
+			checked_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (account_id, channel_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_deliveries_schedule_created ON deliveries(schedule_id, created_at DESC)`,
+		// Account pools
+		`CREATE TABLE IF NOT EXISTS account_pools (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name VARCHAR(255) NOT NULL UNIQUE,
+			description TEXT,
+			daily_limit INTEGER NOT NULL DEFAULT 0,
+			proxy_enabled BOOLEAN NOT NULL DEFAULT false,
+			proxy_host VARCHAR(255),
+			proxy_port INTEGER,
+			proxy_username VARCHAR(255),
+			proxy_password TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS account_pool_members (
+			pool_id UUID NOT NULL REFERENCES account_pools(id) ON DELETE CASCADE,
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			added_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (pool_id, account_id)
+		)`,
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS account_pool_id UUID REFERENCES account_pools(id)`,
+		`ALTER TABLE schedules ALTER COLUMN account_id DROP NOT NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_schedules_account_pool ON schedules(account_pool_id)`,
+		// Per-account send quotas
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS hourly_quota INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS daily_quota INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS daily_new_chat_quota INTEGER NOT NULL DEFAULT 0`,
+		`CREATE TABLE IF NOT EXISTS account_sends (
+			id BIGSERIAL PRIMARY KEY,
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+			new_chat BOOLEAN NOT NULL DEFAULT false,
+			sent_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_account_sends_account_sent ON account_sends(account_id, sent_at)`,
+		// Account warm-up
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS warmup_started_at TIMESTAMP`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS warmup_days INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS warmup_curve VARCHAR(20) NOT NULL DEFAULT 'linear'`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS warmup_start_limit INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS warmup_target_limit INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS warmup_activity BOOLEAN NOT NULL DEFAULT false`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS warmup_next_activity_at TIMESTAMP`,
+		// Schedule failover to fallback accounts
+		`ALTER TABLE schedules ADD COLUMN IF NOT EXISTS fallback_account_ids JSONB NOT NULL DEFAULT '[]'`,
+		`ALTER TABLE deliveries ADD COLUMN IF NOT EXISTS failover_from UUID REFERENCES accounts(id) ON DELETE SET NULL`,
+		`ALTER TABLE deliveries ADD COLUMN IF NOT EXISTS failover_reason TEXT`,
+		// Account sends of Telegram scheduled uploads, released when the upload is cancelled
+		`ALTER TABLE account_sends ADD COLUMN IF NOT EXISTS native_message_id UUID REFERENCES native_scheduled_messages(id) ON DELETE CASCADE`,
+		// Account sends per schedule, counted toward the daily limit of the schedule's pool
+		`ALTER TABLE account_sends ADD COLUMN IF NOT EXISTS schedule_id UUID REFERENCES schedules(id) ON DELETE SET NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_account_sends_schedule_sent ON account_sends(schedule_id, sent_at)`,
+		// Pacing of sends per account across schedules and runs
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS next_allowed_at TIMESTAMP`,
+		// Warm-up curves named after their shape
+		`UPDATE accounts SET warmup_curve = 'quadratic' WHERE warmup_curve = 'exponential'`,
+		`UPDATE accounts SET warmup_curve = 'square_root' WHERE warmup_curve = 'logarithmic'`,
 	}
 
 	for _, migration := range migrations {
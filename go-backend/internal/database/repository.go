@@ -3,9 +3,11 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/GezzyDax/timelith/go-backend/internal/models"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 // Account Repository
@@ -117,28 +119,432 @@ func (db *DB) IncrementAccountMessageCount(id uuid.UUID) error {
 	return err
 }
 
-func (db *DB) GetLeastUsedAccount() (*models.Account, error) {
-	var account models.Account
+// UpdateAccountSettings saves the account's rotation weight and send quotas
+func (db *DB) UpdateAccountSettings(account *models.Account) error {
+	query := `UPDATE accounts
+			  SET rotation_weight = $1, hourly_quota = $2, daily_quota = $3,
+			      daily_new_chat_quota = $4, updated_at = NOW()
+			  WHERE id = $5`
+	_, err := db.Exec(query, account.RotationWeight, account.HourlyQuota, account.DailyQuota,
+		account.DailyNewChatQuota, account.ID)
+	return err
+}
+
+// UpdateAccountWarmup saves the account's warm-up program
+func (db *DB) UpdateAccountWarmup(account *models.Account) error {
+	query := `UPDATE accounts
+			  SET warmup_started_at = $1, warmup_days = $2, warmup_curve = $3, warmup_start_limit = $4,
+			      warmup_target_limit = $5, warmup_activity = $6, warmup_next_activity_at = $7,
+			      updated_at = NOW()
+			  WHERE id = $8`
+	_, err := db.Exec(query, account.WarmupStartedAt.UTC(), account.WarmupDays, account.WarmupCurve,
+		account.WarmupStartLimit, account.WarmupTargetLimit, account.WarmupActivity,
+		account.WarmupNextActivityAt.UTC(), account.ID)
+	return err
+}
+
+// ListWarmupActivityDue returns the active accounts still warming up whose benign activity is due
+func (db *DB) ListWarmupActivityDue(now time.Time) ([]models.Account, error) {
+	var accounts []models.Account
 	query := `SELECT * FROM accounts
-			  WHERE status = 'active'
-			  ORDER BY messages_sent ASC, last_used_at ASC NULLS FIRST
+			  WHERE status = 'active' AND warmup_activity = true AND warmup_started_at IS NOT NULL
+			    AND warmup_started_at + warmup_days * INTERVAL '1 day' > $1
+			    AND (warmup_next_activity_at IS NULL OR warmup_next_activity_at <= $1)`
+	err := db.Select(&accounts, query, now.UTC())
+	return accounts, err
+}
+
+// SetWarmupNextActivity schedules the account's next benign activity
+func (db *DB) SetWarmupNextActivity(id uuid.UUID, at time.Time) error {
+	query := `UPDATE accounts SET warmup_next_activity_at = $1 WHERE id = $2`
+	_, err := db.Exec(query, at.UTC(), id)
+	return err
+}
+
+// RestrictAccount keeps the account out of rotation until the given time. An earlier
+// restriction that lasts longer is kept.
+func (db *DB) RestrictAccount(id uuid.UUID, until time.Time, reason string) error {
+	query := `UPDATE accounts
+			  SET restricted_until = $1, restriction_reason = $2, updated_at = NOW()
+			  WHERE id = $3 AND (restricted_until IS NULL OR restricted_until < $1)`
+	_, err := db.Exec(query, until, reason, id)
+	return err
+}
+
+// Account Channel Repository
+
+// SetAccountChannel records whether the account could post in the channel
+func (db *DB) SetAccountChannel(accountID, channelID uuid.UUID, member bool, reason string) error {
+	query := `INSERT INTO account_channels (account_id, channel_id, member, reason, checked_at)
+			  VALUES ($1, $2, $3, $4, NOW())
+			  ON CONFLICT (account_id, channel_id)
+			  DO UPDATE SET member = EXCLUDED.member, reason = EXCLUDED.reason, checked_at = NOW()`
+	var reasonValue models.NullString
+	if reason != "" {
+		reasonValue = models.NewNullString(reason)
+	}
+	_, err := db.Exec(query, accountID, channelID, member, reasonValue)
+	return err
+}
+
+func (db *DB) ListAccountChannels(accountID uuid.UUID) ([]models.AccountChannel, error) {
+	records := []models.AccountChannel{}
+	query := `SELECT * FROM account_channels WHERE account_id = $1 ORDER BY checked_at DESC`
+	err := db.Select(&records, query, accountID)
+	return records, err
+}
+
+// ListMembers returns the accounts that posted in a channel and did not fail there since
+func (db *DB) ListMembers() ([]models.AccountChannel, error) {
+	var records []models.AccountChannel
+	query := `SELECT * FROM account_channels WHERE member = true`
+	err := db.Select(&records, query)
+	return records, err
+}
+
+// IsChannelMember reports whether the account has posted in the channel before
+func (db *DB) IsChannelMember(accountID, channelID uuid.UUID) (bool, error) {
+	var member bool
+	query := `SELECT EXISTS (SELECT 1 FROM account_channels
+			  WHERE account_id = $1 AND channel_id = $2 AND member = true)`
+	err := db.Get(&member, query, accountID, channelID)
+	return member, err
+}
+
+// ListNonMembers returns the accounts that failed to post in a channel since the cutoff
+func (db *DB) ListNonMembers(since time.Time) ([]models.AccountChannel, error) {
+	var records []models.AccountChannel
+	query := `SELECT * FROM account_channels WHERE member = false AND checked_at >= $1`
+	err := db.Select(&records, query, since)
+	return records, err
+}
+
+// Account Pool Repository
+
+func (db *DB) CreateAccountPool(pool *models.AccountPool) error {
+	query := `INSERT INTO account_pools (id, name, description, daily_limit, proxy_enabled, proxy_host,
+				proxy_port, proxy_username, proxy_password, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+			  RETURNING created_at, updated_at`
+
+	pool.ID = uuid.New()
+	return db.QueryRow(query, pool.ID, pool.Name, pool.Description, pool.DailyLimit, pool.ProxyEnabled,
+		pool.ProxyHost, pool.ProxyPort, pool.ProxyUsername, pool.ProxyPassword).
+		Scan(&pool.CreatedAt, &pool.UpdatedAt)
+}
+
+// GetAccountPool returns the pool with the IDs of its accounts in the order they joined
+func (db *DB) GetAccountPool(id uuid.UUID) (*models.AccountPool, error) {
+	var pool models.AccountPool
+	query := `SELECT * FROM account_pools WHERE id = $1`
+	if err := db.Get(&pool, query, id); err != nil {
+		return nil, err
+	}
+
+	accountIDs, err := db.ListAccountPoolMembers(id)
+	if err != nil {
+		return nil, err
+	}
+	pool.AccountIDs = accountIDs
+	return &pool, nil
+}
+
+// ListAccountPools returns every pool with the IDs of its accounts
+func (db *DB) ListAccountPools() ([]models.AccountPool, error) {
+	pools := []models.AccountPool{}
+	if err := db.Select(&pools, `SELECT * FROM account_pools ORDER BY name`); err != nil {
+		return nil, err
+	}
+
+	var members []models.AccountPoolMember
+	query := `SELECT * FROM account_pool_members ORDER BY added_at, account_id`
+	if err := db.Select(&members, query); err != nil {
+		return nil, err
+	}
+	byPool := make(map[uuid.UUID][]uuid.UUID)
+	for _, member := range members {
+		byPool[member.PoolID] = append(byPool[member.PoolID], member.AccountID)
+	}
+	for i := range pools {
+		pools[i].AccountIDs = byPool[pools[i].ID]
+		if pools[i].AccountIDs == nil {
+			pools[i].AccountIDs = []uuid.UUID{}
+		}
+	}
+	return pools, nil
+}
+
+func (db *DB) UpdateAccountPool(pool *models.AccountPool) error {
+	query := `UPDATE account_pools
+			  SET name = $1, description = $2, daily_limit = $3, proxy_enabled = $4, proxy_host = $5,
+			      proxy_port = $6, proxy_username = $7, proxy_password = $8, updated_at = NOW()
+			  WHERE id = $9`
+	_, err := db.Exec(query, pool.Name, pool.Description, pool.DailyLimit, pool.ProxyEnabled,
+		pool.ProxyHost, pool.ProxyPort, pool.ProxyUsername, pool.ProxyPassword, pool.ID)
+	return err
+}
+
+func (db *DB) DeleteAccountPool(id uuid.UUID) error {
+	query := `DELETE FROM account_pools WHERE id = $1`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+// CountPoolSchedules counts the schedules that send through the pool
+func (db *DB) CountPoolSchedules(poolID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM schedules WHERE account_pool_id = $1`
+	err := db.Get(&count, query, poolID)
+	return count, err
+}
+
+// ListAccountPoolMembers returns the IDs of the pool's accounts in the order they joined
+func (db *DB) ListAccountPoolMembers(poolID uuid.UUID) ([]uuid.UUID, error) {
+	accountIDs := []uuid.UUID{}
+	query := `SELECT account_id FROM account_pool_members WHERE pool_id = $1 ORDER BY added_at, account_id`
+	err := db.Select(&accountIDs, query, poolID)
+	return accountIDs, err
+}
+
+// AddAccountPoolMembers puts the accounts into the pool
+func (db *DB) AddAccountPoolMembers(poolID uuid.UUID, accountIDs []uuid.UUID) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, accountID := range accountIDs {
+		query := `INSERT INTO account_pool_members (pool_id, account_id, added_at)
+				  VALUES ($1, $2, NOW())
+				  ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(query, poolID, accountID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// FindAccountPoolProxy returns the pool with a proxy the account joined first, or nil when
+// none of its pools has a proxy
+func (db *DB) FindAccountPoolProxy(accountID uuid.UUID) (*models.AccountPool, error) {
+	var pool models.AccountPool
+	query := `SELECT p.* FROM account_pools p
+			  JOIN account_pool_members m ON m.pool_id = p.id
+			  WHERE m.account_id = $1 AND p.proxy_enabled
+			  ORDER BY m.added_at, p.id
 			  LIMIT 1`
-	err := db.Get(&account, query)
+	err := db.Get(&pool, query, accountID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &account, nil
+	return &pool, nil
+}
+
+// RemoveAccountPoolMember takes the account out of the pool and reports whether it was in it
+func (db *DB) RemoveAccountPoolMember(poolID, accountID uuid.UUID) (bool, error) {
+	query := `DELETE FROM account_pool_members WHERE pool_id = $1 AND account_id = $2`
+	result, err := db.Exec(query, poolID, accountID)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+// CountAccountPoolUsage counts the messages the pool's schedules sent in (from, to],
+// including uploads to Telegram's scheduled queue due in that period
+func (db *DB) CountAccountPoolUsage(poolID uuid.UUID, from, to time.Time) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM account_sends a
+			  JOIN schedules s ON s.id = a.schedule_id
+			  WHERE s.account_pool_id = $1 AND a.sent_at > $2 AND a.sent_at <= $3`
+	err := db.Get(&count, query, poolID, from.UTC(), to.UTC())
+	return count, err
+}
+
+// ClaimAccountSendSlot lets the account send now and holds back its next send until
+// now+delay, unless an earlier claim still holds it back. It returns when the account may
+// send, which is zero once the slot is taken. Claims of one account on any instance
+// never both succeed within the delay.
+func (db *DB) ClaimAccountSendSlot(accountID uuid.UUID, now time.Time, delay time.Duration) (time.Time, error) {
+	now = now.UTC()
+	query := `UPDATE accounts SET next_allowed_at = $2
+			  WHERE id = $1 AND (next_allowed_at IS NULL OR next_allowed_at <= $3)`
+	result, err := db.Exec(query, accountID, now.Add(delay), now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil || claimed > 0 {
+		return time.Time{}, err
+	}
+
+	var nextAllowedAt models.NullTime
+	if err := db.Get(&nextAllowedAt, `SELECT next_allowed_at FROM accounts WHERE id = $1`, accountID); err != nil {
+		return time.Time{}, err
+	}
+	if !nextAllowedAt.Valid {
+		// Released between the two statements
+		return now, nil
+	}
+	return nextAllowedAt.Time, nil
+}
+
+// Account Send Repository
+
+// RecordAccountSend logs a message the account sent for the schedule, or that Telegram will
+// send for it at sentAt, for its quota windows
+func (db *DB) RecordAccountSend(accountID, channelID, scheduleID uuid.UUID, newChat bool, sentAt time.Time) error {
+	query := `INSERT INTO account_sends (account_id, channel_id, schedule_id, new_chat, sent_at)
+			  VALUES ($1, $2, $3, $4, $5)`
+	_, err := db.Exec(query, accountID, channelID, scheduleID, newChat, sentAt.UTC())
+	return err
+}
+
+// AttachNativeAccountSend ties a reserved send to the Telegram scheduled upload it was made
+// for, so it stops counting once the upload is cancelled
+func (db *DB) AttachNativeAccountSend(id int64, nativeMessageID uuid.UUID) error {
+	_, err := db.Exec(`UPDATE account_sends SET native_message_id = $2 WHERE id = $1`, id, nativeMessageID)
+	return err
+}
+
+// ReserveAccountSend records a send of the account for the schedule at the given time
+// unless allow rejects the account's usage before it or that of the schedule's pool,
+// which is nil when the pool has no daily limit. The account and pool rows stay locked
+// meanwhile, so reservations of one account or pool on any instance never both see room
+// for a single last message. It returns the ID of the reserved send, or 0 when allow
+// refused.
+func (db *DB) ReserveAccountSend(accountID, channelID, scheduleID uuid.UUID, newChat bool, at time.Time, allow func(*models.AccountUsage, *models.PoolUsage) bool) (int64, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT id FROM accounts WHERE id = $1 FOR UPDATE`, accountID); err != nil {
+		return 0, err
+	}
+
+	at = at.UTC()
+	var usage []models.AccountUsage
+	query := accountUsageQuery + ` AND account_id = $4 GROUP BY account_id`
+	if err := tx.Select(&usage, query, at.Add(-24*time.Hour), at.Add(-time.Hour), at, accountID); err != nil {
+		return 0, err
+	}
+	current := &models.AccountUsage{AccountID: accountID}
+	if len(usage) > 0 {
+		current = &usage[0]
+	}
+	pool, err := lockPoolUsage(tx, scheduleID, at)
+	if err != nil {
+		return 0, err
+	}
+	if !allow(current, pool) {
+		return 0, nil
+	}
+
+	var id int64
+	insert := `INSERT INTO account_sends (account_id, channel_id, schedule_id, new_chat, sent_at)
+			   VALUES ($1, $2, $3, $4, $5)
+			   RETURNING id`
+	if err := tx.QueryRow(insert, accountID, channelID, scheduleID, newChat, at).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// lockPoolUsage locks the pool of the schedule if it has a daily limit and returns what its
+// schedules sent in the 24 hours before at, or nil without a limit
+func lockPoolUsage(tx *sqlx.Tx, scheduleID uuid.UUID, at time.Time) (*models.PoolUsage, error) {
+	var pool models.AccountPool
+	query := `SELECT p.* FROM account_pools p
+			  JOIN schedules s ON s.account_pool_id = p.id
+			  WHERE s.id = $1 AND p.daily_limit > 0
+			  FOR UPDATE OF p`
+	if err := tx.Get(&pool, query, scheduleID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	usage := &models.PoolUsage{Pool: &pool}
+	count := `SELECT COUNT(*) AS sent, MIN(a.sent_at) AS oldest FROM account_sends a
+			  JOIN schedules s ON s.id = a.schedule_id
+			  WHERE s.account_pool_id = $1 AND a.sent_at > $2 AND a.sent_at <= $3`
+	if err := tx.Get(usage, count, pool.ID, at.Add(-24*time.Hour), at); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// ReleaseAccountSend gives back a reserved send whose message did not go out
+func (db *DB) ReleaseAccountSend(id int64) error {
+	_, err := db.Exec(`DELETE FROM account_sends WHERE id = $1`, id)
+	return err
+}
+
+// accountUsageQuery counts sends in the day window ($1, $3] and the hour window ($2, $3]
+const accountUsageQuery = `SELECT account_id,
+				COUNT(*) FILTER (WHERE sent_at > $2) AS hourly_sent,
+				COUNT(*) AS daily_sent,
+				COUNT(*) FILTER (WHERE new_chat) AS daily_new_chats,
+				MIN(sent_at) FILTER (WHERE sent_at > $2) AS oldest_hourly,
+				MIN(sent_at) AS oldest_daily,
+				MIN(sent_at) FILTER (WHERE new_chat) AS oldest_new_chat
+			  FROM account_sends
+			  WHERE sent_at > $1 AND sent_at <= $3`
+
+// ListAccountUsage returns the sends of every account that sent anything in the 24 hours
+// before now
+func (db *DB) ListAccountUsage(now time.Time) ([]models.AccountUsage, error) {
+	var usage []models.AccountUsage
+	query := accountUsageQuery + ` GROUP BY account_id`
+	now = now.UTC()
+	err := db.Select(&usage, query, now.Add(-24*time.Hour), now.Add(-time.Hour), now)
+	return usage, err
+}
+
+// GetAccountUsage returns the account's sends in the 24 hours before now
+func (db *DB) GetAccountUsage(accountID uuid.UUID, now time.Time) (*models.AccountUsage, error) {
+	var usage []models.AccountUsage
+	query := accountUsageQuery + ` AND account_id = $4 GROUP BY account_id`
+	now = now.UTC()
+	if err := db.Select(&usage, query, now.Add(-24*time.Hour), now.Add(-time.Hour), now, accountID); err != nil {
+		return nil, err
+	}
+	if len(usage) == 0 {
+		return &models.AccountUsage{AccountID: accountID}, nil
+	}
+	return &usage[0], nil
+}
+
+// PurgeAccountSends removes sends that are older than every quota window
+func (db *DB) PurgeAccountSends(before time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM account_sends WHERE sent_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 // Template Repository
 
 func (db *DB) CreateTemplate(template *models.Template) error {
-	query := `INSERT INTO templates (id, name, content, variables, created_at, updated_at)
-			  VALUES ($1, $2, $3, $4, NOW(), NOW())
+	query := `INSERT INTO templates (id, name, content, variables, media_type, media_payload,
+				copy_from_chat_id, copy_from_message_id, copy_mode, drop_media_captions,
+				copy_album, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
 			  RETURNING id, created_at, updated_at`
 
 	template.ID = uuid.New()
-	return db.QueryRow(query, template.ID, template.Name, template.Content, template.Variables).
+	return db.QueryRow(query, template.ID, template.Name, template.Content, template.Variables,
+		template.MediaType, template.MediaPayload, template.CopyFromChatID,
+		template.CopyFromMessageID, template.CopyMode, template.DropMediaCaptions, template.CopyAlbum).
 		Scan(&template.ID, &template.CreatedAt, &template.UpdatedAt)
 }
 
@@ -161,10 +567,15 @@ func (db *DB) ListTemplates() ([]models.Template, error) {
 
 func (db *DB) UpdateTemplate(template *models.Template) error {
 	query := `UPDATE templates
-			  SET name = $1, content = $2, variables = $3, updated_at = NOW()
-			  WHERE id = $4`
-
-	_, err := db.Exec(query, template.Name, template.Content, template.Variables, template.ID)
+			  SET name = $1, content = $2, variables = $3, media_type = $4, media_payload = $5,
+			      copy_from_chat_id = $6, copy_from_message_id = $7, copy_mode = $8,
+			      drop_media_captions = $9, copy_album = $10, updated_at = NOW()
+			  WHERE id = $11`
+
+	_, err := db.Exec(query, template.Name, template.Content, template.Variables,
+		template.MediaType, template.MediaPayload, template.CopyFromChatID,
+		template.CopyFromMessageID, template.CopyMode, template.DropMediaCaptions,
+		template.CopyAlbum, template.ID)
 	return err
 }
 
@@ -220,18 +631,45 @@ func (db *DB) DeleteChannel(id uuid.UUID) error {
 
 // Schedule Repository
 
+// normalizeScheduleTimes moves the schedule's times to UTC before they are stored, so a
+// time sent with an offset does not read back shifted by it
+func normalizeScheduleTimes(schedule *models.Schedule) {
+	schedule.OnceAt = schedule.OnceAt.UTC()
+	schedule.IntervalAnchor = schedule.IntervalAnchor.UTC()
+	schedule.StartsAt = schedule.StartsAt.UTC()
+	schedule.EndsAt = schedule.EndsAt.UTC()
+	schedule.NextRunAt = schedule.NextRunAt.UTC()
+	schedule.LastRunAt = schedule.LastRunAt.UTC()
+}
+
 func (db *DB) CreateSchedule(schedule *models.Schedule) error {
 	query := `INSERT INTO schedules (id, name, account_id, template_id, channel_ids,
 				cron_expr, timezone, day_filter, custom_days, delay_min_seconds,
-				delay_max_seconds, load_balance, status, created_at, updated_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+				delay_max_seconds, load_balance, status, delivery_mode,
+				schedule_ahead_hours, misfire_policy, misfire_max_runs,
+				misfire_grace_minutes, kind, once_at, interval_seconds, interval_anchor,
+				starts_at, ends_at, max_runs, delay_distribution, typing_action,
+				shuffle_channels, include_calendar_ids, exclude_calendar_ids, retry_max_attempts,
+				retry_base_delay_seconds, retry_max_delay_seconds, rotation_strategy, account_pool_id,
+				fallback_account_ids, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+				$18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35,
+				$36, NOW(), NOW())
 			  RETURNING id, created_at, updated_at`
 
 	schedule.ID = uuid.New()
+	normalizeScheduleTimes(schedule)
 	return db.QueryRow(query, schedule.ID, schedule.Name, schedule.AccountID,
 		schedule.TemplateID, schedule.ChannelIDs, schedule.CronExpr,
 		schedule.Timezone, schedule.DayFilter, schedule.CustomDays,
-		schedule.DelayMinSeconds, schedule.DelayMaxSeconds, schedule.LoadBalance, schedule.Status).
+		schedule.DelayMinSeconds, schedule.DelayMaxSeconds, schedule.LoadBalance, schedule.Status,
+		schedule.DeliveryMode, schedule.ScheduleAheadHours, schedule.MisfirePolicy,
+		schedule.MisfireMaxRuns, schedule.MisfireGraceMinutes, schedule.Kind, schedule.OnceAt,
+		schedule.IntervalSeconds, schedule.IntervalAnchor, schedule.StartsAt, schedule.EndsAt,
+		schedule.MaxRuns, schedule.DelayDistribution, schedule.TypingAction, schedule.ShuffleChannels,
+		schedule.IncludeCalendarIDs, schedule.ExcludeCalendarIDs, schedule.RetryMaxAttempts,
+		schedule.RetryBaseDelaySeconds, schedule.RetryMaxDelaySeconds, schedule.RotationStrategy,
+		schedule.AccountPoolID, schedule.FallbackAccountIDs).
 		Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
 }
 
@@ -259,18 +697,66 @@ func (db *DB) ListActiveSchedules() ([]models.Schedule, error) {
 	return schedules, err
 }
 
+// UpdateSchedule saves the schedule and sets its UpdatedAt to that of the stored version
 func (db *DB) UpdateSchedule(schedule *models.Schedule) error {
 	query := `UPDATE schedules
 			  SET name = $1, channel_ids = $2, cron_expr = $3, timezone = $4,
 			      day_filter = $5, custom_days = $6, delay_min_seconds = $7,
 			      delay_max_seconds = $8, load_balance = $9, status = $10,
-			      next_run_at = $11, last_run_at = $12, updated_at = NOW()
-			  WHERE id = $13`
-
-	_, err := db.Exec(query, schedule.Name, schedule.ChannelIDs, schedule.CronExpr,
+			      next_run_at = $11, last_run_at = $12, delivery_mode = $13,
+			      schedule_ahead_hours = $14, misfire_policy = $15, misfire_max_runs = $16,
+			      misfire_grace_minutes = $17, kind = $18, once_at = $19, interval_seconds = $20,
+			      interval_anchor = $21, starts_at = $22, ends_at = $23, max_runs = $24,
+			      delay_distribution = $25, typing_action = $26, shuffle_channels = $27,
+			      include_calendar_ids = $28, exclude_calendar_ids = $29, retry_max_attempts = $30,
+			      retry_base_delay_seconds = $31, retry_max_delay_seconds = $32, rotation_strategy = $33,
+			      account_id = $34, account_pool_id = $35, fallback_account_ids = $36, updated_at = NOW()
+			  WHERE id = $37
+			  RETURNING updated_at`
+
+	normalizeScheduleTimes(schedule)
+	return db.QueryRow(query, schedule.Name, schedule.ChannelIDs, schedule.CronExpr,
 		schedule.Timezone, schedule.DayFilter, schedule.CustomDays,
 		schedule.DelayMinSeconds, schedule.DelayMaxSeconds, schedule.LoadBalance,
-		schedule.Status, schedule.NextRunAt, schedule.LastRunAt, schedule.ID)
+		schedule.Status, schedule.NextRunAt, schedule.LastRunAt, schedule.DeliveryMode,
+		schedule.ScheduleAheadHours, schedule.MisfirePolicy, schedule.MisfireMaxRuns,
+		schedule.MisfireGraceMinutes, schedule.Kind, schedule.OnceAt, schedule.IntervalSeconds,
+		schedule.IntervalAnchor, schedule.StartsAt, schedule.EndsAt, schedule.MaxRuns,
+		schedule.DelayDistribution, schedule.TypingAction, schedule.ShuffleChannels,
+		schedule.IncludeCalendarIDs, schedule.ExcludeCalendarIDs, schedule.RetryMaxAttempts,
+		schedule.RetryBaseDelaySeconds, schedule.RetryMaxDelaySeconds, schedule.RotationStrategy,
+		schedule.AccountID, schedule.AccountPoolID, schedule.FallbackAccountIDs, schedule.ID).Scan(&schedule.UpdatedAt)
+}
+
+// SetScheduleNextRun updates only next_run_at so scheduler bookkeeping never overwrites API edits
+func (db *DB) SetScheduleNextRun(id uuid.UUID, nextRunAt models.NullTime) error {
+	query := `UPDATE schedules SET next_run_at = $1 WHERE id = $2`
+	nextRunAt.Time = nextRunAt.Time.UTC()
+	_, err := db.Exec(query, nextRunAt, id)
+	return err
+}
+
+// SetScheduleLastRun moves last_run_at forward; catch-up of older runs never moves it back
+func (db *DB) SetScheduleLastRun(id uuid.UUID, lastRunAt time.Time) error {
+	query := `UPDATE schedules SET last_run_at = GREATEST(COALESCE(last_run_at, $1), $1) WHERE id = $2`
+	_, err := db.Exec(query, lastRunAt.UTC(), id)
+	return err
+}
+
+// IncrementScheduleRunCount counts a run toward max_runs and returns the new count
+func (db *DB) IncrementScheduleRunCount(id uuid.UUID) (int, error) {
+	var count int
+	query := `UPDATE schedules SET run_count = run_count + 1 WHERE id = $1 RETURNING run_count`
+	err := db.QueryRow(query, id).Scan(&count)
+	return count, err
+}
+
+// CompleteSchedule marks a schedule that has no runs left as completed
+func (db *DB) CompleteSchedule(id uuid.UUID) error {
+	query := `UPDATE schedules
+			  SET status = 'completed', next_run_at = NULL, updated_at = NOW()
+			  WHERE id = $1 AND status = 'active'`
+	_, err := db.Exec(query, id)
 	return err
 }
 
@@ -280,6 +766,751 @@ func (db *DB) DeleteSchedule(id uuid.UUID) error {
 	return err
 }
 
+// Native Scheduled Message Repository
+
+// CreateNativeScheduledMessage records an upload. Its times are stored in UTC: the columns
+// drop the zone offset, and a run of a zoned schedule would read back shifted.
+func (db *DB) CreateNativeScheduledMessage(msg *models.NativeScheduledMessage) error {
+	query := `INSERT INTO native_scheduled_messages (id, schedule_id, account_id, channel_id,
+				run_at, send_at, telegram_message_ids, fingerprint, status, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+			  RETURNING id, created_at, updated_at`
+
+	msg.ID = uuid.New()
+	msg.RunAt = msg.RunAt.UTC()
+	msg.SendAt = msg.SendAt.UTC()
+	return db.QueryRow(query, msg.ID, msg.ScheduleID, msg.AccountID, msg.ChannelID,
+		msg.RunAt, msg.SendAt, msg.TelegramMessageIDs, msg.Fingerprint, msg.Status).
+		Scan(&msg.ID, &msg.CreatedAt, &msg.UpdatedAt)
+}
+
+// ListPendingNativeScheduledMessages returns messages still waiting in Telegram's queue for a schedule
+func (db *DB) ListPendingNativeScheduledMessages(scheduleID uuid.UUID) ([]models.NativeScheduledMessage, error) {
+	var messages []models.NativeScheduledMessage
+	query := `SELECT * FROM native_scheduled_messages
+			  WHERE schedule_id = $1 AND status = 'scheduled' AND send_at > NOW()
+			  ORDER BY send_at ASC`
+	err := db.Select(&messages, query, scheduleID)
+	return messages, err
+}
+
+// ListOrphanedNativeScheduledMessages returns pending messages whose schedule was deleted,
+// paused or switched back to live delivery
+func (db *DB) ListOrphanedNativeScheduledMessages() ([]models.NativeScheduledMessage, error) {
+	var messages []models.NativeScheduledMessage
+	query := `SELECT m.* FROM native_scheduled_messages m
+			  LEFT JOIN schedules s ON s.id = m.schedule_id
+			  WHERE m.status = 'scheduled' AND m.send_at > NOW()
+			    AND (s.id IS NULL OR s.status != 'active' OR s.delivery_mode != 'telegram')
+			  ORDER BY m.send_at ASC`
+	err := db.Select(&messages, query)
+	return messages, err
+}
+
+// UpdateNativeScheduledMessageStatus sets the status of an upload. A cancelled upload no
+// longer counts toward the quotas of its account.
+func (db *DB) UpdateNativeScheduledMessageStatus(id uuid.UUID, status string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE native_scheduled_messages SET status = $1, updated_at = NOW() WHERE id = $2`
+	if _, err := tx.Exec(query, status, id); err != nil {
+		return err
+	}
+	if status == "cancelled" {
+		if _, err := tx.Exec(`DELETE FROM account_sends WHERE native_message_id = $1`, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MarkNativeScheduledMessagesSent marks messages whose send time has passed as delivered by Telegram
+func (db *DB) MarkNativeScheduledMessagesSent() (int64, error) {
+	query := `UPDATE native_scheduled_messages
+			  SET status = 'sent', updated_at = NOW()
+			  WHERE status = 'scheduled' AND send_at <= NOW()`
+	result, err := db.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Dispatch Job Repository
+
+// CreateDispatchJob inserts a pending job, or a held one when job.Status is held
+func (db *DB) CreateDispatchJob(job *models.DispatchJob) error {
+	query := `INSERT INTO dispatch_jobs (id, schedule_id, account_id, template_id, channel_id,
+				message, idempotency_key, status, next_job_id, run_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+			  RETURNING id, status, created_at, updated_at`
+
+	if job.Status != "held" {
+		job.Status = "pending"
+	}
+	job.ID = uuid.New()
+	return db.QueryRow(query, job.ID, job.ScheduleID, job.AccountID, job.TemplateID,
+		job.ChannelID, job.Message, job.IdempotencyKey, job.Status, job.NextJobID, job.RunAt).
+		Scan(&job.ID, &job.Status, &job.CreatedAt, &job.UpdatedAt)
+}
+
+// ReleaseDispatchJob makes a held job claimable at runAt
+func (db *DB) ReleaseDispatchJob(id uuid.UUID, runAt time.Time) error {
+	query := `UPDATE dispatch_jobs SET status = 'pending', run_at = $1, updated_at = NOW()
+			  WHERE id = $2 AND status = 'held'`
+	_, err := db.Exec(query, runAt, id)
+	return err
+}
+
+// ClaimDispatchJob locks the next due job for a worker. Running jobs whose visibility
+// timeout expired are reclaimed, which recovers work from crashed workers.
+func (db *DB) ClaimDispatchJob(workerID string, visibility time.Duration) (*models.DispatchJob, error) {
+	var job models.DispatchJob
+	query := `UPDATE dispatch_jobs
+			  SET status = 'running',
+			      attempts = attempts + 1,
+			      locked_by = $1,
+			      locked_until = NOW() + ($2 * INTERVAL '1 second'),
+			      updated_at = NOW()
+			  WHERE id = (
+			      SELECT id FROM dispatch_jobs
+			      WHERE (status IN ('pending', 'failed', 'paused') AND run_at <= NOW())
+			         OR (status = 'running' AND locked_until < NOW())
+			      ORDER BY run_at ASC
+			      LIMIT 1
+			      FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING *`
+	err := db.Get(&job, query, workerID, int(visibility.Seconds()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CompleteDispatchJob marks a job locked by the worker as succeeded. This and the other
+// transitions of a claimed job report false when another worker has since claimed it.
+func (db *DB) CompleteDispatchJob(id uuid.UUID, workerID string) (bool, error) {
+	query := `UPDATE dispatch_jobs
+			  SET status = 'succeeded', locked_by = NULL, locked_until = NULL,
+			      last_error = NULL, finished_at = NOW(), updated_at = NOW()
+			  WHERE id = $1 AND locked_by = $2`
+	return db.execAffected(query, id, workerID)
+}
+
+// RetryDispatchJob releases a failed job so it can be claimed again at runAt
+func (db *DB) RetryDispatchJob(id uuid.UUID, workerID string, runAt time.Time, lastError string) (bool, error) {
+	query := `UPDATE dispatch_jobs
+			  SET status = 'failed', run_at = $1, last_error = $2,
+			      locked_by = NULL, locked_until = NULL, updated_at = NOW()
+			  WHERE id = $3 AND locked_by = $4`
+	return db.execAffected(query, runAt, lastError, id, workerID)
+}
+
+// ReassignDispatchJob moves a job to another sending account
+func (db *DB) ReassignDispatchJob(id, accountID uuid.UUID) error {
+	query := `UPDATE dispatch_jobs SET account_id = $1, updated_at = NOW() WHERE id = $2`
+	_, err := db.Exec(query, accountID, id)
+	return err
+}
+
+// ParkDispatchJob puts a claimed job back until runAt without counting the attempt
+func (db *DB) ParkDispatchJob(id uuid.UUID, workerID string, runAt time.Time, reason string) (bool, error) {
+	query := `UPDATE dispatch_jobs
+			  SET status = 'paused', run_at = $1, last_error = $2, attempts = GREATEST(attempts - 1, 0),
+			      locked_by = NULL, locked_until = NULL, updated_at = NOW()
+			  WHERE id = $3 AND locked_by = $4`
+	return db.execAffected(query, runAt, reason, id, workerID)
+}
+
+// KillDispatchJob marks a job as dead after it exhausted its attempts
+func (db *DB) KillDispatchJob(id uuid.UUID, workerID string, lastError string) (bool, error) {
+	query := `UPDATE dispatch_jobs
+			  SET status = 'dead', last_error = $1, locked_by = NULL, locked_until = NULL,
+			      finished_at = NOW(), updated_at = NOW()
+			  WHERE id = $2 AND locked_by = $3`
+	return db.execAffected(query, lastError, id, workerID)
+}
+
+// execAffected runs a statement and reports whether it changed any row
+func (db *DB) execAffected(query string, args ...interface{}) (bool, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ListDeadDispatchJobs returns dead jobs, most recently killed first
+func (db *DB) ListDeadDispatchJobs(scheduleID *uuid.UUID, limit int) ([]models.DispatchJob, error) {
+	jobs := []models.DispatchJob{}
+	query := `SELECT * FROM dispatch_jobs
+			  WHERE status = 'dead' AND ($1::uuid IS NULL OR schedule_id = $1)
+			  ORDER BY finished_at DESC
+			  LIMIT $2`
+	err := db.Select(&jobs, query, scheduleID, limit)
+	return jobs, err
+}
+
+// RequeueDispatchJob makes a dead job due now with its attempts reset. It returns nil
+// when the job is not dead.
+func (db *DB) RequeueDispatchJob(id uuid.UUID) (*models.DispatchJob, error) {
+	var job models.DispatchJob
+	query := `UPDATE dispatch_jobs
+			  SET status = 'pending', attempts = 0, run_at = NOW(), finished_at = NULL, updated_at = NOW()
+			  WHERE id = $1 AND status = 'dead'
+			  RETURNING *`
+	err := db.Get(&job, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// DiscardDispatchJob deletes a dead job and reports whether there was one
+func (db *DB) DiscardDispatchJob(id uuid.UUID) (bool, error) {
+	result, err := db.Exec(`DELETE FROM dispatch_jobs WHERE id = $1 AND status = 'dead'`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// PurgeFinishedDispatchJobs removes succeeded jobs finished before the cutoff
+func (db *DB) PurgeFinishedDispatchJobs(before time.Time) (int64, error) {
+	query := `DELETE FROM dispatch_jobs WHERE status = 'succeeded' AND finished_at < $1`
+	result, err := db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Delivery Repository
+
+// CreateDelivery records a delivery unless one with the same idempotency key exists.
+// It reports whether a new record was created.
+func (db *DB) CreateDelivery(delivery *models.Delivery) (bool, error) {
+	query := `INSERT INTO deliveries (id, idempotency_key, schedule_id, run_id, channel_id, run_at,
+				status, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, 'pending', NOW(), NOW())
+			  ON CONFLICT (idempotency_key) DO NOTHING
+			  RETURNING id, status, created_at, updated_at`
+
+	delivery.ID = uuid.New()
+	delivery.RunAt = delivery.RunAt.UTC()
+	err := db.QueryRow(query, delivery.ID, delivery.IdempotencyKey, delivery.ScheduleID,
+		delivery.RunID, delivery.ChannelID, delivery.RunAt).
+		Scan(&delivery.ID, &delivery.Status, &delivery.CreatedAt, &delivery.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (db *DB) GetDeliveryByKey(key string) (*models.Delivery, error) {
+	var delivery models.Delivery
+	query := `SELECT * FROM deliveries WHERE idempotency_key = $1`
+	err := db.Get(&delivery, query, key)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// StartDeliveryAttempt marks a delivery as being sent; it is written before the Telegram call
+func (db *DB) StartDeliveryAttempt(id, accountID uuid.UUID) error {
+	query := `UPDATE deliveries
+			  SET status = 'sending', account_id = $1, attempts = attempts + 1, updated_at = NOW()
+			  WHERE id = $2`
+	_, err := db.Exec(query, accountID, id)
+	return err
+}
+
+func (db *DB) MarkDeliverySent(id uuid.UUID, messageIDs models.IntList, latency time.Duration) error {
+	query := `UPDATE deliveries
+			  SET status = 'sent', telegram_message_ids = $1, error = NULL, error_category = NULL,
+			      latency_ms = $2, sent_at = NOW(), updated_at = NOW()
+			  WHERE id = $3`
+	_, err := db.Exec(query, messageIDs, latency.Milliseconds(), id)
+	return err
+}
+
+// MarkDeliveryFailed records a failed attempt. A delivery that will be retried is left
+// retrying; otherwise it is failed for good.
+func (db *DB) MarkDeliveryFailed(id uuid.UUID, errorMsg, category string, latency time.Duration, retrying bool) error {
+	status := "failed"
+	if retrying {
+		status = "retrying"
+	}
+	query := `UPDATE deliveries
+			  SET status = $1, error = $2, error_category = $3, latency_ms = $4, updated_at = NOW()
+			  WHERE id = $5`
+	_, err := db.Exec(query, status, errorMsg, category, latency.Milliseconds(), id)
+	return err
+}
+
+// RecordDeliveryFailover notes on the delivery that a fallback account took it over. The
+// account originally assigned is kept across several failovers.
+func (db *DB) RecordDeliveryFailover(key string, from uuid.UUID, reason string) error {
+	query := `UPDATE deliveries
+			  SET failover_from = COALESCE(failover_from, $1), failover_reason = $2, updated_at = NOW()
+			  WHERE idempotency_key = $3`
+	_, err := db.Exec(query, from, reason, key)
+	return err
+}
+
+// ReopenDelivery puts a failed delivery back to retrying when its job is queued again.
+// It returns sql.ErrNoRows when there is no failed delivery with the key.
+func (db *DB) ReopenDelivery(key string) (*models.Delivery, error) {
+	var delivery models.Delivery
+	query := `UPDATE deliveries SET status = 'retrying', updated_at = NOW()
+			  WHERE idempotency_key = $1 AND status = 'failed'
+			  RETURNING *`
+	err := db.Get(&delivery, query, key)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetLastDeliveryAccount returns the account of the schedule's most recent delivery attempt,
+// or nil when there is none
+func (db *DB) GetLastDeliveryAccount(scheduleID uuid.UUID) (*uuid.UUID, error) {
+	var accountID uuid.UUID
+	query := `SELECT account_id FROM deliveries
+			  WHERE schedule_id = $1 AND account_id IS NOT NULL
+			  ORDER BY created_at DESC
+			  LIMIT 1`
+	err := db.Get(&accountID, query, scheduleID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &accountID, nil
+}
+
+// GetChannelSenders maps each channel of the schedule to the account that last delivered to it
+func (db *DB) GetChannelSenders(scheduleID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	var rows []struct {
+		ChannelID uuid.UUID `db:"channel_id"`
+		AccountID uuid.UUID `db:"account_id"`
+	}
+	query := `SELECT DISTINCT ON (channel_id) channel_id, account_id FROM deliveries
+			  WHERE schedule_id = $1 AND status = 'sent' AND account_id IS NOT NULL
+			  ORDER BY channel_id, sent_at DESC`
+	if err := db.Select(&rows, query, scheduleID); err != nil {
+		return nil, err
+	}
+
+	senders := make(map[uuid.UUID]uuid.UUID, len(rows))
+	for _, row := range rows {
+		senders[row.ChannelID] = row.AccountID
+	}
+	return senders, nil
+}
+
+func (db *DB) GetRunDeliveries(runID uuid.UUID) ([]models.Delivery, error) {
+	var deliveries []models.Delivery
+	query := `SELECT * FROM deliveries WHERE run_id = $1 ORDER BY created_at`
+	err := db.Select(&deliveries, query, runID)
+	return deliveries, err
+}
+
+// Blackout Window Repository
+
+func (db *DB) CreateBlackoutWindow(window *models.BlackoutWindow) error {
+	query := `INSERT INTO blackout_windows (id, name, scope, schedule_id, channel_id, kind, weekdays,
+				start_time, end_time, timezone, starts_at, ends_at, policy, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+			  RETURNING id, created_at, updated_at`
+
+	window.ID = uuid.New()
+	window.StartsAt = window.StartsAt.UTC()
+	window.EndsAt = window.EndsAt.UTC()
+	return db.QueryRow(query, window.ID, window.Name, window.Scope, window.ScheduleID,
+		window.ChannelID, window.Kind, window.Weekdays, window.StartTime, window.EndTime,
+		window.Timezone, window.StartsAt, window.EndsAt, window.Policy).
+		Scan(&window.ID, &window.CreatedAt, &window.UpdatedAt)
+}
+
+func (db *DB) GetBlackoutWindow(id uuid.UUID) (*models.BlackoutWindow, error) {
+	var window models.BlackoutWindow
+	query := `SELECT * FROM blackout_windows WHERE id = $1`
+	err := db.Get(&window, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+func (db *DB) ListBlackoutWindows() ([]models.BlackoutWindow, error) {
+	var windows []models.BlackoutWindow
+	query := `SELECT * FROM blackout_windows ORDER BY created_at`
+	err := db.Select(&windows, query)
+	return windows, err
+}
+
+func (db *DB) UpdateBlackoutWindow(window *models.BlackoutWindow) error {
+	query := `UPDATE blackout_windows
+			  SET name = $1, scope = $2, schedule_id = $3, channel_id = $4, kind = $5,
+			      weekdays = $6, start_time = $7, end_time = $8, timezone = $9,
+			      starts_at = $10, ends_at = $11, policy = $12, updated_at = NOW()
+			  WHERE id = $13`
+
+	window.StartsAt = window.StartsAt.UTC()
+	window.EndsAt = window.EndsAt.UTC()
+	_, err := db.Exec(query, window.Name, window.Scope, window.ScheduleID, window.ChannelID,
+		window.Kind, window.Weekdays, window.StartTime, window.EndTime, window.Timezone,
+		window.StartsAt, window.EndsAt, window.Policy, window.ID)
+	return err
+}
+
+func (db *DB) DeleteBlackoutWindow(id uuid.UUID) error {
+	query := `DELETE FROM blackout_windows WHERE id = $1`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+// Pause Repository
+
+// CreatePause sets a pause and records the event. It reports false, without recording
+// anything, when the same scope and target are already paused.
+func (db *DB) CreatePause(pause *models.Pause, event *models.PauseEvent) (bool, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO pauses (id, scope, target_id, reason, paused_by, paused_at)
+			  VALUES ($1, $2, $3, $4, $5, NOW())
+			  ON CONFLICT DO NOTHING
+			  RETURNING paused_at`
+
+	pause.ID = uuid.New()
+	err = tx.QueryRow(query, pause.ID, pause.Scope, pause.TargetID, pause.Reason, pause.PausedBy).
+		Scan(&pause.PausedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := insertPauseEvent(tx, event); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// DeletePause lifts a pause and records the event with the pause's scope and target
+func (db *DB) DeletePause(id uuid.UUID, event *models.PauseEvent) (*models.Pause, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var pause models.Pause
+	if err := tx.Get(&pause, `DELETE FROM pauses WHERE id = $1 RETURNING *`, id); err != nil {
+		return nil, err
+	}
+
+	event.Scope = pause.Scope
+	event.TargetID = pause.TargetID
+	if err := insertPauseEvent(tx, event); err != nil {
+		return nil, err
+	}
+	return &pause, tx.Commit()
+}
+
+func insertPauseEvent(tx *sqlx.Tx, event *models.PauseEvent) error {
+	query := `INSERT INTO pause_events (id, action, scope, target_id, reason, user_id, username, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+			  RETURNING created_at`
+
+	event.ID = uuid.New()
+	return tx.QueryRow(query, event.ID, event.Action, event.Scope, event.TargetID, event.Reason,
+		event.UserID, event.Username).
+		Scan(&event.CreatedAt)
+}
+
+func (db *DB) GetPause(id uuid.UUID) (*models.Pause, error) {
+	var pause models.Pause
+	query := `SELECT * FROM pauses WHERE id = $1`
+	err := db.Get(&pause, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &pause, nil
+}
+
+// FindPause returns the pause of a scope and target; targetID is nil for the global pause
+func (db *DB) FindPause(scope string, targetID *uuid.UUID) (*models.Pause, error) {
+	var pause models.Pause
+	query := `SELECT * FROM pauses WHERE scope = $1 AND target_id IS NOT DISTINCT FROM $2`
+	err := db.Get(&pause, query, scope, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return &pause, nil
+}
+
+func (db *DB) ListPauses() ([]models.Pause, error) {
+	var pauses []models.Pause
+	query := `SELECT * FROM pauses ORDER BY paused_at`
+	err := db.Select(&pauses, query)
+	return pauses, err
+}
+
+func (db *DB) ListPauseEvents(limit int) ([]models.PauseEvent, error) {
+	var events []models.PauseEvent
+	query := `SELECT * FROM pause_events ORDER BY created_at DESC LIMIT $1`
+	err := db.Select(&events, query, limit)
+	return events, err
+}
+
+// Holiday Calendar Repository
+
+// CreateHolidayCalendar stores a calendar together with its days
+func (db *DB) CreateHolidayCalendar(cal *models.HolidayCalendar, days []models.CalendarDay) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO holiday_calendars (id, name, source, path, day_count, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+			  RETURNING id, created_at, updated_at`
+
+	cal.ID = uuid.New()
+	cal.DayCount = len(days)
+	if err := tx.QueryRow(query, cal.ID, cal.Name, cal.Source, cal.Path, cal.DayCount).
+		Scan(&cal.ID, &cal.CreatedAt, &cal.UpdatedAt); err != nil {
+		return err
+	}
+	if err := insertCalendarDays(tx, cal.ID, days); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateHolidayCalendar renames a calendar and replaces all of its days
+func (db *DB) UpdateHolidayCalendar(cal *models.HolidayCalendar, days []models.CalendarDay) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE holiday_calendars
+			  SET name = $1, source = $2, path = $3, day_count = $4, updated_at = NOW()
+			  WHERE id = $5
+			  RETURNING updated_at`
+
+	cal.DayCount = len(days)
+	if err := tx.QueryRow(query, cal.Name, cal.Source, cal.Path, cal.DayCount, cal.ID).
+		Scan(&cal.UpdatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM calendar_days WHERE calendar_id = $1`, cal.ID); err != nil {
+		return err
+	}
+	if err := insertCalendarDays(tx, cal.ID, days); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertCalendarDays(tx *sqlx.Tx, calendarID uuid.UUID, days []models.CalendarDay) error {
+	stmt, err := tx.Prepare(`INSERT INTO calendar_days (calendar_id, day, summary) VALUES ($1, $2, $3)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, day := range days {
+		if _, err := stmt.Exec(calendarID, day.Day.Format("2006-01-02"), day.Summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) GetHolidayCalendar(id uuid.UUID) (*models.HolidayCalendar, error) {
+	var cal models.HolidayCalendar
+	query := `SELECT * FROM holiday_calendars WHERE id = $1`
+	err := db.Get(&cal, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &cal, nil
+}
+
+func (db *DB) GetHolidayCalendarByName(name string) (*models.HolidayCalendar, error) {
+	var cal models.HolidayCalendar
+	query := `SELECT * FROM holiday_calendars WHERE name = $1`
+	err := db.Get(&cal, query, name)
+	if err != nil {
+		return nil, err
+	}
+	return &cal, nil
+}
+
+func (db *DB) ListHolidayCalendars() ([]models.HolidayCalendar, error) {
+	var calendars []models.HolidayCalendar
+	query := `SELECT * FROM holiday_calendars ORDER BY name`
+	err := db.Select(&calendars, query)
+	return calendars, err
+}
+
+func (db *DB) DeleteHolidayCalendar(id uuid.UUID) error {
+	query := `DELETE FROM holiday_calendars WHERE id = $1`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+func (db *DB) ListCalendarDays(calendarID uuid.UUID) ([]models.CalendarDay, error) {
+	var days []models.CalendarDay
+	query := `SELECT * FROM calendar_days WHERE calendar_id = $1 ORDER BY day`
+	err := db.Select(&days, query, calendarID)
+	return days, err
+}
+
+// ListSchedulesUsingCalendar returns the schedules that include or exclude the calendar
+func (db *DB) ListSchedulesUsingCalendar(calendarID uuid.UUID) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	query := `SELECT * FROM schedules
+			  WHERE include_calendar_ids @> jsonb_build_array($1::text)
+			     OR exclude_calendar_ids @> jsonb_build_array($1::text)
+			  ORDER BY name`
+	err := db.Select(&schedules, query, calendarID.String())
+	return schedules, err
+}
+
+// Schedule Run Repository
+
+func (db *DB) CreateScheduleRun(run *models.ScheduleRun) error {
+	query := `INSERT INTO schedule_runs (id, schedule_id, trigger, run_at, started_at, total)
+			  VALUES ($1, $2, $3, $4, NOW(), $5)
+			  RETURNING started_at`
+
+	run.ID = uuid.New()
+	run.RunAt = run.RunAt.UTC()
+	return db.QueryRow(query, run.ID, run.ScheduleID, run.Trigger, run.RunAt, run.Total).
+		Scan(&run.StartedAt)
+}
+
+// SetScheduleRunQueued records how many of the run's channels were queued or skipped as
+// already delivered, and refreshes its totals
+func (db *DB) SetScheduleRunQueued(id uuid.UUID, queued, skipped int) error {
+	query := `UPDATE schedule_runs SET queued = $1, skipped = $2 WHERE id = $3`
+	if _, err := db.Exec(query, queued, skipped, id); err != nil {
+		return err
+	}
+	return db.RefreshScheduleRun(id)
+}
+
+// RefreshScheduleRun recounts the run's deliveries and marks it finished once none of
+// them is still pending or being retried. Channels that never got a delivery count as
+// failed. Runs still being queued are left alone.
+func (db *DB) RefreshScheduleRun(id uuid.UUID) error {
+	query := `WITH counts AS (
+			      SELECT COUNT(*) FILTER (WHERE status = 'sent') AS sent,
+			             COUNT(*) FILTER (WHERE status NOT IN ('sent', 'failed')) AS open
+			      FROM deliveries WHERE run_id = $1
+			  )
+			  UPDATE schedule_runs r
+			  SET sent = counts.sent,
+			      failed = r.total - r.skipped - counts.sent - counts.open,
+			      finished_at = CASE WHEN counts.open = 0 THEN COALESCE(r.finished_at, NOW()) END
+			  FROM counts
+			  WHERE r.id = $1 AND r.queued IS NOT NULL`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+func (db *DB) GetScheduleRun(id uuid.UUID) (*models.ScheduleRun, error) {
+	var run models.ScheduleRun
+	query := `SELECT * FROM schedule_runs WHERE id = $1`
+	err := db.Get(&run, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (db *DB) ListScheduleRuns(scheduleID uuid.UUID, limit int) ([]models.ScheduleRun, error) {
+	var runs []models.ScheduleRun
+	query := `SELECT * FROM schedule_runs WHERE schedule_id = $1
+			  ORDER BY started_at DESC LIMIT $2`
+	err := db.Select(&runs, query, scheduleID, limit)
+	return runs, err
+}
+
+// Scheduler Lease Repository
+
+// AcquireSchedulerLease takes or renews the named lease for holder. It succeeds when the
+// lease is free, expired or already held by holder.
+func (db *DB) AcquireSchedulerLease(name, holder string, ttl time.Duration) (bool, error) {
+	query := `INSERT INTO scheduler_leases (name, holder, acquired_at, renewed_at, expires_at)
+			  VALUES ($1, $2, NOW(), NOW(), NOW() + ($3 * INTERVAL '1 second'))
+			  ON CONFLICT (name) DO UPDATE
+			  SET holder = EXCLUDED.holder,
+			      acquired_at = CASE WHEN scheduler_leases.holder = EXCLUDED.holder
+			                         THEN scheduler_leases.acquired_at ELSE NOW() END,
+			      renewed_at = NOW(),
+			      expires_at = EXCLUDED.expires_at
+			  WHERE scheduler_leases.holder = EXCLUDED.holder OR scheduler_leases.expires_at < NOW()
+			  RETURNING holder`
+
+	var current string
+	err := db.QueryRow(query, name, holder, int(ttl.Seconds())).Scan(&current)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return current == holder, nil
+}
+
+// ReleaseSchedulerLease gives up the lease if holder still owns it
+func (db *DB) ReleaseSchedulerLease(name, holder string) error {
+	query := `DELETE FROM scheduler_leases WHERE name = $1 AND holder = $2`
+	_, err := db.Exec(query, name, holder)
+	return err
+}
+
+func (db *DB) GetSchedulerLease(name string) (*models.SchedulerLease, error) {
+	var lease models.SchedulerLease
+	query := `SELECT * FROM scheduler_leases WHERE name = $1`
+	err := db.Get(&lease, query, name)
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
 // JobLog Repository
 
 func (db *DB) CreateJobLog(log *models.JobLog) error {
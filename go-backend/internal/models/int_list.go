@@ -0,0 +1,32 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// IntList is a list of integers stored as a JSONB array
+type IntList []int
+
+// Scan implements sql.Scanner for JSONB columns
+func (l *IntList) Scan(src interface{}) error {
+	if src == nil {
+		*l = nil
+		return nil
+	}
+
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported int list type %T", src)
+	}
+	return json.Unmarshal(data, l)
+}
+
+// Value implements driver.Valuer for JSONB columns
+func (l IntList) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]int(l))
+}
@@ -75,6 +75,12 @@ func NewNullTime(value time.Time) NullTime {
 	return NullTime{sql.NullTime{Time: value, Valid: true}}
 }
 
+// UTC returns the time in UTC, as TIMESTAMP columns store it without its offset
+func (nt NullTime) UTC() NullTime {
+	nt.Time = nt.Time.UTC()
+	return nt
+}
+
 func (nt NullTime) MarshalJSON() ([]byte, error) {
 	if !nt.Valid {
 		return []byte("null"), nil
@@ -107,3 +113,20 @@ func (nt *NullTime) UnmarshalJSON(data []byte) error {
 func stringNull(data []byte) bool {
 	return strings.EqualFold(string(data), "null")
 }
+
+// Optional is a request field that tells an omitted value from an explicit null, so a
+// partial update can leave a setting alone or clear it
+type Optional[T any] struct {
+	Set   bool // The field was present, possibly as null
+	Value T    // Zero value for null
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if stringNull(data) {
+		var zero T
+		o.Value = zero
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MediaPayload holds structured content for template media types that are not files
+type MediaPayload struct {
+	Poll     *PollPayload     `json:"poll,omitempty"`
+	Location *LocationPayload `json:"location,omitempty"`
+	Contact  *ContactPayload  `json:"contact,omitempty"`
+	Sticker  *StickerPayload  `json:"sticker,omitempty"`
+}
+
+// PollPayload describes a regular poll or a quiz
+type PollPayload struct {
+	Question       string   `json:"question"`
+	Options        []string `json:"options"`
+	Quiz           bool     `json:"quiz"`
+	CorrectOption  int      `json:"correct_option"` // Index into Options, quiz only
+	Solution       string   `json:"solution,omitempty"`
+	Anonymous      *bool    `json:"anonymous,omitempty"` // Defaults to true; channels only accept anonymous polls
+	MultipleChoice bool     `json:"multiple_choice"`
+	ClosePeriod    int      `json:"close_period,omitempty"` // Seconds after posting until the poll closes, 0 to keep it open
+}
+
+// LocationPayload describes a geo point, or a venue when Title is set
+type LocationPayload struct {
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	AccuracyRadius int     `json:"accuracy_radius,omitempty"` // Meters
+	Title          string  `json:"title,omitempty"`
+	Address        string  `json:"address,omitempty"`
+	Provider       string  `json:"provider,omitempty"` // e.g. foursquare, gplaces
+	VenueID        string  `json:"venue_id,omitempty"`
+	VenueType      string  `json:"venue_type,omitempty"`
+}
+
+// ContactPayload describes a contact card
+type ContactPayload struct {
+	PhoneNumber string `json:"phone_number"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name,omitempty"`
+	VCard       string `json:"vcard,omitempty"`
+}
+
+// StickerPayload selects a sticker from a set by emoji or by position
+type StickerPayload struct {
+	SetName string `json:"set_name"`        // Sticker set short name
+	Emoji   string `json:"emoji,omitempty"` // Takes precedence over Index
+	Index   int    `json:"index"`
+}
+
+// IsAnonymous reports whether voters stay hidden, which is the default
+func (p *PollPayload) IsAnonymous() bool {
+	return p.Anonymous == nil || *p.Anonymous
+}
+
+// IsVenue reports whether the location should be sent as a venue
+func (l *LocationPayload) IsVenue() bool {
+	return l.Title != ""
+}
+
+// Validate checks that the payload matches the template media type
+func (p *MediaPayload) Validate(mediaType string) error {
+	switch mediaType {
+	case "poll":
+		if p == nil || p.Poll == nil {
+			return fmt.Errorf("media_payload.poll is required for poll templates")
+		}
+		return p.Poll.validate()
+	case "location":
+		if p == nil || p.Location == nil {
+			return fmt.Errorf("media_payload.location is required for location templates")
+		}
+		return p.Location.validate()
+	case "contact":
+		if p == nil || p.Contact == nil {
+			return fmt.Errorf("media_payload.contact is required for contact templates")
+		}
+		if p.Contact.PhoneNumber == "" || p.Contact.FirstName == "" {
+			return fmt.Errorf("contact requires phone_number and first_name")
+		}
+	case "sticker":
+		if p == nil || p.Sticker == nil {
+			return fmt.Errorf("media_payload.sticker is required for sticker templates")
+		}
+		if p.Sticker.SetName == "" {
+			return fmt.Errorf("sticker requires set_name")
+		}
+		if p.Sticker.Index < 0 {
+			return fmt.Errorf("sticker index must not be negative")
+		}
+	}
+	return nil
+}
+
+func (p *PollPayload) validate() error {
+	if p.Question == "" {
+		return fmt.Errorf("poll question is required")
+	}
+	if len(p.Options) < 2 || len(p.Options) > 10 {
+		return fmt.Errorf("poll must have between 2 and 10 options")
+	}
+	for _, option := range p.Options {
+		if option == "" {
+			return fmt.Errorf("poll options must not be empty")
+		}
+	}
+	if p.Quiz {
+		if p.MultipleChoice {
+			return fmt.Errorf("quiz polls cannot allow multiple answers")
+		}
+		if p.CorrectOption < 0 || p.CorrectOption >= len(p.Options) {
+			return fmt.Errorf("quiz correct_option is out of range")
+		}
+	}
+	if p.ClosePeriod < 0 {
+		return fmt.Errorf("poll close_period must not be negative")
+	}
+	return nil
+}
+
+func (l *LocationPayload) validate() error {
+	if l.Latitude < -90 || l.Latitude > 90 || l.Longitude < -180 || l.Longitude > 180 {
+		return fmt.Errorf("location coordinates are out of range")
+	}
+	if l.IsVenue() && l.Address == "" {
+		return fmt.Errorf("venue requires an address")
+	}
+	return nil
+}
+
+// Scan implements sql.Scanner for JSONB columns
+func (p *MediaPayload) Scan(src interface{}) error {
+	if src == nil {
+		*p = MediaPayload{}
+		return nil
+	}
+
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported media payload type %T", src)
+	}
+	return json.Unmarshal(data, p)
+}
+
+// Value implements driver.Valuer for JSONB columns
+func (p MediaPayload) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
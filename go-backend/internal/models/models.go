@@ -16,8 +16,15 @@ type Account struct {
 	ProxyHost         NullString `db:"proxy_host" json:"proxy_host"`
 	ProxyPort         NullInt64  `db:"proxy_port" json:"proxy_port"`
 	ProxyUsername     NullString `db:"proxy_username" json:"proxy_username"`
-	ProxyPassword     NullString `db:"proxy_password" json:"proxy_password"` // Encrypted
+	ProxyPassword     NullString `db:"proxy_password" json:"-"` // Encrypted
 	MessagesSent      int        `db:"messages_sent" json:"messages_sent"`
+	RotationWeight    int        `db:"rotation_weight" json:"rotation_weight"`           // Share of deliveries under weighted rotation
+	RestrictedUntil   NullTime   `db:"restricted_until" json:"restricted_until"`         // Left out of rotation until then after a flood wait or spam limit
+	RestrictionReason NullString `db:"restriction_reason" json:"restriction_reason"`     // Telegram error that caused the restriction
+	HourlyQuota       int        `db:"hourly_quota" json:"hourly_quota"`                 // Messages per rolling hour, 0 for no limit
+	DailyQuota        int        `db:"daily_quota" json:"daily_quota"`                   // Messages per rolling 24 hours, 0 for no limit
+	DailyNewChatQuota int        `db:"daily_new_chat_quota" json:"daily_new_chat_quota"` // First posts in a chat per rolling 24 hours, 0 for no limit
+	NextAllowedAt     NullTime   `db:"next_allowed_at" json:"next_allowed_at"`           // Earliest next send, pacing the sends of all its schedules
 	LastUsedAt        NullTime   `db:"last_used_at" json:"last_used_at"`
 	LastLoginAt       NullTime   `db:"last_login_at" json:"last_login_at"`
 	ErrorMessage      NullString `db:"error_message" json:"error_message,omitempty"`
@@ -27,20 +34,56 @@ type Account struct {
 	TwoFactorHint     NullString `db:"two_factor_hint" json:"two_factor_hint"`
 	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt         time.Time  `db:"updated_at" json:"updated_at"`
+
+	// Warm-up program that ramps up the daily cap of a freshly linked account
+	WarmupStartedAt      NullTime `db:"warmup_started_at" json:"warmup_started_at"` // Null when the account is not warming up
+	WarmupDays           int      `db:"warmup_days" json:"warmup_days"`
+	WarmupCurve          string   `db:"warmup_curve" json:"warmup_curve"` // linear, quadratic, square_root
+	WarmupStartLimit     int      `db:"warmup_start_limit" json:"warmup_start_limit"`
+	WarmupTargetLimit    int      `db:"warmup_target_limit" json:"warmup_target_limit"`
+	WarmupActivity       bool     `db:"warmup_activity" json:"warmup_activity"` // Goes online and reads dialogs now and then
+	WarmupNextActivityAt NullTime `db:"warmup_next_activity_at" json:"warmup_next_activity_at"`
+
+	Usage  *AccountUsage  `db:"-" json:"usage,omitempty"`  // Sends in the quota windows, filled in by the API
+	Warmup *AccountWarmup `db:"-" json:"warmup,omitempty"` // Current warm-up day and cap, filled in by the API
+}
+
+// AccountUsage counts what an account sent in its rolling quota windows
+type AccountUsage struct {
+	AccountID     uuid.UUID `db:"account_id" json:"-"`
+	HourlySent    int       `db:"hourly_sent" json:"hourly_sent"`
+	DailySent     int       `db:"daily_sent" json:"daily_sent"`
+	DailyNewChats int       `db:"daily_new_chats" json:"daily_new_chats"`
+	// Oldest sends still in each window; the window frees up when they age out
+	OldestHourly  NullTime `db:"oldest_hourly" json:"-"`
+	OldestDaily   NullTime `db:"oldest_daily" json:"-"`
+	OldestNewChat NullTime `db:"oldest_new_chat" json:"-"`
+}
+
+// PoolUsage is what the schedules of an account pool with a daily limit sent in the 24
+// hours before a reservation
+type PoolUsage struct {
+	Pool   *AccountPool `db:"-"`
+	Sent   int          `db:"sent"`
+	Oldest NullTime     `db:"oldest"` // Oldest send still in the window
 }
 
 // Template represents a message template
 type Template struct {
-	ID                uuid.UUID  `db:"id" json:"id"`
-	Name              string     `db:"name" json:"name"`
-	Content           string     `db:"content" json:"content"`
-	Variables         []string   `db:"variables" json:"variables"`                       // JSON array of variable names
-	MediaType         NullString `db:"media_type" json:"media_type"`                     // photo, video, document, album
-	MediaUrls         []string   `db:"media_urls" json:"media_urls"`                     // JSON array of media URLs
-	CopyFromChatID    NullString `db:"copy_from_chat_id" json:"copy_from_chat_id"`       // Source chat for copying
-	CopyFromMessageID NullInt64  `db:"copy_from_message_id" json:"copy_from_message_id"` // Source message ID
-	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt         time.Time  `db:"updated_at" json:"updated_at"`
+	ID                uuid.UUID    `db:"id" json:"id"`
+	Name              string       `db:"name" json:"name"`
+	Content           string       `db:"content" json:"content"`
+	Variables         []string     `db:"variables" json:"variables"`                       // JSON array of variable names
+	MediaType         NullString   `db:"media_type" json:"media_type"`                     // photo, video, document, album, poll, location, contact, sticker
+	MediaUrls         []string     `db:"media_urls" json:"media_urls"`                     // JSON array of media URLs
+	MediaPayload      MediaPayload `db:"media_payload" json:"media_payload"`               // Poll, location, contact or sticker content
+	CopyFromChatID    NullString   `db:"copy_from_chat_id" json:"copy_from_chat_id"`       // Source chat for copying
+	CopyFromMessageID NullInt64    `db:"copy_from_message_id" json:"copy_from_message_id"` // Source message ID
+	CopyMode          NullString   `db:"copy_mode" json:"copy_mode"`                       // forward, copy, resend
+	DropMediaCaptions bool         `db:"drop_media_captions" json:"drop_media_captions"`   // Strip captions when copying
+	CopyAlbum         bool         `db:"copy_album" json:"copy_album"`                     // Copy the whole grouped album
+	CreatedAt         time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time    `db:"updated_at" json:"updated_at"`
 }
 
 // Channel represents a Telegram channel/chat target
@@ -55,30 +98,232 @@ type Channel struct {
 
 // Schedule represents a scheduled message job
 type Schedule struct {
-	ID              uuid.UUID  `db:"id" json:"id"`
-	Name            string     `db:"name" json:"name"`
-	AccountID       uuid.UUID  `db:"account_id" json:"account_id"`
-	TemplateID      uuid.UUID  `db:"template_id" json:"template_id"`
-	ChannelIDs      []string   `db:"channel_ids" json:"channel_ids"` // JSON array of channel UUIDs
-	CronExpr        string     `db:"cron_expr" json:"cron_expr"`
-	Timezone        string     `db:"timezone" json:"timezone"`                   // e.g., "Europe/Moscow"
-	DayFilter       NullString `db:"day_filter" json:"day_filter"`               // all, weekdays, weekends, custom
-	CustomDays      []int      `db:"custom_days" json:"custom_days"`             // [1,3,5] for Mon,Wed,Fri (0=Sunday)
-	DelayMinSeconds int        `db:"delay_min_seconds" json:"delay_min_seconds"` // Min delay between messages
-	DelayMaxSeconds int        `db:"delay_max_seconds" json:"delay_max_seconds"` // Max delay between messages
-	LoadBalance     bool       `db:"load_balance" json:"load_balance"`           // Use account rotation
-	Status          string     `db:"status" json:"status"`                       // active, paused, completed
-	NextRunAt       NullTime   `db:"next_run_at" json:"next_run_at"`
-	LastRunAt       NullTime   `db:"last_run_at" json:"last_run_at"`
-	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
+	ID                    uuid.UUID  `db:"id" json:"id"`
+	Name                  string     `db:"name" json:"name"`
+	AccountID             *uuid.UUID `db:"account_id" json:"account_id"`                     // Sending account; NULL when the schedule targets a pool
+	AccountPoolID         *uuid.UUID `db:"account_pool_id" json:"account_pool_id"`           // Pool whose accounts rotate through the deliveries
+	FallbackAccountIDs    UUIDList   `db:"fallback_account_ids" json:"fallback_account_ids"` // Accounts that take over, in order, when the sending account fails
+	TemplateID            uuid.UUID  `db:"template_id" json:"template_id"`
+	ChannelIDs            StringList `db:"channel_ids" json:"channel_ids"` // JSON array of channel UUIDs
+	Kind                  string     `db:"kind" json:"kind"`               // cron, once, interval
+	CronExpr              string     `db:"cron_expr" json:"cron_expr"`
+	OnceAt                NullTime   `db:"once_at" json:"once_at"`                   // Run time of a once schedule
+	IntervalSeconds       int        `db:"interval_seconds" json:"interval_seconds"` // Period of an interval schedule
+	IntervalAnchor        NullTime   `db:"interval_anchor" json:"interval_anchor"`   // Interval runs fall on anchor + k*period
+	StartsAt              NullTime   `db:"starts_at" json:"starts_at"`               // No runs before this time
+	EndsAt                NullTime   `db:"ends_at" json:"ends_at"`                   // No runs after this time
+	MaxRuns               int        `db:"max_runs" json:"max_runs"`                 // 0 means unlimited; live delivery only
+	RunCount              int        `db:"run_count" json:"run_count"`
+	Timezone              string     `db:"timezone" json:"timezone"`                                 // e.g., "Europe/Moscow"
+	DayFilter             NullString `db:"day_filter" json:"day_filter"`                             // all, weekdays, weekends, custom
+	CustomDays            IntList    `db:"custom_days" json:"custom_days"`                           // [1,3,5] for Mon,Wed,Fri (0=Sunday)
+	IncludeCalendarIDs    UUIDList   `db:"include_calendar_ids" json:"include_calendar_ids"`         // Holiday calendars whose dates always run
+	ExcludeCalendarIDs    UUIDList   `db:"exclude_calendar_ids" json:"exclude_calendar_ids"`         // Holiday calendars whose dates never run
+	DelayMinSeconds       int        `db:"delay_min_seconds" json:"delay_min_seconds"`               // Min delay between messages
+	DelayMaxSeconds       int        `db:"delay_max_seconds" json:"delay_max_seconds"`               // Max delay between messages
+	DelayDistribution     string     `db:"delay_distribution" json:"delay_distribution"`             // uniform, normal
+	TypingAction          bool       `db:"typing_action" json:"typing_action"`                       // Show typing before each send
+	ShuffleChannels       bool       `db:"shuffle_channels" json:"shuffle_channels"`                 // Send to channels in random order
+	RetryMaxAttempts      int        `db:"retry_max_attempts" json:"retry_max_attempts"`             // Send attempts per delivery, the first included
+	RetryBaseDelaySeconds int        `db:"retry_base_delay_seconds" json:"retry_base_delay_seconds"` // Backoff before the first retry
+	RetryMaxDelaySeconds  int        `db:"retry_max_delay_seconds" json:"retry_max_delay_seconds"`   // Cap of the exponential backoff
+	LoadBalance           bool       `db:"load_balance" json:"load_balance"`                         // Rotate through every account when no pool is set
+	RotationStrategy      string     `db:"rotation_strategy" json:"rotation_strategy"`               // least_used, least_recently_used, round_robin, weighted, sticky, random
+	DeliveryMode          string     `db:"delivery_mode" json:"delivery_mode"`                       // live, telegram
+	ScheduleAheadHours    int        `db:"schedule_ahead_hours" json:"schedule_ahead_hours"`         // Horizon for Telegram scheduled delivery
+	MisfirePolicy         string     `db:"misfire_policy" json:"misfire_policy"`                     // skip, run_once, run_all, run_if_recent
+	MisfireMaxRuns        int        `db:"misfire_max_runs" json:"misfire_max_runs"`                 // Cap for run_all
+	MisfireGraceMinutes   int        `db:"misfire_grace_minutes" json:"misfire_grace_minutes"`       // Window for run_if_recent
+	Status                string     `db:"status" json:"status"`                                     // active, paused, completed
+	NextRunAt             NullTime   `db:"next_run_at" json:"next_run_at"`
+	LastRunAt             NullTime   `db:"last_run_at" json:"last_run_at"`
+	CreatedAt             time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt             time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// NativeScheduledMessage tracks a delivery uploaded to Telegram's own scheduled queue
+type NativeScheduledMessage struct {
+	ID                 uuid.UUID  `db:"id" json:"id"`
+	ScheduleID         *uuid.UUID `db:"schedule_id" json:"schedule_id"` // NULL once the schedule is deleted
+	AccountID          uuid.UUID  `db:"account_id" json:"account_id"`
+	ChannelID          uuid.UUID  `db:"channel_id" json:"channel_id"`
+	RunAt              time.Time  `db:"run_at" json:"run_at"`                             // Cron fire time the message belongs to
+	SendAt             time.Time  `db:"send_at" json:"send_at"`                           // schedule_date passed to Telegram
+	TelegramMessageIDs IntList    `db:"telegram_message_ids" json:"telegram_message_ids"` // Scheduled message IDs in the chat
+	Fingerprint        string     `db:"fingerprint" json:"fingerprint"`                   // Schedule and template version
+	Status             string     `db:"status" json:"status"`                             // scheduled, sent, cancelled
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// DispatchJob is a single message delivery persisted in the dispatcher queue
+type DispatchJob struct {
+	ID         uuid.UUID `db:"id" json:"id"`
+	ScheduleID uuid.UUID `db:"schedule_id" json:"schedule_id"`
+	AccountID  uuid.UUID `db:"account_id" json:"account_id"`
+	TemplateID uuid.UUID `db:"template_id" json:"template_id"`
+	ChannelID  uuid.UUID `db:"channel_id" json:"channel_id"`
+	Message    string    `db:"message" json:"message"`
+	// IdempotencyKey identifies the (schedule run, channel) delivery the job performs
+	IdempotencyKey string `db:"idempotency_key" json:"idempotency_key"`
+	Status         string `db:"status" json:"status"` // held, pending, running, paused, succeeded, failed, dead
+	// NextJobID is the job of the same run that is held until this one finishes
+	NextJobID   *uuid.UUID `db:"next_job_id" json:"next_job_id"`
+	Attempts    int        `db:"attempts" json:"attempts"`
+	RunAt       time.Time  `db:"run_at" json:"run_at"`             // Not claimed before this time
+	LockedBy    NullString `db:"locked_by" json:"locked_by"`       // Worker holding the job
+	LockedUntil NullTime   `db:"locked_until" json:"locked_until"` // Visibility timeout for running jobs
+	LastError   NullString `db:"last_error" json:"last_error,omitempty"`
+	FinishedAt  NullTime   `db:"finished_at" json:"finished_at"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Delivery is one (schedule run, channel) message delivery, recorded before it is sent
+type Delivery struct {
+	ID                 uuid.UUID  `db:"id" json:"id"`
+	IdempotencyKey     string     `db:"idempotency_key" json:"idempotency_key"`
+	ScheduleID         uuid.UUID  `db:"schedule_id" json:"schedule_id"`
+	RunID              *uuid.UUID `db:"run_id" json:"run_id"`
+	ChannelID          uuid.UUID  `db:"channel_id" json:"channel_id"`
+	AccountID          *uuid.UUID `db:"account_id" json:"account_id"`                     // Account of the last attempt
+	FailoverFrom       *uuid.UUID `db:"failover_from" json:"failover_from"`               // Account originally assigned, when a fallback took over
+	FailoverReason     NullString `db:"failover_reason" json:"failover_reason,omitempty"` // Why the latest fallback took over
+	RunAt              time.Time  `db:"run_at" json:"run_at"`                             // Schedule run the delivery belongs to
+	Status             string     `db:"status" json:"status"`                             // pending, sending, retrying, sent, failed
+	Attempts           int        `db:"attempts" json:"attempts"`
+	TelegramMessageIDs IntList    `db:"telegram_message_ids" json:"telegram_message_ids"`
+	Error              NullString `db:"error" json:"error,omitempty"`
+	ErrorCategory      NullString `db:"error_category" json:"error_category,omitempty"` // flood_wait, peer, permission, session, network, internal
+	LatencyMs          NullInt64  `db:"latency_ms" json:"latency_ms"`                   // Duration of the last attempt
+	SentAt             NullTime   `db:"sent_at" json:"sent_at"`
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// BlackoutWindow is a period in which nothing may be posted. Recurring windows repeat on
+// the given weekdays between StartTime and EndTime in Timezone and may cross midnight;
+// absolute windows cover StartsAt to EndsAt.
+type BlackoutWindow struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	Name       string     `db:"name" json:"name"`
+	Scope      string     `db:"scope" json:"scope"` // global, schedule, channel
+	ScheduleID *uuid.UUID `db:"schedule_id" json:"schedule_id"`
+	ChannelID  *uuid.UUID `db:"channel_id" json:"channel_id"`
+	Kind       string     `db:"kind" json:"kind"`             // recurring, absolute
+	Weekdays   IntList    `db:"weekdays" json:"weekdays"`     // 0=Sunday; empty means every day
+	StartTime  NullString `db:"start_time" json:"start_time"` // HH:MM, recurring windows
+	EndTime    NullString `db:"end_time" json:"end_time"`     // HH:MM, recurring windows
+	Timezone   string     `db:"timezone" json:"timezone"`
+	StartsAt   NullTime   `db:"starts_at" json:"starts_at"` // Absolute windows
+	EndsAt     NullTime   `db:"ends_at" json:"ends_at"`
+	Policy     string     `db:"policy" json:"policy"` // skip, defer (to the end of the window)
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// AccountPool is a named group of accounts that schedules send through, so separate
+// projects keep to their own accounts
+type AccountPool struct {
+	ID            uuid.UUID   `db:"id" json:"id"`
+	Name          string      `db:"name" json:"name"`
+	Description   NullString  `db:"description" json:"description"`
+	DailyLimit    int         `db:"daily_limit" json:"daily_limit"` // Messages the pool's schedules may send per 24 hours, 0 for no limit
+	ProxyEnabled  bool        `db:"proxy_enabled" json:"proxy_enabled"`
+	ProxyHost     NullString  `db:"proxy_host" json:"proxy_host"`
+	ProxyPort     NullInt64   `db:"proxy_port" json:"proxy_port"`
+	ProxyUsername NullString  `db:"proxy_username" json:"proxy_username"`
+	ProxyPassword NullString  `db:"proxy_password" json:"-"` // Encrypted
+	AccountIDs    []uuid.UUID `db:"-" json:"account_ids"`
+	CreatedAt     time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time   `db:"updated_at" json:"updated_at"`
+}
+
+// AccountPoolMember places an account in a pool; an account may belong to several pools
+type AccountPoolMember struct {
+	PoolID    uuid.UUID `db:"pool_id" json:"pool_id"`
+	AccountID uuid.UUID `db:"account_id" json:"account_id"`
+	AddedAt   time.Time `db:"added_at" json:"added_at"`
+}
+
+// AccountChannel records whether an account could post in a channel the last time it tried
+type AccountChannel struct {
+	AccountID uuid.UUID  `db:"account_id" json:"account_id"`
+	ChannelID uuid.UUID  `db:"channel_id" json:"channel_id"`
+	Member    bool       `db:"member" json:"member"`
+	Reason    NullString `db:"reason" json:"reason,omitempty"` // Telegram error of a failed send
+	CheckedAt time.Time  `db:"checked_at" json:"checked_at"`
+}
+
+// Pause stops sending globally, for one account or for one channel until it is removed
+type Pause struct {
+	ID       uuid.UUID  `db:"id" json:"id"`
+	Scope    string     `db:"scope" json:"scope"`         // global, account, channel
+	TargetID *uuid.UUID `db:"target_id" json:"target_id"` // Account or channel; empty for global
+	Reason   NullString `db:"reason" json:"reason"`
+	PausedBy *uuid.UUID `db:"paused_by" json:"paused_by"`
+	PausedAt time.Time  `db:"paused_at" json:"paused_at"`
+}
+
+// PauseEvent is an audit record of a pause being set or lifted
+type PauseEvent struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Action    string     `db:"action" json:"action"` // pause, resume
+	Scope     string     `db:"scope" json:"scope"`
+	TargetID  *uuid.UUID `db:"target_id" json:"target_id"`
+	Reason    NullString `db:"reason" json:"reason"`
+	UserID    *uuid.UUID `db:"user_id" json:"user_id"`
+	Username  NullString `db:"username" json:"username"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// HolidayCalendar is a named set of dates imported from an .ics file
+type HolidayCalendar struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name"`
+	Source    string     `db:"source" json:"source"` // upload, path
+	Path      NullString `db:"path" json:"path"`     // File the calendar was imported from, for path imports
+	DayCount  int        `db:"day_count" json:"day_count"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// CalendarDay is a date covered by a holiday calendar
+type CalendarDay struct {
+	CalendarID uuid.UUID `db:"calendar_id" json:"calendar_id"`
+	Day        time.Time `db:"day" json:"day"`
+	Summary    string    `db:"summary" json:"summary"`
+}
+
+// ScheduleRun is one execution of a schedule and the totals of its deliveries
+type ScheduleRun struct {
+	ID         uuid.UUID `db:"id" json:"id"`
+	ScheduleID uuid.UUID `db:"schedule_id" json:"schedule_id"`
+	Trigger    string    `db:"trigger" json:"trigger"` // cron, misfire, manual
+	RunAt      time.Time `db:"run_at" json:"run_at"`   // Fire time the run stands for
+	StartedAt  time.Time `db:"started_at" json:"started_at"`
+	FinishedAt NullTime  `db:"finished_at" json:"finished_at"` // Set once every delivery is sent or failed
+	Total      int       `db:"total" json:"total"`             // Channels targeted
+	Queued     NullInt64 `db:"queued" json:"queued"`           // Set once every channel was handed to the dispatcher
+	Skipped    int       `db:"skipped" json:"skipped"`         // Already delivered by an earlier run or blacked out
+	Sent       int       `db:"sent" json:"sent"`
+	Failed     int       `db:"failed" json:"failed"` // Includes channels that could not be queued
+}
+
+// SchedulerLease records which backend instance currently runs the cron scheduler
+type SchedulerLease struct {
+	Name       string    `db:"name" json:"name"`
+	Holder     string    `db:"holder" json:"holder"` // Instance ID of the leader
+	AcquiredAt time.Time `db:"acquired_at" json:"acquired_at"`
+	RenewedAt  time.Time `db:"renewed_at" json:"renewed_at"`
+	ExpiresAt  time.Time `db:"expires_at" json:"expires_at"`
 }
 
 // JobLog represents execution history
 type JobLog struct {
 	ID         uuid.UUID  `db:"id" json:"id"`
 	ScheduleID uuid.UUID  `db:"schedule_id" json:"schedule_id"`
-	Status     string     `db:"status" json:"status"` // success, failed, retry
+	Status     string     `db:"status" json:"status"` // success, failed, retry, misfire, completed, manual, blackout, paused
 	Message    NullString `db:"message" json:"message,omitempty"`
 	Error      NullString `db:"error" json:"error,omitempty"`
 	ExecutedAt time.Time  `db:"executed_at" json:"executed_at"`
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringList is a list of strings stored as a JSONB array
+type StringList []string
+
+// Scan implements sql.Scanner for JSONB columns
+func (l *StringList) Scan(src interface{}) error {
+	if src == nil {
+		*l = nil
+		return nil
+	}
+
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported string list type %T", src)
+	}
+	return json.Unmarshal(data, l)
+}
+
+// Value implements driver.Valuer for JSONB columns
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(l))
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// UUIDList is a list of UUIDs stored as a JSONB array of strings
+type UUIDList []uuid.UUID
+
+// Scan implements sql.Scanner for JSONB columns
+func (l *UUIDList) Scan(src interface{}) error {
+	if src == nil {
+		*l = nil
+		return nil
+	}
+
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported uuid list type %T", src)
+	}
+	return json.Unmarshal(data, l)
+}
+
+// Value implements driver.Valuer for JSONB columns
+func (l UUIDList) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]uuid.UUID(l))
+}
+
+// Contains reports whether id is in the list
+func (l UUIDList) Contains(id uuid.UUID) bool {
+	for _, item := range l {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Warm-up curves: how an account's daily cap climbs from its start to its target limit
+const (
+	WarmupCurveLinear     = "linear"
+	WarmupCurveQuadratic  = "quadratic"   // Square of the progress: slow at first, steep near the end
+	WarmupCurveSquareRoot = "square_root" // Square root of the progress: steep at first, slow near the end
+)
+
+// ValidWarmupCurve reports whether the curve is one the warm-up supports
+func ValidWarmupCurve(curve string) bool {
+	switch curve {
+	case WarmupCurveLinear, WarmupCurveQuadratic, WarmupCurveSquareRoot:
+		return true
+	}
+	return false
+}
+
+// AccountWarmup is where an account stands in its warm-up program
+type AccountWarmup struct {
+	Day      int       `json:"day"` // Starting at 1
+	Days     int       `json:"days"`
+	DailyCap int       `json:"daily_cap"` // Messages allowed per rolling 24 hours today
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// WarmupAt returns the account's warm-up state at the given time, or nil when the account
+// is not warming up or its program is over. The cap starts at the start limit on the first
+// day and follows the curve up to the target limit on the last; afterwards only the
+// account's own quotas apply.
+func (a *Account) WarmupAt(now time.Time) *AccountWarmup {
+	if !a.WarmupStartedAt.Valid || a.WarmupDays <= 0 {
+		return nil
+	}
+	day := int(now.Sub(a.WarmupStartedAt.Time) / (24 * time.Hour))
+	if day < 0 {
+		day = 0
+	}
+	if day >= a.WarmupDays {
+		return nil
+	}
+
+	progress := 1.0
+	if a.WarmupDays > 1 {
+		progress = float64(day) / float64(a.WarmupDays-1)
+	}
+	switch a.WarmupCurve {
+	case WarmupCurveQuadratic:
+		progress *= progress
+	case WarmupCurveSquareRoot:
+		progress = math.Sqrt(progress)
+	}
+	ramp := float64(a.WarmupTargetLimit - a.WarmupStartLimit)
+	return &AccountWarmup{
+		Day:      day + 1,
+		Days:     a.WarmupDays,
+		DailyCap: a.WarmupStartLimit + int(math.Round(ramp*progress)),
+		EndsAt:   a.WarmupStartedAt.Time.Add(time.Duration(a.WarmupDays) * 24 * time.Hour),
+	}
+}
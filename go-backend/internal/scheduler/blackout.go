@@ -0,0 +1,222 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// blackoutScanLimit bounds how many back-to-back windows a deferral follows
+const blackoutScanLimit = 100
+
+// blackout is the effect of blackout windows on a send time
+type blackout struct {
+	window *models.BlackoutWindow // First window that applies
+	skip   bool
+	until  time.Time // Deferred send time, unless skipped
+}
+
+// blackoutSet holds the blackout windows that apply to one schedule
+type blackoutSet struct {
+	run     []models.BlackoutWindow               // Global and schedule windows
+	channel map[uuid.UUID][]models.BlackoutWindow // Channel windows by channel ID
+}
+
+// loadBlackouts returns the windows that apply to a schedule and its channels.
+// An unsaved schedule only gets the global windows.
+func (s *Scheduler) loadBlackouts(schedule *models.Schedule) (*blackoutSet, error) {
+	windows, err := s.db.ListBlackoutWindows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blackout windows: %w", err)
+	}
+
+	set := &blackoutSet{channel: make(map[uuid.UUID][]models.BlackoutWindow)}
+	for _, window := range windows {
+		switch {
+		case window.Scope == "global":
+			set.run = append(set.run, window)
+		case window.Scope == "schedule" && window.ScheduleID != nil && *window.ScheduleID == schedule.ID && schedule.ID != uuid.Nil:
+			set.run = append(set.run, window)
+		case window.Scope == "channel" && window.ChannelID != nil:
+			set.channel[*window.ChannelID] = append(set.channel[*window.ChannelID], window)
+		}
+	}
+	return set, nil
+}
+
+// forRun applies the global and schedule windows to a run due at t
+func (b *blackoutSet) forRun(t time.Time) *blackout {
+	if b == nil {
+		return nil
+	}
+	return blackoutAt(b.run, t)
+}
+
+// forChannel applies every window that concerns a channel to a send at t
+func (b *blackoutSet) forChannel(channelID uuid.UUID, t time.Time) *blackout {
+	if b == nil {
+		return nil
+	}
+	windows := append(append([]models.BlackoutWindow(nil), b.run...), b.channel[channelID]...)
+	return blackoutAt(windows, t)
+}
+
+// blackoutAt applies windows to a send at t. A skip window wins over defer windows; a
+// deferral follows overlapping and adjacent windows to the end of the last one, and is
+// skipped if it lands in a skip window.
+func blackoutAt(windows []models.BlackoutWindow, t time.Time) *blackout {
+	var result *blackout
+	at := t
+	for i := 0; i < blackoutScanLimit; i++ {
+		extended := false
+		for j := range windows {
+			window := &windows[j]
+			end, ok := windowEnd(window, at)
+			if !ok {
+				continue
+			}
+			if window.Policy == "skip" {
+				return &blackout{window: window, skip: true}
+			}
+			if result == nil {
+				result = &blackout{window: window}
+			}
+			if end.After(at) {
+				at = end
+				extended = true
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+
+	if result != nil {
+		result.until = at
+	}
+	return result
+}
+
+// windowEnd reports whether a window covers t and, if so, when that occurrence ends
+func windowEnd(window *models.BlackoutWindow, t time.Time) (time.Time, bool) {
+	if window.Kind == "absolute" {
+		if window.StartsAt.Valid && window.EndsAt.Valid &&
+			!t.Before(window.StartsAt.Time) && t.Before(window.EndsAt.Time) {
+			return window.EndsAt.Time, true
+		}
+		return time.Time{}, false
+	}
+
+	loc, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	startHour, startMinute, err := parseClock(window.StartTime.String)
+	if err != nil {
+		return time.Time{}, false
+	}
+	endHour, endMinute, err := parseClock(window.EndTime.String)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// An occurrence that started the day before may still be running past midnight
+	local := t.In(loc)
+	for _, offset := range []int{0, -1} {
+		year, month, day := local.AddDate(0, 0, offset).Date()
+		start := time.Date(year, month, day, startHour, startMinute, 0, 0, loc)
+		if !weekdayAllowed(window.Weekdays, start.Weekday()) {
+			continue
+		}
+
+		end := time.Date(year, month, day, endHour, endMinute, 0, 0, loc)
+		if !end.After(start) {
+			end = time.Date(year, month, day+1, endHour, endMinute, 0, 0, loc)
+		}
+		if !t.Before(start) && t.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func weekdayAllowed(weekdays []int, weekday time.Weekday) bool {
+	if len(weekdays) == 0 {
+		return true
+	}
+	for _, day := range weekdays {
+		if day == int(weekday) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses an HH:MM time of day
+func parseClock(value string) (int, int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return clock.Hour(), clock.Minute(), nil
+}
+
+// ValidateBlackoutWindow checks that a window is complete and consistent
+func ValidateBlackoutWindow(window *models.BlackoutWindow) error {
+	if window.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch window.Scope {
+	case "global":
+		if window.ScheduleID != nil || window.ChannelID != nil {
+			return fmt.Errorf("global windows take neither schedule_id nor channel_id")
+		}
+	case "schedule":
+		if window.ScheduleID == nil || window.ChannelID != nil {
+			return fmt.Errorf("schedule windows require schedule_id and no channel_id")
+		}
+	case "channel":
+		if window.ChannelID == nil || window.ScheduleID != nil {
+			return fmt.Errorf("channel windows require channel_id and no schedule_id")
+		}
+	default:
+		return fmt.Errorf("scope must be one of global, schedule, channel")
+	}
+
+	switch window.Policy {
+	case "skip", "defer":
+	default:
+		return fmt.Errorf("policy must be one of skip, defer")
+	}
+
+	switch window.Kind {
+	case "recurring":
+		if _, err := time.LoadLocation(window.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", window.Timezone, err)
+		}
+		if _, _, err := parseClock(window.StartTime.String); err != nil {
+			return fmt.Errorf("start_time: %w", err)
+		}
+		if _, _, err := parseClock(window.EndTime.String); err != nil {
+			return fmt.Errorf("end_time: %w", err)
+		}
+		for _, day := range window.Weekdays {
+			if day < 0 || day > 6 {
+				return fmt.Errorf("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+			}
+		}
+	case "absolute":
+		if !window.StartsAt.Valid || !window.EndsAt.Valid {
+			return fmt.Errorf("absolute windows require starts_at and ends_at")
+		}
+		if !window.EndsAt.Time.After(window.StartsAt.Time) {
+			return fmt.Errorf("ends_at must be after starts_at")
+		}
+	default:
+		return fmt.Errorf("kind must be one of recurring, absolute")
+	}
+	return nil
+}
@@ -2,7 +2,11 @@ package scheduler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/GezzyDax/timelith/go-backend/internal/database"
@@ -13,124 +17,330 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// dispatchPollInterval is how often idle workers look for due jobs
+	dispatchPollInterval = time.Second
+	// dispatchVisibility is how long a claimed job stays invisible to other workers;
+	// jobs of a worker that crashed become claimable again once it expires
+	dispatchVisibility = 5 * time.Minute
+	// dispatchRetention is how long succeeded jobs are kept before being purged
+	dispatchRetention = 7 * 24 * time.Hour
+	// dispatchAbortGrace is how long shutdown waits for interrupted sends to return
+	dispatchAbortGrace = 5 * time.Second
+)
+
 type MessageJob struct {
-	ScheduleID uuid.UUID
-	Account    *models.Account
-	Template   *models.Template
-	Channel    *models.Channel
-	Message    string
-	Delay      time.Duration
-	Retries    int
+	ID             uuid.UUID
+	ScheduleID     uuid.UUID
+	RunID          uuid.UUID // Recorded schedule run, if any
+	RunAt          time.Time // Schedule run the job belongs to
+	IdempotencyKey string    // Derived from ScheduleID, RunAt and Channel when empty
+	Account        *models.Account
+	Template       *models.Template
+	Channel        *models.Channel
+	Schedule       *models.Schedule // Loaded for queued jobs; carries the pacing settings
+	Message        string
+	NextJobID      *uuid.UUID // Released once this job finishes
+	Attempts       int
+
+	delivery *models.Delivery
+	newChat  bool   // The account never posted in the channel before
+	sendID   int64  // Send reserved within the account's quotas, 0 if none
+	workerID string // Worker that claimed the job; queue transitions are fenced on it
+}
+
+// deliveryKey identifies the delivery of one schedule run to one channel
+func deliveryKey(scheduleID uuid.UUID, runAt time.Time, channelID uuid.UUID) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s", scheduleID, runAt.Unix(), channelID)))
+	return hex.EncodeToString(sum[:])
 }
 
 type Dispatcher struct {
 	db             *database.DB
 	sessionManager *telegram.SessionManager
-	queue          chan *MessageJob
+	queue          Queue
 	workers        int
+	accountMu      sync.Mutex
+	accountLocks   map[uuid.UUID]*sync.Mutex
+	wake           chan struct{}
 	stopCh         chan struct{}
+	abortCh        chan struct{} // Closed when shutdown gives up waiting for in-flight sends
+	stopOnce       sync.Once
+	workersWG      sync.WaitGroup
 }
 
-func NewDispatcher(db *database.DB, sessionManager *telegram.SessionManager) *Dispatcher {
+func NewDispatcher(db *database.DB, sessionManager *telegram.SessionManager, queue Queue) *Dispatcher {
+	workers := 5
 	return &Dispatcher{
 		db:             db,
 		sessionManager: sessionManager,
-		queue:          make(chan *MessageJob, 100),
-		workers:        5,
+		queue:          queue,
+		workers:        workers,
+		accountLocks:   make(map[uuid.UUID]*sync.Mutex),
+		wake:           make(chan struct{}, workers),
 		stopCh:         make(chan struct{}),
+		abortCh:        make(chan struct{}),
 	}
 }
 
-func (d *Dispatcher) Run(ctx context.Context) {
+// Start launches the workers and the purge loop in the background
+func (d *Dispatcher) Start(ctx context.Context) {
 	logger.Log.Info("Starting dispatcher",
 		zap.Int("workers", d.workers))
 
+	host, _ := os.Hostname()
+
+	// Sends in flight are cancelled only when shutdown runs out of time
+	workCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		select {
+		case <-d.abortCh:
+		case <-workCtx.Done():
+		}
+	}()
+
 	// Start workers
+	d.workersWG.Add(d.workers)
 	for i := 0; i < d.workers; i++ {
-		go d.worker(ctx, i)
+		go func(workerID string) {
+			defer d.workersWG.Done()
+			d.worker(workCtx, workerID)
+		}(fmt.Sprintf("%s-%d-%d", host, os.Getpid(), i))
 	}
 
-	<-d.stopCh
-	close(d.queue)
+	go d.run(ctx)
+}
+
+func (d *Dispatcher) run(ctx context.Context) {
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
 
-	logger.Log.Info("Dispatcher stopped")
+	for {
+		select {
+		case <-purge.C:
+			d.purge()
+		case <-ctx.Done():
+			logger.Log.Info("Dispatcher stopped")
+			return
+		case <-d.stopCh:
+			logger.Log.Info("Dispatcher stopped")
+			return
+		}
+	}
 }
 
-func (d *Dispatcher) worker(ctx context.Context, workerID int) {
+func (d *Dispatcher) worker(ctx context.Context, workerID string) {
 	logger.Log.Info("Dispatcher worker started",
-		zap.Int("worker_id", workerID))
+		zap.String("worker_id", workerID))
 
-	for job := range d.queue {
-		d.processJob(ctx, job, workerID)
-	}
+	ticker := time.NewTicker(dispatchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain due jobs before going idle; nothing new is claimed once stopping
+		for {
+			select {
+			case <-d.stopCh:
+				logger.Log.Info("Dispatcher worker stopped",
+					zap.String("worker_id", workerID))
+				return
+			default:
+			}
+
+			job, err := d.queue.Claim(ctx, workerID, dispatchVisibility)
+			if err != nil {
+				logger.Log.Error("Failed to claim dispatcher job",
+					zap.String("worker_id", workerID),
+					zap.Error(err))
+				break
+			}
+			if job == nil {
+				break
+			}
+			d.processJob(ctx, job, workerID)
+		}
 
-	logger.Log.Info("Dispatcher worker stopped",
-		zap.Int("worker_id", workerID))
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-ctx.Done():
+			logger.Log.Info("Dispatcher worker stopped",
+				zap.String("worker_id", workerID))
+			return
+		case <-d.stopCh:
+			logger.Log.Info("Dispatcher worker stopped",
+				zap.String("worker_id", workerID))
+			return
+		}
+	}
 }
 
-func (d *Dispatcher) processJob(ctx context.Context, job *MessageJob, workerID int) {
-	// Apply delay if specified
-	if job.Delay > 0 {
-		logger.Log.Info("Delaying message",
-			zap.Duration("delay", job.Delay),
-			zap.String("channel", job.Channel.Name))
-		time.Sleep(job.Delay)
+func (d *Dispatcher) processJob(ctx context.Context, queued *models.DispatchJob, workerID string) {
+	job, err := d.loadJob(queued)
+	if err != nil {
+		logger.Log.Error("Failed to load dispatcher job",
+			zap.String("job_id", queued.ID.String()),
+			zap.Error(err))
+		// The database may only be briefly unavailable; the default policy decides
+		d.retryOrKill(ctx, &MessageJob{
+			ID:             queued.ID,
+			ScheduleID:     queued.ScheduleID,
+			IdempotencyKey: queued.IdempotencyKey,
+			NextJobID:      queued.NextJobID,
+			Attempts:       queued.Attempts,
+			workerID:       workerID,
+		}, err)
+		return
 	}
+	job.workerID = workerID
 
 	logger.Log.Info("Processing message job",
-		zap.Int("worker_id", workerID),
+		zap.String("worker_id", workerID),
+		zap.String("job_id", job.ID.String()),
+		zap.Int("attempt", job.Attempts),
 		zap.String("schedule_id", job.ScheduleID.String()),
 		zap.String("account", job.Account.Phone),
 		zap.String("channel", job.Channel.Name))
 
+	if d.parkIfPaused(ctx, job) || d.applyQuota(ctx, job) {
+		return
+	}
+	// The reserved send only counts if the message goes out
+	defer d.releaseSend(job)
+	if d.parkForPacing(ctx, job) {
+		return
+	}
+
 	// Load Telegram session if not already loaded
 	if err := d.sessionManager.LoadSession(ctx, job.Account); err != nil {
+		if ctx.Err() != nil {
+			d.interrupt(job)
+			return
+		}
 		logger.Log.Error("Failed to load Telegram session",
 			zap.String("account", job.Account.Phone),
 			zap.Error(err))
+		if d.failover(ctx, job, err) {
+			return
+		}
+		if !d.kill(job, err.Error()) {
+			return
+		}
+		d.abandonDelivery(job.IdempotencyKey, err, "session")
+		d.releaseNext(job)
 		d.logJobResult(job.ScheduleID, "failed", "", fmt.Sprintf("Failed to load session: %v", err))
 		return
 	}
 
-	// Send message (text or media based on template)
-	var err error
-	if job.Template.MediaType.Valid && job.Template.MediaType.String != "" {
-		err = d.sessionManager.SendMediaMessage(ctx, job.Account.Phone, job.Channel.ChatID, job.Template)
-	} else if job.Template.CopyFromChatID.Valid && job.Template.CopyFromMessageID.Valid {
-		err = d.sessionManager.ForwardMessage(ctx, job.Account.Phone, job.Channel.ChatID,
-			job.Template.CopyFromChatID.String, int(job.Template.CopyFromMessageID.Int64))
-	} else {
-		err = d.sessionManager.SendMessage(ctx, job.Account.Phone, job.Channel.ChatID, job.Message)
+	// The delivery record tells whether an earlier attempt already got through
+	var delivery *models.Delivery
+	if job.IdempotencyKey != "" {
+		if delivery, err = d.db.GetDeliveryByKey(job.IdempotencyKey); err != nil {
+			logger.Log.Error("Failed to load delivery",
+				zap.String("job_id", job.ID.String()),
+				zap.Error(err))
+			d.retryOrKill(ctx, job, err)
+			return
+		}
+		if delivery.Status == "sent" {
+			logger.Log.Info("Skipping already delivered message",
+				zap.String("job_id", job.ID.String()),
+				zap.String("idempotency_key", job.IdempotencyKey))
+			if err := d.queue.Complete(ctx, job.ID, job.workerID); err != nil {
+				logger.Log.Error("Failed to complete dispatcher job",
+					zap.String("job_id", job.ID.String()),
+					zap.Error(err))
+			}
+			d.releaseNext(job)
+			return
+		}
+		if err := d.db.StartDeliveryAttempt(delivery.ID, job.Account.ID); err != nil {
+			// Without the record a retry could not be told apart from a first send
+			logger.Log.Error("Failed to record delivery attempt",
+				zap.String("job_id", job.ID.String()),
+				zap.Error(err))
+			d.retryOrKill(ctx, job, err)
+			return
+		}
 	}
 
+	// Send message (text or media based on template)
+	// One account sends one message at a time, however many workers hold its jobs
+	unlock := d.lockAccount(job.Account.ID)
+	if job.Schedule.TypingAction {
+		if err := d.sessionManager.SendTyping(ctx, job.Account.Phone, job.Channel.ChatID, typingDuration(job.Message)); err != nil {
+			// Typing is cosmetic; channels do not even show it
+			logger.Log.Debug("Failed to send typing action",
+				zap.String("channel", job.Channel.ChatID),
+				zap.Error(err))
+		}
+	}
+	started := time.Now()
+	ids, err := d.send(ctx, job, telegram.SendOptions{IdempotencyKey: job.IdempotencyKey})
+	latency := time.Since(started)
+	unlock()
+	if err != nil && telegram.IsDuplicateSend(err) {
+		// An earlier attempt was accepted by Telegram even though we never saw the result
+		logger.Log.Info("Telegram already has this message, treating as delivered",
+			zap.String("job_id", job.ID.String()),
+			zap.String("idempotency_key", job.IdempotencyKey))
+		err = nil
+	}
+	if err != nil && ctx.Err() != nil {
+		d.interrupt(job)
+		return
+	}
+	d.learnFromSend(job, err)
 	if err != nil {
 		logger.Log.Error("Failed to send message",
 			zap.String("account", job.Account.Phone),
 			zap.String("channel", job.Channel.ChatID),
 			zap.Error(err))
 
-		// Retry logic
-		if job.Retries < 3 {
-			job.Retries++
-			logger.Log.Info("Retrying message job",
-				zap.Int("retry", job.Retries),
-				zap.String("schedule_id", job.ScheduleID.String()))
-
-			time.Sleep(time.Second * time.Duration(job.Retries*2)) // Exponential backoff
-			d.Enqueue(job)
-			return
+		// A fallback account takes over when the failure lies with the account
+		failedOver := isAccountFailure(err) && d.failover(ctx, job, err)
+		if delivery != nil {
+			retrying := failedOver || scheduleRetryPolicy(job.Schedule).retries(job.Attempts)
+			if err := d.db.MarkDeliveryFailed(delivery.ID, err.Error(), telegram.ErrorCategory(err), latency, retrying); err != nil {
+				logger.Log.Error("Failed to update delivery",
+					zap.String("delivery_id", delivery.ID.String()),
+					zap.Error(err))
+			}
+			if !retrying {
+				d.refreshRun(delivery)
+			}
 		}
 
-		d.logJobResult(job.ScheduleID, "failed", "", fmt.Sprintf("Failed after %d retries: %v", job.Retries, err))
+		if !failedOver {
+			d.retryOrKill(ctx, job, err)
+		}
 		return
 	}
 
+	if delivery != nil {
+		if err := d.db.MarkDeliverySent(delivery.ID, ids, latency); err != nil {
+			logger.Log.Error("Failed to update delivery",
+				zap.String("delivery_id", delivery.ID.String()),
+				zap.Error(err))
+		}
+		d.refreshRun(delivery)
+	}
+
+	if err := d.queue.Complete(ctx, job.ID, job.workerID); err != nil {
+		logger.Log.Error("Failed to complete dispatcher job",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+	}
+	d.releaseNext(job)
+
 	// Success - update account statistics
 	if err := d.db.IncrementAccountMessageCount(job.Account.ID); err != nil {
 		logger.Log.Error("Failed to update account stats",
 			zap.String("account_id", job.Account.ID.String()),
 			zap.Error(err))
 	}
+	d.keepSend(job)
 
 	logger.Log.Info("Message sent successfully",
 		zap.String("account", job.Account.Phone),
@@ -139,14 +349,343 @@ func (d *Dispatcher) processJob(ctx context.Context, job *MessageJob, workerID i
 	d.logJobResult(job.ScheduleID, "success", "Message sent successfully", "")
 }
 
-func (d *Dispatcher) Enqueue(job *MessageJob) {
-	select {
-	case d.queue <- job:
-		logger.Log.Debug("Job enqueued",
+// retryOrKill reschedules a failed job with the schedule's backoff, or moves it to the
+// dead-letter queue and fails its delivery once its attempts are exhausted. The worker does
+// not wait for the retry.
+func (d *Dispatcher) retryOrKill(ctx context.Context, job *MessageJob, cause error) {
+	policy := scheduleRetryPolicy(job.Schedule)
+	if policy.retries(job.Attempts) {
+		runAt := time.Now().Add(policy.delay(job.Attempts, cause))
+		logger.Log.Info("Retrying message job",
+			zap.Int("retry", job.Attempts),
+			zap.Int("max_attempts", policy.maxAttempts),
+			zap.Time("run_at", runAt),
 			zap.String("schedule_id", job.ScheduleID.String()))
+
+		if err := d.queue.Retry(ctx, job.ID, job.workerID, runAt, cause.Error()); err != nil {
+			logger.Log.Error("Failed to reschedule dispatcher job",
+				zap.String("job_id", job.ID.String()),
+				zap.Error(err))
+		}
+		return
+	}
+
+	if !d.kill(job, cause.Error()) {
+		return
+	}
+	d.abandonDelivery(job.IdempotencyKey, cause, "internal")
+	d.releaseNext(job)
+	d.logJobResult(job.ScheduleID, "failed", "", fmt.Sprintf("Failed after %d retries: %v", job.Attempts-1, cause))
+}
+
+// interrupt puts back a job whose send was cut short by shutdown. The attempt does not
+// count; should the message have gone out anyway, its idempotency key stops a second post.
+func (d *Dispatcher) interrupt(job *MessageJob) {
+	logger.Log.Warn("Message job interrupted by shutdown",
+		zap.String("job_id", job.ID.String()),
+		zap.String("schedule_id", job.ScheduleID.String()))
+	if err := d.queue.Park(context.Background(), job.ID, job.workerID, time.Now(), "interrupted by shutdown"); err != nil {
+		logger.Log.Error("Failed to put back interrupted dispatcher job",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+	}
+}
+
+// releaseNext lets the next job of the run go out. The pause between sends is kept per
+// account by parkForPacing.
+func (d *Dispatcher) releaseNext(job *MessageJob) {
+	if job.NextJobID == nil {
+		return
+	}
+	d.release(job.NextJobID, 0)
+}
+
+// lockAccount serializes sends of one account within this instance and returns the unlock func
+func (d *Dispatcher) lockAccount(accountID uuid.UUID) func() {
+	d.accountMu.Lock()
+	lock, ok := d.accountLocks[accountID]
+	if !ok {
+		lock = &sync.Mutex{}
+		d.accountLocks[accountID] = lock
+	}
+	d.accountMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// loadJob resolves the entities referenced by a queued job
+func (d *Dispatcher) loadJob(queued *models.DispatchJob) (*MessageJob, error) {
+	account, err := d.db.GetAccount(queued.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+	template, err := d.db.GetTemplate(queued.TemplateID)
+	if err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
+	}
+	channel, err := d.db.GetChannel(queued.ChannelID)
+	if err != nil {
+		return nil, fmt.Errorf("channel not found: %w", err)
+	}
+	schedule, err := d.db.GetSchedule(queued.ScheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("schedule not found: %w", err)
+	}
+
+	return &MessageJob{
+		ID:             queued.ID,
+		ScheduleID:     queued.ScheduleID,
+		IdempotencyKey: queued.IdempotencyKey,
+		Account:        account,
+		Template:       template,
+		Channel:        channel,
+		Schedule:       schedule,
+		Message:        queued.Message,
+		NextJobID:      queued.NextJobID,
+		Attempts:       queued.Attempts,
+	}, nil
+}
+
+// abandonDelivery marks the delivery of a job that will not be attempted again as failed,
+// unless the failed send already did
+func (d *Dispatcher) abandonDelivery(key string, cause error, category string) {
+	if key == "" {
+		return
+	}
+	delivery, err := d.db.GetDeliveryByKey(key)
+	if err != nil {
+		logger.Log.Error("Failed to load delivery",
+			zap.String("idempotency_key", key),
+			zap.Error(err))
+		return
+	}
+	if delivery.Status == "failed" {
+		return
+	}
+	if err := d.db.MarkDeliveryFailed(delivery.ID, cause.Error(), category, 0, false); err != nil {
+		logger.Log.Error("Failed to update delivery",
+			zap.String("delivery_id", delivery.ID.String()),
+			zap.Error(err))
+		return
+	}
+	d.refreshRun(delivery)
+}
+
+// refreshRun updates the totals of the run a delivery belongs to
+func (d *Dispatcher) refreshRun(delivery *models.Delivery) {
+	if delivery.RunID == nil {
+		return
+	}
+	if err := d.db.RefreshScheduleRun(*delivery.RunID); err != nil {
+		logger.Log.Error("Failed to update schedule run",
+			zap.String("run_id", delivery.RunID.String()),
+			zap.Error(err))
+	}
+}
+
+// kill moves the job to the dead-letter queue. It reports false when another worker has
+// since claimed the job, which then is that worker's to finish.
+func (d *Dispatcher) kill(job *MessageJob, reason string) bool {
+	err := d.queue.Kill(context.Background(), job.ID, job.workerID, reason)
+	if err == ErrLeaseLost {
+		logger.Log.Warn("Dispatcher job was claimed by another worker before it could be marked dead",
+			zap.String("job_id", job.ID.String()))
+		return false
+	}
+	if err != nil {
+		logger.Log.Error("Failed to mark dispatcher job dead",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+	}
+	return true
+}
+
+func (d *Dispatcher) purge() {
+	purged, err := d.queue.Purge(context.Background(), time.Now().Add(-dispatchRetention))
+	if err != nil {
+		logger.Log.Error("Failed to purge dispatcher jobs", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		logger.Log.Info("Purged finished dispatcher jobs", zap.Int64("count", purged))
+	}
+
+	if _, err := d.db.PurgeAccountSends(time.Now().Add(-quotaDayWindow)); err != nil {
+		logger.Log.Error("Failed to purge account sends", zap.Error(err))
+	}
+}
+
+// send delivers the job's template to its channel and returns the Telegram message IDs
+func (d *Dispatcher) send(ctx context.Context, job *MessageJob, opts telegram.SendOptions) ([]int, error) {
+	switch {
+	case job.Template.MediaType.Valid && job.Template.MediaType.String != "":
+		return d.sessionManager.SendMediaMessage(ctx, job.Account.Phone, job.Channel.ChatID, job.Template, opts)
+	case job.Template.CopyFromChatID.Valid && job.Template.CopyFromMessageID.Valid:
+		return d.copyMessage(ctx, job, opts)
 	default:
-		logger.Log.Warn("Dispatcher queue is full, dropping job",
-			zap.String("schedule_id", job.ScheduleID.String()))
+		return d.sessionManager.SendMessage(ctx, job.Account.Phone, job.Channel.ChatID, job.Message, opts)
+	}
+}
+
+// copyMessage delivers a template sourced from an existing message according to its copy mode
+func (d *Dispatcher) copyMessage(ctx context.Context, job *MessageJob, sendOpts telegram.SendOptions) ([]int, error) {
+	template := job.Template
+	opts := telegram.ForwardOptions{
+		DropMediaCaptions: template.DropMediaCaptions,
+		Album:             template.CopyAlbum,
+	}
+	fromChatID := template.CopyFromChatID.String
+	messageID := int(template.CopyFromMessageID.Int64)
+
+	switch template.CopyMode.String {
+	case "", "forward":
+		return d.sessionManager.ForwardMessage(ctx, job.Account.Phone, job.Channel.ChatID, fromChatID, messageID, opts, sendOpts)
+	case "copy":
+		opts.DropAuthor = true
+		return d.sessionManager.ForwardMessage(ctx, job.Account.Phone, job.Channel.ChatID, fromChatID, messageID, opts, sendOpts)
+	case "resend":
+		return d.sessionManager.ResendMessage(ctx, job.Account.Phone, job.Channel.ChatID, fromChatID, messageID, opts, sendOpts)
+	default:
+		return nil, fmt.Errorf("unsupported copy mode: %s", template.CopyMode.String)
+	}
+}
+
+// EnqueueRun records the deliveries of a run and persists a job for each of them. The
+// jobs are sent one after another in the given order: only the first is claimable, at
+// startAt or right away when it is zero, and each following job is released once its predecessor finished, after the
+// schedule's pacing delay. A delivery that was already enqueued is not enqueued again
+// and leaves job.ID unset. The returned slice holds the error of each job, if any.
+func (d *Dispatcher) EnqueueRun(jobs []*MessageJob, startAt time.Time) []error {
+	errs := make([]error, len(jobs))
+	var created []bool
+	head := -1
+	for i, job := range jobs {
+		ok, err := d.recordDelivery(job)
+		created = append(created, ok)
+		errs[i] = err
+		if ok && head < 0 {
+			head = i
+		}
+	}
+
+	// Pushed last to first, so each job can point at its successor
+	var next *uuid.UUID
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if !created[i] {
+			continue
+		}
+		status := "held"
+		if i == head {
+			status = "pending"
+		}
+		if err := d.push(jobs[i], status, next, startAt); err != nil {
+			errs[i] = err
+			continue
+		}
+		id := jobs[i].ID
+		next = &id
+	}
+
+	// Without its head the chain would never start
+	if head >= 0 && errs[head] != nil && next != nil {
+		var delay time.Duration
+		if !startAt.IsZero() {
+			delay = time.Until(startAt)
+		}
+		d.release(next, delay)
+	}
+
+	if next != nil && !startAt.After(time.Now()) {
+		// Let an idle worker pick up the first job
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return errs
+}
+
+// recordDelivery creates the delivery record of a job and reports whether it is new
+func (d *Dispatcher) recordDelivery(job *MessageJob) (bool, error) {
+	if job.IdempotencyKey == "" {
+		job.IdempotencyKey = deliveryKey(job.ScheduleID, job.RunAt, job.Channel.ID)
+	}
+
+	job.delivery = &models.Delivery{
+		IdempotencyKey: job.IdempotencyKey,
+		ScheduleID:     job.ScheduleID,
+		ChannelID:      job.Channel.ID,
+		RunAt:          job.RunAt,
+	}
+	if job.RunID != uuid.Nil {
+		job.delivery.RunID = &job.RunID
+	}
+	created, err := d.db.CreateDelivery(job.delivery)
+	if err != nil {
+		return false, fmt.Errorf("failed to record delivery: %w", err)
+	}
+	if !created {
+		logger.Log.Info("Delivery already enqueued, skipping",
+			zap.String("schedule_id", job.ScheduleID.String()),
+			zap.String("channel", job.Channel.Name),
+			zap.String("idempotency_key", job.IdempotencyKey))
+	}
+	return created, nil
+}
+
+// push persists the job of a recorded delivery; a failed push fails the delivery.
+// A zero runAt makes the job due right away.
+func (d *Dispatcher) push(job *MessageJob, status string, next *uuid.UUID, runAt time.Time) error {
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+
+	queued := &models.DispatchJob{
+		ScheduleID:     job.ScheduleID,
+		AccountID:      job.Account.ID,
+		TemplateID:     job.Template.ID,
+		ChannelID:      job.Channel.ID,
+		Message:        job.Message,
+		IdempotencyKey: job.IdempotencyKey,
+		Status:         status,
+		NextJobID:      next,
+		RunAt:          runAt,
+	}
+	if err := d.queue.Push(context.Background(), queued); err != nil {
+		err = fmt.Errorf("failed to enqueue job: %w", err)
+		if markErr := d.db.MarkDeliveryFailed(job.delivery.ID, err.Error(), "internal", 0, false); markErr != nil {
+			logger.Log.Error("Failed to update delivery",
+				zap.String("delivery_id", job.delivery.ID.String()),
+				zap.Error(markErr))
+		}
+		return err
+	}
+	job.ID = queued.ID
+
+	logger.Log.Debug("Job enqueued",
+		zap.String("job_id", job.ID.String()),
+		zap.String("schedule_id", job.ScheduleID.String()),
+		zap.String("status", status))
+	return nil
+}
+
+// release lets the next job of a run be claimed after delay
+func (d *Dispatcher) release(next *uuid.UUID, delay time.Duration) {
+	if next == nil {
+		return
+	}
+	if err := d.queue.Release(context.Background(), *next, time.Now().Add(delay)); err != nil {
+		logger.Log.Error("Failed to release next dispatcher job",
+			zap.String("job_id", next.String()),
+			zap.Error(err))
+		return
+	}
+	if delay <= 0 {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
 	}
 }
 
@@ -171,6 +710,36 @@ func (d *Dispatcher) logJobResult(scheduleID uuid.UUID, status, message, errorMs
 	}
 }
 
-func (d *Dispatcher) Stop() {
-	close(d.stopCh)
+// Shutdown stops the workers from claiming jobs and waits for the sends in flight. Sends
+// still running when ctx expires are cancelled and their jobs put back without counting
+// the attempt. Jobs nobody claimed stay in the queue for the next start.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.stopOnce.Do(func() { close(d.stopCh) })
+
+	done := make(chan struct{})
+	go func() {
+		d.workersWG.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+		logger.Log.Info("Dispatcher drained")
+	case <-ctx.Done():
+		err = fmt.Errorf("dispatcher did not drain in time: %w", ctx.Err())
+		logger.Log.Warn("Interrupting in-flight sends")
+		close(d.abortCh)
+		select {
+		case <-done:
+		case <-time.After(dispatchAbortGrace):
+			// Jobs of workers that never returned are reclaimed after the visibility timeout
+			logger.Log.Warn("Dispatcher workers did not stop")
+		}
+	}
+
+	if closeErr := d.queue.Close(); closeErr != nil {
+		logger.Log.Error("Failed to close dispatcher queue", zap.Error(closeErr))
+	}
+	return err
 }
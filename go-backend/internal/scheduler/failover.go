@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/GezzyDax/timelith/go-backend/internal/telegram"
+	"go.uber.org/zap"
+)
+
+// isAccountFailure reports whether a send failed because of the sending account rather
+// than the message or the network: its session is gone, Telegram restricted it or it
+// cannot post in the channel
+func isAccountFailure(err error) bool {
+	if _, ok := telegram.Restriction(err); ok {
+		return true
+	}
+	return telegram.IsNotMember(err) || telegram.ErrorCategory(err) == "session"
+}
+
+// failover hands a job whose account failed to the next fallback account of the schedule
+// that can take it, and notes the substitution on the delivery. The job goes out again
+// right away without counting the attempt. It reports whether a fallback took over.
+func (d *Dispatcher) failover(ctx context.Context, job *MessageJob, cause error) bool {
+	fallback, err := d.nextFallback(job)
+	if err != nil {
+		logger.Log.Error("Failed to look for a fallback account",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+		return false
+	}
+	if fallback == nil {
+		return false
+	}
+
+	if err := d.queue.Reassign(ctx, job.ID, fallback.ID); err != nil {
+		logger.Log.Error("Failed to reassign dispatcher job",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+		return false
+	}
+
+	reason := fmt.Sprintf("%s failed: %s", job.Account.Phone, telegram.ErrorType(cause))
+	if job.IdempotencyKey != "" {
+		if err := d.db.RecordDeliveryFailover(job.IdempotencyKey, job.Account.ID, reason); err != nil {
+			logger.Log.Error("Failed to record delivery failover",
+				zap.String("idempotency_key", job.IdempotencyKey),
+				zap.Error(err))
+		}
+	}
+
+	logger.Log.Warn("Failing over message job to a fallback account",
+		zap.String("job_id", job.ID.String()),
+		zap.String("from", job.Account.Phone),
+		zap.String("to", fallback.Phone),
+		zap.Error(cause))
+	if err := d.queue.Park(ctx, job.ID, job.workerID, time.Now(), fmt.Sprintf("failed over to %s", fallback.Phone)); err != nil {
+		logger.Log.Error("Failed to requeue dispatcher job",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+	}
+	return true
+}
+
+// nextFallback returns the first fallback account after the job's own in the schedule's
+// list that is active, not paused or restricted, not known to be unable to post in the
+// channel and within its quotas, or nil when none is left
+func (d *Dispatcher) nextFallback(job *MessageJob) (*models.Account, error) {
+	fallbacks := job.Schedule.FallbackAccountIDs
+	for i, id := range fallbacks {
+		if id == job.Account.ID {
+			// Earlier fallbacks already had their turn
+			fallbacks = fallbacks[i+1:]
+			break
+		}
+	}
+	if len(fallbacks) == 0 {
+		return nil, nil
+	}
+
+	pauses, err := loadPauses(d.db)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	nonMembers, err := d.db.ListNonMembers(now.Add(-membershipTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load channel memberships: %w", err)
+	}
+	locked := channelAccounts(nonMembers)[job.Channel.ID]
+
+	for _, id := range fallbacks {
+		account, err := d.db.GetAccount(id)
+		if err != nil {
+			// Deleted since the schedule was saved
+			continue
+		}
+		switch {
+		case account.ID == job.Account.ID, account.Status != "active", locked[account.ID]:
+			continue
+		case pauses.forSend(account.ID, job.Channel.ID) != nil:
+			continue
+		case account.RestrictedUntil.Valid && account.RestrictedUntil.Time.After(now):
+			continue
+		}
+
+		member, err := d.db.IsChannelMember(account.ID, job.Channel.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load channel membership: %w", err)
+		}
+		usage, err := d.db.GetAccountUsage(account.ID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load account usage: %w", err)
+		}
+		if checkQuota(account, quotaLoad{usage: *usage, newChat: !member}, now) != nil {
+			continue
+		}
+		return account, nil
+	}
+	return nil, nil
+}
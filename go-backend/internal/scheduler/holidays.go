@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// calendarCacheTTL is how long the days of a holiday calendar are reused before being read
+// again. It bounds how long other replicas keep using a calendar that was re-imported.
+const calendarCacheTTL = time.Minute
+
+type cachedCalendar struct {
+	days     map[string]bool // YYYY-MM-DD
+	loadedAt time.Time
+}
+
+// calendarDays returns the dates of a holiday calendar. A deleted calendar has no dates.
+func (s *Scheduler) calendarDays(id uuid.UUID) (map[string]bool, error) {
+	s.calendarMu.Lock()
+	defer s.calendarMu.Unlock()
+
+	if cached, ok := s.calendars[id]; ok && time.Since(cached.loadedAt) < calendarCacheTTL {
+		return cached.days, nil
+	}
+
+	rows, err := s.db.ListCalendarDays(id)
+	if err != nil {
+		return nil, err
+	}
+	days := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		days[row.Day.Format("2006-01-02")] = true
+	}
+	s.calendars[id] = cachedCalendar{days: days, loadedAt: time.Now()}
+	return days, nil
+}
+
+// InvalidateCalendar drops the cached days of a calendar after it was re-imported or deleted
+func (s *Scheduler) InvalidateCalendar(id uuid.UUID) {
+	s.calendarMu.Lock()
+	delete(s.calendars, id)
+	s.calendarMu.Unlock()
+}
+
+// inCalendars reports whether the date is listed in any of the calendars. Calendars that
+// cannot be read are logged and treated as empty.
+func (s *Scheduler) inCalendars(ids models.UUIDList, date string) bool {
+	for _, id := range ids {
+		days, err := s.calendarDays(id)
+		if err != nil {
+			logger.Log.Error("Failed to load holiday calendar",
+				zap.String("calendar_id", id.String()),
+				zap.Error(err))
+			continue
+		}
+		if days[date] {
+			return true
+		}
+	}
+	return false
+}
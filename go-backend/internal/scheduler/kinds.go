@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/robfig/cron/v3"
+)
+
+// onceSchedule fires a single time
+type onceSchedule struct {
+	at time.Time
+}
+
+func (o onceSchedule) Next(t time.Time) time.Time {
+	if o.at.After(t) {
+		return o.at
+	}
+	return time.Time{}
+}
+
+// intervalSchedule fires every period at anchor + k*every. The period is absolute time,
+// so it is not affected by timezones or DST.
+type intervalSchedule struct {
+	anchor time.Time
+	every  time.Duration
+}
+
+func (i intervalSchedule) Next(t time.Time) time.Time {
+	if t.Before(i.anchor) {
+		return i.anchor
+	}
+	periods := t.Sub(i.anchor)/i.every + 1
+	return i.anchor.Add(periods * i.every)
+}
+
+// intervalAnchor is the first fire time of an interval schedule
+func intervalAnchor(schedule *models.Schedule) time.Time {
+	switch {
+	case schedule.IntervalAnchor.Valid:
+		return schedule.IntervalAnchor.Time
+	case schedule.StartsAt.Valid:
+		return schedule.StartsAt.Time
+	default:
+		return schedule.CreatedAt
+	}
+}
+
+// windowSchedule limits another schedule to fire times within [startsAt, endsAt]
+type windowSchedule struct {
+	inner    cron.Schedule
+	startsAt models.NullTime
+	endsAt   models.NullTime
+}
+
+func (w windowSchedule) Next(t time.Time) time.Time {
+	// Fire times at exactly startsAt are included
+	if w.startsAt.Valid && t.Before(w.startsAt.Time) {
+		t = w.startsAt.Time.Add(-time.Nanosecond)
+	}
+
+	next := w.inner.Next(t)
+	if next.IsZero() || (w.endsAt.Valid && next.After(w.endsAt.Time)) {
+		return time.Time{}
+	}
+	return next
+}
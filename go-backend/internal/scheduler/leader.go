@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// leaseName identifies the cron scheduler lease in scheduler_leases
+	leaseName = "cron-scheduler"
+	// leaseTTL is how long a lease stays valid without renewal; it bounds failover time
+	leaseTTL = 15 * time.Second
+	// leaseRenewInterval is how often instances try to take or renew the lease and the
+	// leader reloads schedules changed through other instances
+	leaseRenewInterval = 5 * time.Second
+)
+
+// LeaderStatus describes the cron leader as seen by this instance
+type LeaderStatus struct {
+	InstanceID     string     `json:"instance_id"`
+	IsLeader       bool       `json:"is_leader"`
+	Leader         string     `json:"leader,omitempty"`
+	LeaderSince    *time.Time `json:"leader_since,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+}
+
+// newInstanceID returns an identifier that is unique per process across replicas
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// IsLeader reports whether this instance currently evaluates cron schedules
+func (s *Scheduler) IsLeader() bool {
+	return s.leader.Load()
+}
+
+// LeaderStatus reports the current lease holder
+func (s *Scheduler) LeaderStatus() LeaderStatus {
+	status := LeaderStatus{
+		InstanceID: s.instanceID,
+		IsLeader:   s.IsLeader(),
+	}
+
+	lease, err := s.db.GetSchedulerLease(leaseName)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Log.Error("Failed to get scheduler lease", zap.Error(err))
+		}
+		return status
+	}
+	if lease.ExpiresAt.After(time.Now()) {
+		status.Leader = lease.Holder
+		status.LeaderSince = &lease.AcquiredAt
+		status.LeaseExpiresAt = &lease.ExpiresAt
+	}
+	return status
+}
+
+// runElection keeps trying to take the lease; the holder runs cron, everyone runs dispatch workers
+func (s *Scheduler) runElection(ctx context.Context) {
+	ticker := time.NewTicker(leaseRenewInterval)
+	defer ticker.Stop()
+
+	var lastRenewed time.Time
+	for {
+		s.electionMu.Lock()
+		select {
+		case <-s.stopCh:
+			s.electionMu.Unlock()
+			return
+		default:
+		}
+
+		acquired, err := s.db.AcquireSchedulerLease(leaseName, s.instanceID, leaseTTL)
+		switch {
+		case err != nil:
+			logger.Log.Error("Failed to renew scheduler lease", zap.Error(err))
+			// Step down before the lease can expire and be taken by another instance
+			if s.IsLeader() && time.Since(lastRenewed) > leaseTTL-leaseRenewInterval {
+				s.stepDown()
+			}
+		case acquired && !s.IsLeader():
+			lastRenewed = time.Now()
+			s.becomeLeader()
+		case acquired:
+			lastRenewed = time.Now()
+			s.reconcileSchedules()
+		case s.IsLeader():
+			s.stepDown()
+		}
+		s.electionMu.Unlock()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			s.electionMu.Lock()
+			s.releaseLease()
+			s.electionMu.Unlock()
+			return
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) becomeLeader() {
+	s.leader.Store(true)
+	logger.Log.Info("Acquired scheduler leadership",
+		zap.String("instance_id", s.instanceID))
+
+	// Runs missed while no instance led are judged before registration moves next_run_at
+	missed := s.planMisfires(time.Now())
+	s.reconcileSchedules()
+	go s.runMisfires(missed)
+}
+
+func (s *Scheduler) stepDown() {
+	s.leader.Store(false)
+
+	s.jobsMu.Lock()
+	for scheduleID := range s.versions {
+		s.removeEntryLocked(scheduleID)
+		delete(s.versions, scheduleID)
+	}
+	s.jobsMu.Unlock()
+
+	logger.Log.Warn("Lost scheduler leadership",
+		zap.String("instance_id", s.instanceID))
+}
+
+// releaseLease steps down and frees the lease; callers must hold electionMu
+func (s *Scheduler) releaseLease() {
+	if !s.IsLeader() {
+		return
+	}
+	s.stepDown()
+
+	// Releasing lets another instance take over without waiting for the lease to expire
+	if err := s.db.ReleaseSchedulerLease(leaseName, s.instanceID); err != nil {
+		logger.Log.Error("Failed to release scheduler lease", zap.Error(err))
+	}
+}
+
+// reconcileSchedules brings cron in line with the active schedules in the database,
+// picking up changes made through the API of any instance
+func (s *Scheduler) reconcileSchedules() {
+	schedules, err := s.db.ListActiveSchedules()
+	if err != nil {
+		logger.Log.Error("Failed to load schedules", zap.Error(err))
+		return
+	}
+
+	active := make(map[uuid.UUID]bool, len(schedules))
+	for i := range schedules {
+		schedule := &schedules[i]
+		active[schedule.ID] = true
+
+		s.jobsMu.Lock()
+		version, known := s.versions[schedule.ID]
+		s.jobsMu.Unlock()
+		if known && version.Equal(schedule.UpdatedAt) {
+			continue
+		}
+
+		if err := s.register(schedule); err != nil {
+			logger.Log.Error("Failed to add schedule",
+				zap.String("schedule_id", schedule.ID.String()),
+				zap.Error(err))
+
+			// Not retried until the schedule is edited again
+			s.jobsMu.Lock()
+			s.versions[schedule.ID] = schedule.UpdatedAt
+			s.jobsMu.Unlock()
+		}
+	}
+
+	var removed bool
+	s.jobsMu.Lock()
+	for scheduleID := range s.versions {
+		if !active[scheduleID] {
+			s.removeEntryLocked(scheduleID)
+			delete(s.versions, scheduleID)
+			removed = true
+		}
+	}
+	s.jobsMu.Unlock()
+
+	if removed {
+		s.cancelOrphanedNativeMessages(context.Background())
+	}
+}
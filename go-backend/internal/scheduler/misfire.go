@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// misfireScanLimit bounds how many missed occurrences are counted for one schedule
+const misfireScanLimit = 100000
+
+// misfireRun is a missed run selected for catch-up
+type misfireRun struct {
+	schedule models.Schedule
+	runAt    time.Time
+}
+
+// planMisfires applies each active schedule's misfire policy to the runs it missed while
+// no instance was evaluating cron, and logs every decision to the job log.
+// It must run before the schedules are registered, which moves next_run_at forward.
+func (s *Scheduler) planMisfires(now time.Time) []misfireRun {
+	schedules, err := s.db.ListActiveSchedules()
+	if err != nil {
+		logger.Log.Error("Failed to load schedules for misfire check", zap.Error(err))
+		return nil
+	}
+
+	var plan []misfireRun
+	for i := range schedules {
+		schedule := schedules[i]
+		// Telegram keeps delivering uploaded runs while we are down
+		if schedule.DeliveryMode == "telegram" {
+			continue
+		}
+
+		for _, runAt := range s.planMisfire(&schedule, now) {
+			plan = append(plan, misfireRun{schedule: schedule, runAt: runAt})
+		}
+	}
+	return plan
+}
+
+// planMisfire returns the missed runs of a schedule that should be executed now
+func (s *Scheduler) planMisfire(schedule *models.Schedule, now time.Time) []time.Time {
+	var from time.Time
+	switch {
+	case schedule.LastRunAt.Valid:
+		from = schedule.LastRunAt.Time
+	case schedule.NextRunAt.Valid:
+		from = schedule.NextRunAt.Time.Add(-time.Second)
+	default:
+		return nil
+	}
+
+	keep := 1
+	if schedule.MisfirePolicy == "run_all" {
+		keep = schedule.MisfireMaxRuns
+	}
+
+	missed, total, err := s.missedRuns(schedule, from, now, keep)
+	if err != nil {
+		logger.Log.Error("Failed to compute missed runs",
+			zap.String("schedule_id", schedule.ID.String()),
+			zap.Error(err))
+		return nil
+	}
+	if total == 0 {
+		return nil
+	}
+
+	latest := missed[len(missed)-1]
+	var runs []time.Time
+	var decision string
+
+	switch schedule.MisfirePolicy {
+	case "run_once":
+		runs = missed
+		decision = fmt.Sprintf("Running the latest of %d missed run(s), due at %s", total, formatRunTime(latest))
+	case "run_all":
+		runs = missed
+		decision = fmt.Sprintf("Running %d of %d missed run(s) since %s", len(runs), total, formatRunTime(from))
+	case "run_if_recent":
+		grace := time.Duration(schedule.MisfireGraceMinutes) * time.Minute
+		if now.Sub(latest) <= grace {
+			runs = missed
+			decision = fmt.Sprintf("Running missed run due at %s, within the %d minute grace period",
+				formatRunTime(latest), schedule.MisfireGraceMinutes)
+		} else {
+			decision = fmt.Sprintf("Skipped %d missed run(s): the latest, due at %s, is older than %d minutes",
+				total, formatRunTime(latest), schedule.MisfireGraceMinutes)
+		}
+	default:
+		decision = fmt.Sprintf("Skipped %d missed run(s) since %s", total, formatRunTime(from))
+	}
+
+	logger.Log.Info("Applied misfire policy",
+		zap.String("schedule_id", schedule.ID.String()),
+		zap.String("policy", schedule.MisfirePolicy),
+		zap.Int("missed", total),
+		zap.Int("running", len(runs)))
+	s.logJobExecution(schedule.ID, "misfire", decision, "")
+
+	return runs
+}
+
+// missedRuns returns the last keep fire times in (from, now] that pass the day filter,
+// together with the total number of such fire times. The latest is always kept.
+func (s *Scheduler) missedRuns(schedule *models.Schedule, from, now time.Time, keep int) ([]time.Time, int, error) {
+	cronSchedule, loc, err := parseSchedule(schedule)
+	if err != nil {
+		return nil, 0, err
+	}
+	if keep < 1 {
+		keep = 1
+	}
+
+	var runs []time.Time
+	total := 0
+	t := from.In(loc)
+	for i := 0; i < misfireScanLimit; i++ {
+		t = cronSchedule.Next(t)
+		if t.IsZero() || t.After(now) {
+			break
+		}
+		if !s.shouldRunOn(schedule, t) {
+			continue
+		}
+
+		total++
+		runs = append(runs, t)
+		if len(runs) > keep {
+			runs = runs[1:]
+		}
+	}
+	return runs, total, nil
+}
+
+// runMisfires executes the selected missed runs oldest first
+func (s *Scheduler) runMisfires(plan []misfireRun) {
+	for _, run := range plan {
+		logger.Log.Info("Catching up missed run",
+			zap.String("schedule_id", run.schedule.ID.String()),
+			zap.Time("run_at", run.runAt))
+		s.executeSchedule(run.schedule.ID, run.runAt, "misfire")
+	}
+}
+
+func formatRunTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
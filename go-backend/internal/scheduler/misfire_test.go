@@ -0,0 +1,28 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+)
+
+func TestMissedRunsKeepsLatest(t *testing.T) {
+	schedule := &models.Schedule{Kind: "cron", CronExpr: "0 0 * * * *", Timezone: "UTC"}
+	from := time.Date(2026, 3, 1, 0, 30, 0, 0, time.UTC)
+	now := from.Add(3 * time.Hour)
+
+	s := &Scheduler{}
+	for _, keep := range []int{0, 1} {
+		runs, total, err := s.missedRuns(schedule, from, now, keep)
+		if err != nil {
+			t.Fatalf("missedRuns: %v", err)
+		}
+		if total != 3 {
+			t.Errorf("keep %d: total = %d, want 3", keep, total)
+		}
+		if len(runs) != 1 || !runs[0].Equal(time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)) {
+			t.Errorf("keep %d: got %v, want only the latest run", keep, runs)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/GezzyDax/timelith/go-backend/internal/telegram"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	// nativeSyncSpec is how often Telegram scheduled deliveries are topped up and reconciled
+	nativeSyncSpec = "@every 5m"
+	// nativeMinLead keeps uploads far enough ahead for Telegram to accept the schedule_date
+	nativeMinLead = time.Minute
+	// nativeMaxPerChat mirrors Telegram's limit of 100 scheduled messages per chat
+	nativeMaxPerChat = 100
+	// nativeMaxRuns bounds how many cron occurrences a single sync looks at
+	nativeMaxRuns = 500
+)
+
+// syncNativeSchedules reconciles every schedule using Telegram scheduled delivery
+func (s *Scheduler) syncNativeSchedules() {
+	if !s.IsLeader() || s.stopping.Load() {
+		return
+	}
+
+	ctx := context.Background()
+
+	if sent, err := s.db.MarkNativeScheduledMessagesSent(); err != nil {
+		logger.Log.Error("Failed to mark Telegram scheduled messages as sent", zap.Error(err))
+	} else if sent > 0 {
+		logger.Log.Info("Telegram delivered scheduled messages", zap.Int64("count", sent))
+	}
+
+	s.cancelOrphanedNativeMessages(ctx)
+
+	schedules, err := s.db.ListActiveSchedules()
+	if err != nil {
+		logger.Log.Error("Failed to load schedules for Telegram sync", zap.Error(err))
+		return
+	}
+
+	for i := range schedules {
+		if schedules[i].DeliveryMode == "telegram" {
+			s.syncNativeSchedule(ctx, &schedules[i])
+		}
+	}
+}
+
+// cancelOrphanedNativeMessages cancels uploads of schedules that were deleted, paused
+// or switched back to live delivery
+func (s *Scheduler) cancelOrphanedNativeMessages(ctx context.Context) {
+	s.nativeMu.Lock()
+	defer s.nativeMu.Unlock()
+	if s.stopping.Load() {
+		return
+	}
+
+	orphans, err := s.db.ListOrphanedNativeScheduledMessages()
+	if err != nil {
+		logger.Log.Error("Failed to list orphaned Telegram scheduled messages", zap.Error(err))
+		return
+	}
+	s.cancelNativeMessages(ctx, orphans)
+}
+
+// syncNativeSchedule uploads upcoming runs of a schedule into Telegram's scheduled queue
+// and cancels uploads that no longer match the schedule or template
+func (s *Scheduler) syncNativeSchedule(ctx context.Context, schedule *models.Schedule) {
+	s.nativeMu.Lock()
+	defer s.nativeMu.Unlock()
+	if s.stopping.Load() {
+		return
+	}
+
+	template, err := s.db.GetTemplate(schedule.TemplateID)
+	if err != nil {
+		logger.Log.Error("Failed to get template",
+			zap.String("template_id", schedule.TemplateID.String()),
+			zap.Error(err))
+		return
+	}
+
+	channels := make([]*models.Channel, 0, len(schedule.ChannelIDs))
+	channelSet := make(map[uuid.UUID]*models.Channel, len(schedule.ChannelIDs))
+	for _, cidStr := range schedule.ChannelIDs {
+		cid, err := uuid.Parse(cidStr)
+		if err != nil {
+			continue
+		}
+		channel, err := s.db.GetChannel(cid)
+		if err != nil {
+			logger.Log.Error("Failed to get channel",
+				zap.String("channel_id", cid.String()),
+				zap.Error(err))
+			continue
+		}
+		channels = append(channels, channel)
+		channelSet[channel.ID] = channel
+	}
+
+	fingerprint := nativeFingerprint(schedule, template)
+
+	blackouts, err := s.loadBlackouts(schedule)
+	if err != nil {
+		logger.Log.Error("Failed to load blackout windows",
+			zap.String("schedule_id", schedule.ID.String()),
+			zap.Error(err))
+		return
+	}
+
+	pauses, err := loadPauses(s.db)
+	if err != nil {
+		logger.Log.Error("Failed to load pauses",
+			zap.String("schedule_id", schedule.ID.String()),
+			zap.Error(err))
+		return
+	}
+
+	pending, err := s.db.ListPendingNativeScheduledMessages(schedule.ID)
+	if err != nil {
+		logger.Log.Error("Failed to list Telegram scheduled messages",
+			zap.String("schedule_id", schedule.ID.String()),
+			zap.Error(err))
+		return
+	}
+
+	cronSchedule, loc, err := parseSchedule(schedule)
+	if err != nil {
+		logger.Log.Error("Failed to parse schedule",
+			zap.String("schedule_id", schedule.ID.String()),
+			zap.Error(err))
+		return
+	}
+
+	// Anything uploaded for an older version of the schedule, a run it no longer fires, a
+	// removed channel, a day that a holiday calendar now excludes or a paused account or
+	// channel is cancelled
+	var stale []models.NativeScheduledMessage
+	uploaded := make(map[string]bool, len(pending))
+	perChannel := make(map[uuid.UUID]int)
+	for _, msg := range pending {
+		channel := channelSet[msg.ChannelID]
+		if channel == nil || msg.Fingerprint != nativeUpload(schedule, template, channel, msg.RunAt, fingerprint).fingerprint ||
+			!isFireTime(cronSchedule, loc, msg.RunAt) ||
+			!s.shouldRunOn(schedule, msg.RunAt) || pauses.forSend(msg.AccountID, msg.ChannelID) != nil {
+			stale = append(stale, msg)
+			continue
+		}
+		uploaded[nativeKey(msg.ChannelID, msg.RunAt)] = true
+		perChannel[msg.ChannelID]++
+	}
+	s.cancelNativeMessages(ctx, stale)
+
+	// Nothing is uploaded while paused; cancelled runs are uploaded again once resumed
+	if pauses.forRun(schedule) != nil {
+		return
+	}
+
+	now := time.Now()
+	horizon := time.Duration(schedule.ScheduleAheadHours) * time.Hour
+	runs, err := s.upcomingRuns(schedule, now.Add(nativeMinLead), now.Add(horizon))
+	if err != nil {
+		logger.Log.Error("Failed to compute upcoming runs",
+			zap.String("schedule_id", schedule.ID.String()),
+			zap.Error(err))
+		return
+	}
+
+	// Once Telegram has sent everything, a schedule without further runs is completed
+	if len(runs) == 0 && len(uploaded) == 0 {
+		if reason := exhaustedReason(schedule, cronSchedule.Next(now.In(loc))); reason != "" {
+			s.completeSchedule(schedule.ID, reason)
+			return
+		}
+	}
+
+	if len(runs) > 0 {
+		schedule.NextRunAt = models.NewNullTime(runs[0])
+		if err := s.db.SetScheduleNextRun(schedule.ID, schedule.NextRunAt); err != nil {
+			logger.Log.Error("Failed to update schedule next_run_at",
+				zap.String("schedule_id", schedule.ID.String()),
+				zap.Error(err))
+		}
+	}
+
+	var rotation *accountRotation
+	var limited bool
+	warmedOut := make(map[uuid.UUID]bool)
+	sessions := make(map[uuid.UUID]error)
+	for _, runAt := range runs {
+		order := channels
+		if schedule.ShuffleChannels {
+			order = shuffled(channels)
+		}
+
+		// Telegram sends the uploads itself, so pacing is baked into their send times
+		var offset time.Duration
+		for i, channel := range order {
+			if i > 0 {
+				offset += pacingDelay(schedule)
+			}
+			if uploaded[nativeKey(channel.ID, runAt)] || perChannel[channel.ID] >= nativeMaxPerChat {
+				continue
+			}
+			if pauses.channels[channel.ID] != nil {
+				continue
+			}
+
+			if rotation == nil {
+				if rotation, err = newRotation(s.db, schedule, pauses); err != nil {
+					s.logJobExecution(schedule.ID, "failed", "", err.Error())
+					return
+				}
+			}
+			account, err := rotation.pick(channel.ID)
+			if err != nil {
+				s.logJobExecution(schedule.ID, "failed", "",
+					fmt.Sprintf("Failed to schedule in Telegram for %s: %v", channel.Name, err))
+				continue
+			}
+			sessionErr, loaded := sessions[account.ID]
+			if !loaded {
+				sessionErr = s.sessionManager.LoadSession(ctx, account)
+				sessions[account.ID] = sessionErr
+				if sessionErr != nil {
+					s.logJobExecution(schedule.ID, "failed", "",
+						fmt.Sprintf("Failed to load session of %s: %v", account.Phone, sessionErr))
+				}
+			}
+			if sessionErr != nil {
+				continue
+			}
+
+			sendAt := runAt.Add(offset)
+			if b := blackouts.forChannel(channel.ID, sendAt); b != nil {
+				if b.skip {
+					continue
+				}
+				sendAt = b.until
+			}
+			block, err := s.nativeWarmupBlock(account, sendAt)
+			if err != nil {
+				s.logJobExecution(schedule.ID, "failed", "", err.Error())
+				return
+			}
+			if block != nil {
+				if !warmedOut[account.ID] {
+					s.logJobExecution(schedule.ID, "skipped",
+						fmt.Sprintf("Not scheduled in Telegram for %s: %s", account.Phone, block.reason), "")
+					warmedOut[account.ID] = true
+				}
+				continue
+			}
+
+			// Counts toward the account's quotas and the pool's daily limit at its send
+			// time unless the upload fails or is cancelled
+			member, err := s.db.IsChannelMember(account.ID, channel.ID)
+			if err != nil {
+				s.logJobExecution(schedule.ID, "failed", "", fmt.Sprintf("Failed to load channel membership: %v", err))
+				return
+			}
+			var poolBlock *quotaBlock
+			sendID, err := s.db.ReserveAccountSend(account.ID, channel.ID, schedule.ID, !member, sendAt,
+				func(_ *models.AccountUsage, pool *models.PoolUsage) bool {
+					poolBlock = checkPoolLimit(pool, sendAt)
+					return poolBlock == nil
+				})
+			if err != nil {
+				s.logJobExecution(schedule.ID, "failed", "", fmt.Sprintf("Failed to reserve account send: %v", err))
+				return
+			}
+			if sendID == 0 {
+				if !limited {
+					s.logJobExecution(schedule.ID, "skipped",
+						fmt.Sprintf("Not scheduled in Telegram: %s", poolBlock.reason), "")
+					limited = true
+				}
+				continue
+			}
+			upload := nativeUpload(schedule, template, channel, runAt, fingerprint)
+			job := &MessageJob{
+				ScheduleID: schedule.ID,
+				Account:    account,
+				Template:   template,
+				Channel:    channel,
+				Message:    upload.message,
+			}
+
+			ids, err := s.dispatcher.send(ctx, job, telegram.SendOptions{ScheduleDate: sendAt})
+			if err != nil {
+				logger.Log.Error("Failed to upload Telegram scheduled message",
+					zap.String("schedule_id", schedule.ID.String()),
+					zap.String("channel", channel.ChatID),
+					zap.Time("send_at", sendAt),
+					zap.Error(err))
+				s.logJobExecution(schedule.ID, "failed", "",
+					fmt.Sprintf("Failed to schedule in Telegram for %s: %v", channel.Name, err))
+				if err := s.db.ReleaseAccountSend(sendID); err != nil {
+					logger.Log.Error("Failed to release account send",
+						zap.String("account_id", account.ID.String()),
+						zap.Error(err))
+				}
+				continue
+			}
+
+			scheduleID := schedule.ID
+			record := &models.NativeScheduledMessage{
+				ScheduleID:         &scheduleID,
+				AccountID:          account.ID,
+				ChannelID:          channel.ID,
+				RunAt:              runAt,
+				SendAt:             sendAt,
+				TelegramMessageIDs: ids,
+				Fingerprint:        upload.fingerprint,
+				Status:             "scheduled",
+			}
+			if err := s.db.CreateNativeScheduledMessage(record); err != nil {
+				logger.Log.Error("Failed to record Telegram scheduled message",
+					zap.String("schedule_id", schedule.ID.String()),
+					zap.Error(err))
+			} else if err := s.db.AttachNativeAccountSend(sendID, record.ID); err != nil {
+				logger.Log.Error("Failed to record account send",
+					zap.String("account_id", account.ID.String()),
+					zap.Error(err))
+			}
+
+			uploaded[nativeKey(channel.ID, runAt)] = true
+			perChannel[channel.ID]++
+			s.logJobExecution(schedule.ID, "success",
+				fmt.Sprintf("Scheduled in Telegram for %s at %s", channel.Name, sendAt.UTC().Format(time.RFC3339)), "")
+		}
+	}
+}
+
+// cancelNativeMessages deletes messages from Telegram's scheduled queue and marks them cancelled
+func (s *Scheduler) cancelNativeMessages(ctx context.Context, messages []models.NativeScheduledMessage) {
+	for _, msg := range messages {
+		if len(msg.TelegramMessageIDs) > 0 {
+			if err := s.deleteNativeMessage(ctx, &msg); err != nil {
+				// Left as scheduled so the next sync retries the cancellation
+				logger.Log.Error("Failed to cancel Telegram scheduled message",
+					zap.String("id", msg.ID.String()),
+					zap.Error(err))
+				continue
+			}
+		}
+
+		if err := s.db.UpdateNativeScheduledMessageStatus(msg.ID, "cancelled"); err != nil {
+			logger.Log.Error("Failed to mark Telegram scheduled message cancelled",
+				zap.String("id", msg.ID.String()),
+				zap.Error(err))
+			continue
+		}
+
+		logger.Log.Info("Cancelled Telegram scheduled message",
+			zap.String("id", msg.ID.String()),
+			zap.Time("send_at", msg.SendAt))
+	}
+}
+
+func (s *Scheduler) deleteNativeMessage(ctx context.Context, msg *models.NativeScheduledMessage) error {
+	account, err := s.db.GetAccount(msg.AccountID)
+	if err != nil {
+		return fmt.Errorf("account not found: %w", err)
+	}
+	channel, err := s.db.GetChannel(msg.ChannelID)
+	if err != nil {
+		return fmt.Errorf("channel not found: %w", err)
+	}
+	if err := s.sessionManager.LoadSession(ctx, account); err != nil {
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+	return s.sessionManager.DeleteScheduledMessages(ctx, account.Phone, channel.ChatID, msg.TelegramMessageIDs)
+}
+
+// upcomingRuns lists cron fire times in (from, until] that pass the schedule's day filter
+func (s *Scheduler) upcomingRuns(schedule *models.Schedule, from, until time.Time) ([]time.Time, error) {
+	cronSchedule, loc, err := parseSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []time.Time
+	t := from.In(loc)
+	for i := 0; i < nativeMaxRuns; i++ {
+		t = cronSchedule.Next(t)
+		if t.IsZero() || t.After(until) {
+			break
+		}
+		if s.shouldRunOn(schedule, t) {
+			runs = append(runs, t)
+		}
+	}
+	return runs, nil
+}
+
+// isFireTime reports whether the schedule, evaluated in loc, still fires at t. A run moved
+// forward out of a DST gap is found by looking back over the longest gap.
+func isFireTime(cronSchedule cron.Schedule, loc *time.Location, t time.Time) bool {
+	if cronSchedule.Next(t.In(loc).Add(-time.Nanosecond)).Equal(t) {
+		return true
+	}
+	next := t.In(loc).Add(-maxDSTGap)
+	for i := 0; i < maxWallSteps; i++ {
+		next = cronSchedule.Next(next)
+		if next.IsZero() || next.After(t) {
+			return false
+		}
+		if next.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nativeMessage is the text of one upload and the fingerprint it is recorded under
+type nativeMessage struct {
+	message     string
+	fingerprint string
+}
+
+// nativeUpload renders the template for a run in a channel. The fingerprint extends the
+// schedule's with the rendered text, so an upload whose text would now differ is replaced.
+func nativeUpload(schedule *models.Schedule, template *models.Template, channel *models.Channel, runAt time.Time, fingerprint string) nativeMessage {
+	message, _ := renderMessage(template.Content, messageVariables(schedule, channel, runAt))
+	sum := sha256.Sum256([]byte(fingerprint + "|" + message))
+	return nativeMessage{message: message, fingerprint: hex.EncodeToString(sum[:])}
+}
+
+// nativeFingerprint identifies the version of a schedule and template that an upload was made for
+func nativeFingerprint(schedule *models.Schedule, template *models.Template) string {
+	var accountID string
+	if schedule.AccountID != nil {
+		accountID = schedule.AccountID.String()
+	}
+	parts := []string{
+		accountID,
+		schedule.TemplateID.String(),
+		schedule.Kind,
+		schedule.CronExpr,
+		fingerprintTime(schedule.OnceAt),
+		fmt.Sprint(schedule.IntervalSeconds),
+		fingerprintTime(schedule.IntervalAnchor),
+		fingerprintTime(schedule.StartsAt),
+		fingerprintTime(schedule.EndsAt),
+		fmt.Sprint(schedule.MaxRuns),
+		schedule.Timezone,
+		schedule.DayFilter.String,
+		fmt.Sprint(schedule.CustomDays),
+		fmt.Sprint(schedule.IncludeCalendarIDs, schedule.ExcludeCalendarIDs),
+		fmt.Sprint(schedule.DelayMinSeconds, schedule.DelayMaxSeconds, schedule.LoadBalance),
+		fmt.Sprint(schedule.FallbackAccountIDs),
+		template.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if rotates(schedule) {
+		parts = append(parts, schedule.RotationStrategy)
+	}
+	if schedule.AccountPoolID != nil {
+		parts = append(parts, schedule.AccountPoolID.String())
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprintTime formats an optional schedule time for the fingerprint, in UTC
+func fingerprintTime(t models.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339Nano)
+}
+
+// nativeKey identifies the upload of a run to a channel, whatever zone the run time is in
+func nativeKey(channelID uuid.UUID, runAt time.Time) string {
+	return fmt.Sprintf("%s@%d", channelID, runAt.UTC().Unix())
+}
@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+)
+
+func TestNativeUploadOfZonedRunIsRecognised(t *testing.T) {
+	db := openTestDB(t)
+	f := seedSchedule(t, db, "Asia/Tokyo")
+
+	schedule := &models.Schedule{
+		ID:       f.scheduleID,
+		Kind:     "cron",
+		CronExpr: "0 9 * * *",
+		Timezone: "Asia/Tokyo",
+	}
+	s := &Scheduler{db: db}
+	now := time.Now()
+	runs, err := s.upcomingRuns(schedule, now, now.Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("upcomingRuns: %v", err)
+	}
+	if len(runs) == 0 {
+		t.Fatal("no upcoming runs")
+	}
+	run := runs[0]
+	if run.Location().String() != "Asia/Tokyo" {
+		t.Fatalf("run is in %s, want the schedule's zone", run.Location())
+	}
+
+	scheduleID := f.scheduleID
+	record := &models.NativeScheduledMessage{
+		ScheduleID:         &scheduleID,
+		AccountID:          f.accountID,
+		ChannelID:          f.channelID,
+		RunAt:              run,
+		SendAt:             run,
+		TelegramMessageIDs: models.IntList{1},
+		Fingerprint:        "test",
+		Status:             "scheduled",
+	}
+	if err := db.CreateNativeScheduledMessage(record); err != nil {
+		t.Fatalf("CreateNativeScheduledMessage: %v", err)
+	}
+
+	pending, err := db.ListPendingNativeScheduledMessages(f.scheduleID)
+	if err != nil {
+		t.Fatalf("ListPendingNativeScheduledMessages: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("got %d pending uploads, want 1", len(pending))
+	}
+	saved := pending[0]
+	if !saved.RunAt.Equal(run) {
+		t.Errorf("run_at read back as %s, want %s", saved.RunAt, run)
+	}
+	if !saved.SendAt.Equal(run) {
+		t.Errorf("send_at read back as %s, want %s", saved.SendAt, run)
+	}
+	if nativeKey(saved.ChannelID, saved.RunAt) != nativeKey(f.channelID, run) {
+		t.Error("saved upload is not recognised as the upload of the run")
+	}
+	if !s.shouldRunOn(schedule, saved.RunAt) {
+		t.Error("saved run is not on a day the schedule runs")
+	}
+}
+
+func TestNativeUploadRendersPerChannelAndRun(t *testing.T) {
+	schedule := &models.Schedule{Name: "Morning", Timezone: "Asia/Tokyo"}
+	template := &models.Template{Content: "{{channel}} {{date}}"}
+	news := &models.Channel{Name: "News"}
+	sport := &models.Channel{Name: "Sport"}
+	runAt := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+
+	upload := nativeUpload(schedule, template, news, runAt, "v1")
+	if upload.message != "News 2026-03-02" {
+		t.Errorf("message = %q, want it rendered in the schedule's zone", upload.message)
+	}
+	if other := nativeUpload(schedule, template, sport, runAt, "v1"); other.fingerprint == upload.fingerprint {
+		t.Error("uploads with different text should have different fingerprints")
+	}
+	if next := nativeUpload(schedule, template, news, runAt.Add(24*time.Hour), "v1"); next.fingerprint == upload.fingerprint {
+		t.Error("uploads for different days should have different fingerprints")
+	}
+	if again := nativeUpload(schedule, template, news, runAt, "v1"); again != upload {
+		t.Error("the same upload should render the same message and fingerprint")
+	}
+}
+
+func TestIsFireTime(t *testing.T) {
+	schedule := &models.Schedule{Kind: "cron", CronExpr: "0 30 2 * * *", Timezone: "Europe/Berlin"}
+	cronSchedule, loc, err := parseSchedule(schedule)
+	if err != nil {
+		t.Fatalf("parseSchedule: %v", err)
+	}
+
+	regular := time.Date(2026, 3, 28, 2, 30, 0, 0, loc)
+	// 02:30 does not exist on the spring-forward day and fires at 03:30 instead
+	shifted := cronSchedule.Next(time.Date(2026, 3, 29, 0, 0, 0, 0, loc))
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"regular run", regular, true},
+		{"regular run read back in UTC", regular.UTC(), true},
+		{"run moved out of the DST gap", shifted, true},
+		{"not a fire time", regular.Add(time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFireTime(cronSchedule, loc, tt.at); got != tt.want {
+				t.Errorf("isFireTime(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
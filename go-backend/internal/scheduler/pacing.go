@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"math/rand"
+	"time"
+	"unicode/utf8"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// typingPerChar is how long simulated typing takes per character of the message
+	typingPerChar = 50 * time.Millisecond
+	// minTyping and maxTyping bound the simulated typing; Telegram shows the action for ~5s
+	minTyping = time.Second
+	maxTyping = 5 * time.Second
+)
+
+// pacingDelay picks the pause after a send before the account sends again, between
+// DelayMinSeconds and DelayMaxSeconds. The normal distribution centers on the middle of
+// the range with the bounds three standard deviations away.
+func pacingDelay(schedule *models.Schedule) time.Duration {
+	minDelay := float64(schedule.DelayMinSeconds)
+	maxDelay := float64(schedule.DelayMaxSeconds)
+	if maxDelay <= 0 {
+		return 0
+	}
+	if minDelay >= maxDelay {
+		return seconds(minDelay)
+	}
+
+	var delay float64
+	switch schedule.DelayDistribution {
+	case "normal":
+		mean := (minDelay + maxDelay) / 2
+		stddev := (maxDelay - minDelay) / 6
+		delay = rand.NormFloat64()*stddev + mean
+		if delay < minDelay {
+			delay = minDelay
+		}
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	default:
+		delay = minDelay + rand.Float64()*(maxDelay-minDelay)
+	}
+	return seconds(delay)
+}
+
+// parkForPacing takes the account's send slot for the job, or parks the job until the
+// pause after the account's previous send is over. The pause applies across all schedules
+// and runs that share the account.
+func (d *Dispatcher) parkForPacing(ctx context.Context, job *MessageJob) bool {
+	until, err := d.db.ClaimAccountSendSlot(job.Account.ID, time.Now(), pacingDelay(job.Schedule))
+	if err != nil {
+		// Sending without the pause beats holding the message back indefinitely
+		logger.Log.Error("Failed to claim account send slot",
+			zap.String("account_id", job.Account.ID.String()),
+			zap.Error(err))
+		return false
+	}
+	if until.IsZero() {
+		return false
+	}
+
+	logger.Log.Debug("Parking message job until the account's pacing pause is over",
+		zap.String("job_id", job.ID.String()),
+		zap.String("account", job.Account.Phone),
+		zap.Time("until", until))
+	if err := d.queue.Park(ctx, job.ID, job.workerID, until, "account pacing"); err != nil {
+		logger.Log.Error("Failed to park dispatcher job",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+	}
+	return true
+}
+
+// typingDuration is how long typing is shown before a message is sent
+func typingDuration(message string) time.Duration {
+	d := time.Duration(utf8.RuneCountInString(message)) * typingPerChar
+	if d < minTyping {
+		return minTyping
+	}
+	if d > maxTyping {
+		return maxTyping
+	}
+	return d
+}
+
+// shuffled returns the items in random order, leaving the input untouched
+func shuffled[T any](items []T) []T {
+	shuffled := append([]T(nil), items...)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
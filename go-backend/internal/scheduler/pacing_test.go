@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAccountSendSlotPacesAcrossSchedules(t *testing.T) {
+	db := openTestDB(t)
+	f := seedSchedule(t, db, "UTC")
+
+	const workers = 8
+	var wg sync.WaitGroup
+	claimed := make(chan bool, workers)
+	now := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			until, err := db.ClaimAccountSendSlot(f.accountID, now, time.Minute)
+			if err != nil {
+				t.Errorf("ClaimAccountSendSlot: %v", err)
+				return
+			}
+			claimed <- until.IsZero()
+		}()
+	}
+	wg.Wait()
+	close(claimed)
+
+	sends := 0
+	for ok := range claimed {
+		if ok {
+			sends++
+		}
+	}
+	if sends != 1 {
+		t.Errorf("%d concurrent sends of one account claimed the slot, want 1", sends)
+	}
+
+	until, err := db.ClaimAccountSendSlot(f.accountID, now.Add(30*time.Second), time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimAccountSendSlot: %v", err)
+	}
+	if want := now.Add(time.Minute); until.Sub(want).Abs() > time.Millisecond {
+		t.Errorf("next send allowed at %v, want %v", until, want)
+	}
+	until, err = db.ClaimAccountSendSlot(f.accountID, now.Add(time.Minute), time.Minute)
+	if err != nil || !until.IsZero() {
+		t.Errorf("slot should be free once the pause is over, got %v, %v", until, err)
+	}
+}
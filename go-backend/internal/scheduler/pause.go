@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/database"
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// pauseRecheckInterval is how often a parked job looks again whether its pause was lifted
+const pauseRecheckInterval = 15 * time.Second
+
+// pauseSet indexes the active pauses by what they stop
+type pauseSet struct {
+	global   *models.Pause
+	accounts map[uuid.UUID]*models.Pause
+	channels map[uuid.UUID]*models.Pause
+}
+
+func loadPauses(db *database.DB) (*pauseSet, error) {
+	pauses, err := db.ListPauses()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pauses: %w", err)
+	}
+
+	set := &pauseSet{
+		accounts: make(map[uuid.UUID]*models.Pause),
+		channels: make(map[uuid.UUID]*models.Pause),
+	}
+	for i := range pauses {
+		pause := &pauses[i]
+		switch {
+		case pause.Scope == "global":
+			set.global = pause
+		case pause.Scope == "account" && pause.TargetID != nil:
+			set.accounts[*pause.TargetID] = pause
+		case pause.Scope == "channel" && pause.TargetID != nil:
+			set.channels[*pause.TargetID] = pause
+		}
+	}
+	return set, nil
+}
+
+// forRun returns the pause that stops a whole run of the schedule: the global pause, or
+// the pause of its account unless rotation picks another account
+func (p *pauseSet) forRun(schedule *models.Schedule) *models.Pause {
+	if p.global != nil {
+		return p.global
+	}
+	if !rotates(schedule) && schedule.AccountID != nil {
+		return p.accounts[*schedule.AccountID]
+	}
+	return nil
+}
+
+// forSend returns the pause that stops the account from sending to the channel
+func (p *pauseSet) forSend(accountID, channelID uuid.UUID) *models.Pause {
+	if p.global != nil {
+		return p.global
+	}
+	if pause := p.accounts[accountID]; pause != nil {
+		return pause
+	}
+	return p.channels[channelID]
+}
+
+// forChannel returns the pause that stops sending to the channel from any account
+func (p *pauseSet) forChannel(channelID uuid.UUID) *models.Pause {
+	if p.global != nil {
+		return p.global
+	}
+	return p.channels[channelID]
+}
+
+// describePause names a pause for logs and job errors
+func describePause(pause *models.Pause) string {
+	desc := "sending paused"
+	if pause.Scope != "global" {
+		desc = fmt.Sprintf("%s %s paused", pause.Scope, pause.TargetID)
+	}
+	if pause.Reason.Valid && pause.Reason.String != "" {
+		desc += ": " + pause.Reason.String
+	}
+	return desc
+}
+
+// parkIfPaused parks the job while its account or channel is paused and reports whether it
+// did. The job is not lost: it is claimed again after pauseRecheckInterval and goes out
+// once the pause is lifted. If the pauses cannot be read, the job is parked as well.
+func (d *Dispatcher) parkIfPaused(ctx context.Context, job *MessageJob) bool {
+	var reason string
+	pauses, err := loadPauses(d.db)
+	if err != nil {
+		reason = err.Error()
+	} else if pause := pauses.forSend(job.Account.ID, job.Channel.ID); pause != nil {
+		reason = describePause(pause)
+	} else {
+		return false
+	}
+
+	logger.Log.Debug("Parking paused message job",
+		zap.String("job_id", job.ID.String()),
+		zap.String("reason", reason))
+	if err := d.queue.Park(ctx, job.ID, job.workerID, time.Now().Add(pauseRecheckInterval), reason); err != nil {
+		logger.Log.Error("Failed to park dispatcher job",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+	}
+	return true
+}
+
+// ApplyPauses makes a changed pause take effect on Telegram scheduled deliveries right away
+// instead of at the next periodic sync. Live sends pick pauses up on their own.
+func (s *Scheduler) ApplyPauses() {
+	go s.syncNativeSchedules()
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+)
+
+// poolLimitWindow is the rolling period an account pool's daily limit applies to
+const poolLimitWindow = 24 * time.Hour
+
+// poolBudget tracks how many more messages a schedule's pool may send during a run. It
+// only plans the run; the send reservation enforces the limit across runs and instances.
+type poolBudget struct {
+	pool      *models.AccountPool
+	remaining int
+}
+
+// loadPoolBudget returns what is left of the daily limit of the schedule's pool at the
+// given time, or nil when the schedule has no pool or the pool has no limit
+func (s *Scheduler) loadPoolBudget(schedule *models.Schedule, at time.Time) (*poolBudget, error) {
+	if schedule.AccountPoolID == nil {
+		return nil, nil
+	}
+	pool, err := s.db.GetAccountPool(*schedule.AccountPoolID)
+	if err != nil {
+		return nil, fmt.Errorf("account pool not found: %w", err)
+	}
+	if pool.DailyLimit <= 0 {
+		return nil, nil
+	}
+
+	used, err := s.db.CountAccountPoolUsage(pool.ID, at.Add(-poolLimitWindow), at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count account pool usage: %w", err)
+	}
+	return &poolBudget{pool: pool, remaining: pool.DailyLimit - used}, nil
+}
+
+// take reserves one message of the budget and reports false once it is used up. A nil
+// budget is unlimited.
+func (b *poolBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+func (b *poolBudget) describe() string {
+	return describePoolLimit(b.pool)
+}
+
+// checkPoolLimit returns the pool's daily limit as a block when its schedules already sent
+// that many messages, or nil when there is room or no limit
+func checkPoolLimit(usage *models.PoolUsage, now time.Time) *quotaBlock {
+	if usage == nil || usage.Sent < usage.Pool.DailyLimit {
+		return nil
+	}
+	return &quotaBlock{
+		reason: describePoolLimit(usage.Pool),
+		until:  freesAt(usage.Oldest, poolLimitWindow, now),
+		pool:   true,
+	}
+}
+
+func describePoolLimit(pool *models.AccountPool) string {
+	return fmt.Sprintf("daily limit of %d messages of account pool %q reached", pool.DailyLimit, pool.Name)
+}
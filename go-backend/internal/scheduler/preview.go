@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+)
+
+// previewScanLimit bounds how many fire times a preview inspects, skipped ones included
+const previewScanLimit = 10000
+
+// PreviewRun is a fire time of a schedule and whether it would actually run
+type PreviewRun struct {
+	Time       time.Time  `json:"time"` // In the schedule's timezone
+	UTC        time.Time  `json:"utc"`
+	Skipped    bool       `json:"skipped"`
+	Reason     string     `json:"reason,omitempty"`      // day_filter, calendar, blackout, ends_at, max_runs
+	Blackout   string     `json:"blackout,omitempty"`    // Window that skips or defers the run
+	DeferredTo *time.Time `json:"deferred_to,omitempty"` // In the schedule's timezone
+}
+
+// Preview lists fire times of a schedule after from until count of them would run.
+// Fire times that would be skipped are included with the reason; the list stops at the
+// first fire time past ends_at or beyond max_runs. Only global and schedule blackout
+// windows are applied; channel windows affect single channels, not the run.
+func (s *Scheduler) Preview(schedule *models.Schedule, from time.Time, count int) ([]PreviewRun, error) {
+	if err := ValidateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	loc, _ := time.LoadLocation(schedule.Timezone)
+	base, err := parseBaseSchedule(schedule, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	blackouts, err := s.loadBlackouts(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := -1
+	if schedule.MaxRuns > 0 {
+		remaining = schedule.MaxRuns - schedule.RunCount
+	}
+
+	// Fire times at exactly starts_at are included
+	t := from.In(loc)
+	if schedule.StartsAt.Valid && t.Before(schedule.StartsAt.Time) {
+		t = schedule.StartsAt.Time.Add(-time.Nanosecond).In(loc)
+	}
+
+	runs := make([]PreviewRun, 0, count)
+	effective := 0
+	for i := 0; i < previewScanLimit && effective < count; i++ {
+		t = base.Next(t)
+		if t.IsZero() {
+			break
+		}
+
+		run := PreviewRun{Time: t.In(loc), UTC: t.UTC()}
+		dayReason := s.dayReason(schedule, t)
+		switch {
+		case schedule.EndsAt.Valid && t.After(schedule.EndsAt.Time):
+			run.Skipped, run.Reason = true, "ends_at"
+		case remaining == 0:
+			run.Skipped, run.Reason = true, "max_runs"
+		case dayReason != "":
+			run.Skipped, run.Reason = true, dayReason
+		default:
+			if b := blackouts.forRun(t); b != nil {
+				run.Blackout = b.window.Name
+				if b.skip {
+					run.Skipped, run.Reason = true, "blackout"
+				} else {
+					until := b.until.In(loc)
+					run.DeferredTo = &until
+				}
+			}
+		}
+		runs = append(runs, run)
+
+		if run.Reason == "ends_at" || run.Reason == "max_runs" {
+			break
+		}
+		if !run.Skipped {
+			effective++
+			if remaining > 0 {
+				remaining--
+			}
+		}
+	}
+	return runs, nil
+}
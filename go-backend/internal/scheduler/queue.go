@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/config"
+	"github.com/GezzyDax/timelith/go-backend/internal/database"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrLeaseLost is returned when a worker finishes a job whose lease expired and that
+// another worker has since claimed. The transition is not applied.
+var ErrLeaseLost = errors.New("job lease was lost to another worker")
+
+// Queue stores dispatcher jobs until they are due and hands each one to a single worker.
+// A claimed job that is not completed, retried or killed within its visibility timeout
+// is handed out again, so work held by a crashed worker is not lost.
+type Queue interface {
+	// Push stores a job that becomes claimable at job.RunAt and assigns its ID.
+	// A job pushed with status held is not claimable until it is released.
+	Push(ctx context.Context, job *models.DispatchJob) error
+	// Release makes a held job claimable at runAt; other jobs are left alone
+	Release(ctx context.Context, id uuid.UUID, runAt time.Time) error
+	// Claim returns the next due job locked for workerID, or nil when nothing is due
+	Claim(ctx context.Context, workerID string, visibility time.Duration) (*models.DispatchJob, error)
+	// Complete marks a job claimed by workerID as succeeded. This and the other transitions
+	// of a claimed job return ErrLeaseLost when workerID no longer holds it.
+	Complete(ctx context.Context, id uuid.UUID, workerID string) error
+	// Retry releases a claimed job to be claimed again at runAt
+	Retry(ctx context.Context, id uuid.UUID, workerID string, runAt time.Time, lastError string) error
+	// Park releases a claimed job to be claimed again at runAt without counting the attempt
+	Park(ctx context.Context, id uuid.UUID, workerID string, runAt time.Time, reason string) error
+	// Reassign hands a claimed job to another sending account
+	Reassign(ctx context.Context, id, accountID uuid.UUID) error
+	// Kill moves a claimed job to the dead state
+	Kill(ctx context.Context, id uuid.UUID, workerID string, lastError string) error
+	// ListDead returns up to limit dead jobs, most recently killed first, optionally of one schedule
+	ListDead(ctx context.Context, scheduleID *uuid.UUID, limit int) ([]models.DispatchJob, error)
+	// Requeue makes a dead job claimable now with its attempts reset and returns it,
+	// or nil when the job is not dead
+	Requeue(ctx context.Context, id uuid.UUID) (*models.DispatchJob, error)
+	// Discard deletes a dead job and reports whether there was one
+	Discard(ctx context.Context, id uuid.UUID) (bool, error)
+	// Purge removes succeeded jobs that finished before the cutoff
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	Close() error
+}
+
+// NewQueue creates the queue backend selected by QUEUE_BACKEND
+func NewQueue(cfg *config.Config, db *database.DB) (Queue, error) {
+	switch cfg.QueueBackend {
+	case "", "postgres":
+		return NewPostgresQueue(db), nil
+	case "redis":
+		return NewRedisQueue(cfg.RedisURL)
+	default:
+		return nil, fmt.Errorf("unsupported queue backend: %s", cfg.QueueBackend)
+	}
+}
+
+// PostgresQueue stores dispatcher jobs in the dispatch_jobs table so they survive restarts.
+// Workers claim jobs with SELECT ... FOR UPDATE SKIP LOCKED, which lets any number of
+// workers and processes share the queue without handing the same job out twice.
+type PostgresQueue struct {
+	db *database.DB
+}
+
+func NewPostgresQueue(db *database.DB) *PostgresQueue {
+	return &PostgresQueue{db: db}
+}
+
+func (q *PostgresQueue) Push(ctx context.Context, job *models.DispatchJob) error {
+	return q.db.CreateDispatchJob(job)
+}
+
+func (q *PostgresQueue) Release(ctx context.Context, id uuid.UUID, runAt time.Time) error {
+	return q.db.ReleaseDispatchJob(id, runAt)
+}
+
+func (q *PostgresQueue) Claim(ctx context.Context, workerID string, visibility time.Duration) (*models.DispatchJob, error) {
+	return q.db.ClaimDispatchJob(workerID, visibility)
+}
+
+func (q *PostgresQueue) Complete(ctx context.Context, id uuid.UUID, workerID string) error {
+	return fenced(q.db.CompleteDispatchJob(id, workerID))
+}
+
+func (q *PostgresQueue) Retry(ctx context.Context, id uuid.UUID, workerID string, runAt time.Time, lastError string) error {
+	return fenced(q.db.RetryDispatchJob(id, workerID, runAt, lastError))
+}
+
+func (q *PostgresQueue) Park(ctx context.Context, id uuid.UUID, workerID string, runAt time.Time, reason string) error {
+	return fenced(q.db.ParkDispatchJob(id, workerID, runAt, reason))
+}
+
+func (q *PostgresQueue) Reassign(ctx context.Context, id, accountID uuid.UUID) error {
+	return q.db.ReassignDispatchJob(id, accountID)
+}
+
+func (q *PostgresQueue) Kill(ctx context.Context, id uuid.UUID, workerID string, lastError string) error {
+	return fenced(q.db.KillDispatchJob(id, workerID, lastError))
+}
+
+// fenced turns a transition that found the job no longer locked by the worker into ErrLeaseLost
+func fenced(applied bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (q *PostgresQueue) ListDead(ctx context.Context, scheduleID *uuid.UUID, limit int) ([]models.DispatchJob, error) {
+	return q.db.ListDeadDispatchJobs(scheduleID, limit)
+}
+
+func (q *PostgresQueue) Requeue(ctx context.Context, id uuid.UUID) (*models.DispatchJob, error) {
+	return q.db.RequeueDispatchJob(id)
+}
+
+func (q *PostgresQueue) Discard(ctx context.Context, id uuid.UUID) (bool, error) {
+	return q.db.DiscardDispatchJob(id)
+}
+
+func (q *PostgresQueue) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return q.db.PurgeFinishedDispatchJobs(before)
+}
+
+// Close is a no-op; the database connection is owned by the caller
+func (q *PostgresQueue) Close() error {
+	return nil
+}
@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	quotaHourWindow = time.Hour
+	quotaDayWindow  = 24 * time.Hour
+)
+
+// quotaBlock is the quota that keeps an account from sending and when it frees up
+type quotaBlock struct {
+	reason string
+	until  time.Time
+	pool   bool // The daily limit of the pool, which holds back all of its accounts
+}
+
+// quotaLoad is what an account would have sent in its quota windows with one more message
+type quotaLoad struct {
+	usage    models.AccountUsage
+	planned  int  // Messages assigned to it but not sent yet
+	newChats int  // Planned messages that are its first post in a chat
+	newChat  bool // The next message is its first post in the chat
+}
+
+// checkQuota returns the quota the account's next message would exceed, or nil when it is
+// within all of them
+func checkQuota(account *models.Account, load quotaLoad, now time.Time) *quotaBlock {
+	if limit := account.HourlyQuota; limit > 0 && load.usage.HourlySent+load.planned >= limit {
+		return &quotaBlock{
+			reason: fmt.Sprintf("hourly quota of %d messages reached", limit),
+			until:  freesAt(load.usage.OldestHourly, quotaHourWindow, now),
+		}
+	}
+	if limit := account.DailyQuota; limit > 0 && load.usage.DailySent+load.planned >= limit {
+		return &quotaBlock{
+			reason: fmt.Sprintf("daily quota of %d messages reached", limit),
+			until:  freesAt(load.usage.OldestDaily, quotaDayWindow, now),
+		}
+	}
+	if block := warmupBlock(account, load, now); block != nil {
+		return block
+	}
+	if limit := account.DailyNewChatQuota; limit > 0 && load.newChat && load.usage.DailyNewChats+load.newChats >= limit {
+		return &quotaBlock{
+			reason: fmt.Sprintf("daily quota of %d new chats reached", limit),
+			until:  freesAt(load.usage.OldestNewChat, quotaDayWindow, now),
+		}
+	}
+	return nil
+}
+
+// freesAt is when the oldest send of a window ages out. Without recorded sends the limit
+// was used up by planned messages, which free up a whole window later at the latest.
+func freesAt(oldest models.NullTime, window time.Duration, now time.Time) time.Time {
+	if !oldest.Valid {
+		return now.Add(window)
+	}
+	return oldest.Time.Add(window)
+}
+
+// applyQuota reserves a send of the job's account within its send quotas before the send.
+// An account over quota hands the job to another account of a rotating schedule that has
+// room left; otherwise the job is parked until the quota frees up. It reports whether the
+// job was parked. Bookkeeping errors do not hold sends back.
+func (d *Dispatcher) applyQuota(ctx context.Context, job *MessageJob) bool {
+	now := time.Now()
+	block, err := d.reserveSend(job, now)
+	if err != nil {
+		logger.Log.Error("Failed to reserve account send",
+			zap.String("account_id", job.Account.ID.String()),
+			zap.Error(err))
+		return false
+	}
+	if block == nil {
+		return false
+	}
+
+	if rotates(job.Schedule) && !block.pool {
+		if account, newChat := d.rerouteForQuota(ctx, job); account != nil {
+			from := job.Account.Phone
+			job.Account = account
+			job.newChat = newChat
+			again, err := d.reserveSend(job, now)
+			if err != nil || again == nil {
+				logger.Log.Info("Rerouted message job to an account with quota left",
+					zap.String("job_id", job.ID.String()),
+					zap.String("from", from),
+					zap.String("to", account.Phone),
+					zap.String("reason", block.reason))
+				return false
+			}
+			// Another job took the last of its room in the meantime
+			block = again
+		}
+	}
+
+	logger.Log.Info("Deferring message job until the account quota frees up",
+		zap.String("job_id", job.ID.String()),
+		zap.String("account", job.Account.Phone),
+		zap.String("reason", block.reason),
+		zap.Time("until", block.until))
+	if err := d.queue.Park(ctx, job.ID, job.workerID, block.until, block.reason); err != nil {
+		logger.Log.Error("Failed to park dispatcher job",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+	}
+	return true
+}
+
+// reserveSend counts the job's message toward its account's quotas and its pool's daily
+// limit unless they are used up, and returns the one that is. Concurrent jobs of the
+// account or pool reserve one after another.
+func (d *Dispatcher) reserveSend(job *MessageJob, now time.Time) (*quotaBlock, error) {
+	member, err := d.db.IsChannelMember(job.Account.ID, job.Channel.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load channel membership: %w", err)
+	}
+	job.newChat = !member
+
+	var block *quotaBlock
+	job.sendID, err = d.db.ReserveAccountSend(job.Account.ID, job.Channel.ID, job.ScheduleID, job.newChat, now,
+		func(usage *models.AccountUsage, pool *models.PoolUsage) bool {
+			if block = checkPoolLimit(pool, now); block != nil {
+				return false
+			}
+			block = checkQuota(job.Account, quotaLoad{usage: *usage, newChat: job.newChat}, now)
+			return block == nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// keepSend counts the job's sent message toward its account's quotas. The send reserved
+// before it is kept; without one it is recorded now.
+func (d *Dispatcher) keepSend(job *MessageJob) {
+	if job.sendID != 0 {
+		job.sendID = 0
+		return
+	}
+	if err := d.db.RecordAccountSend(job.Account.ID, job.Channel.ID, job.ScheduleID, job.newChat, time.Now()); err != nil {
+		logger.Log.Error("Failed to record account send",
+			zap.String("account_id", job.Account.ID.String()),
+			zap.Error(err))
+	}
+}
+
+// releaseSend gives back the send reserved for a job whose message did not go out
+func (d *Dispatcher) releaseSend(job *MessageJob) {
+	if job.sendID == 0 {
+		return
+	}
+	if err := d.db.ReleaseAccountSend(job.sendID); err != nil {
+		logger.Log.Error("Failed to release account send",
+			zap.String("account_id", job.Account.ID.String()),
+			zap.Error(err))
+	}
+	job.sendID = 0
+}
+
+// rerouteForQuota moves the job to another eligible account of the schedule that is within
+// its quotas, and returns it along with whether the channel is new to it. It returns nil
+// when there is none.
+func (d *Dispatcher) rerouteForQuota(ctx context.Context, job *MessageJob) (*models.Account, bool) {
+	pauses, err := loadPauses(d.db)
+	if err != nil {
+		return nil, false
+	}
+	rotation, err := newRotation(d.db, job.Schedule, pauses)
+	if err != nil {
+		return nil, false
+	}
+	account := rotation.pickWithin(job.Channel.ID, job.Account.ID)
+	if account == nil {
+		return nil, false
+	}
+	if err := d.queue.Reassign(ctx, job.ID, account.ID); err != nil {
+		logger.Log.Error("Failed to reassign dispatcher job",
+			zap.String("job_id", job.ID.String()),
+			zap.Error(err))
+		return nil, false
+	}
+	return account, !rotation.members[job.Channel.ID][account.ID]
+}
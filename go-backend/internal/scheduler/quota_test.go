@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestReserveSendIsAtomicPerAccount(t *testing.T) {
+	db := openTestDB(t)
+	f := seedSchedule(t, db, "UTC")
+	d := &Dispatcher{db: db}
+
+	const workers = 8
+	var wg sync.WaitGroup
+	results := make(chan *quotaBlock, workers)
+	now := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job := &MessageJob{
+				ScheduleID: f.scheduleID,
+				Account:    &models.Account{ID: f.accountID, HourlyQuota: 1},
+				Channel:    &models.Channel{ID: f.channelID},
+			}
+			block, err := d.reserveSend(job, now)
+			if err != nil {
+				t.Errorf("reserveSend: %v", err)
+				return
+			}
+			results <- block
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	allowed := 0
+	for block := range results {
+		if block == nil {
+			allowed++
+		}
+	}
+	if allowed != 1 {
+		t.Errorf("%d concurrent jobs passed an hourly quota of 1, want 1", allowed)
+	}
+}
+
+func TestReserveSendIsAtomicPerPool(t *testing.T) {
+	db := openTestDB(t)
+	fixtures := []testFixture{seedSchedule(t, db, "UTC"), seedSchedule(t, db, "UTC")}
+
+	poolID := uuid.New()
+	if _, err := db.Exec(`INSERT INTO account_pools (id, name, daily_limit) VALUES ($1, $2, 1)`,
+		poolID, "test "+poolID.String()); err != nil {
+		t.Fatalf("seed pool: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec(`UPDATE schedules SET account_pool_id = NULL WHERE account_pool_id = $1`, poolID)
+		db.Exec(`DELETE FROM account_pools WHERE id = $1`, poolID)
+	})
+	for _, f := range fixtures {
+		if _, err := db.Exec(`UPDATE schedules SET account_pool_id = $2 WHERE id = $1`, f.scheduleID, poolID); err != nil {
+			t.Fatalf("seed pool schedule: %v", err)
+		}
+	}
+
+	d := &Dispatcher{db: db}
+	const workers = 8
+	var wg sync.WaitGroup
+	results := make(chan *quotaBlock, workers)
+	now := time.Now()
+	for i := 0; i < workers; i++ {
+		f := fixtures[i%len(fixtures)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job := &MessageJob{
+				ScheduleID: f.scheduleID,
+				Account:    &models.Account{ID: f.accountID},
+				Channel:    &models.Channel{ID: f.channelID},
+			}
+			block, err := d.reserveSend(job, now)
+			if err != nil {
+				t.Errorf("reserveSend: %v", err)
+				return
+			}
+			results <- block
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	allowed := 0
+	for block := range results {
+		if block == nil {
+			allowed++
+		} else if !block.pool {
+			t.Errorf("blocked by %q, want the pool's daily limit", block.reason)
+		}
+	}
+	if allowed != 1 {
+		t.Errorf("%d concurrent jobs of two schedules passed a pool limit of 1, want 1", allowed)
+	}
+}
@@ -0,0 +1,448 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix carries a hash tag, so on Redis Cluster every key of the queue maps to one
+// slot and scripts and transactions may span them
+const redisKeyPrefix = "timelith:{dispatch}:"
+
+// Redis keys used by RedisQueue. Each job is a hash; the sorted sets index job IDs by the
+// time they next need attention.
+const (
+	redisDueKey     = redisKeyPrefix + "due"     // pending, failed and paused jobs, scored by run_at; held jobs are in no set
+	redisRunningKey = redisKeyPrefix + "running" // claimed jobs, scored by locked_until
+	redisDeadKey    = redisKeyPrefix + "dead"    // dead jobs, scored by finished_at
+	redisJobPrefix  = redisKeyPrefix + "job:"
+)
+
+// claimScript atomically moves a job that is due, or running with an expired visibility
+// timeout, into the running set and locks it for the calling worker. It returns 0 when
+// another worker claimed the job first or the job no longer exists.
+var claimScript = redis.NewScript(`
+local from = KEYS[2]
+local score = redis.call('ZSCORE', from, ARGV[4])
+if not score then
+	from = KEYS[1]
+	score = redis.call('ZSCORE', from, ARGV[4])
+end
+if not score or tonumber(score) > tonumber(ARGV[1]) then
+	return 0
+end
+
+redis.call('ZREM', from, ARGV[4])
+if redis.call('EXISTS', KEYS[3]) == 0 then
+	return 0
+end
+
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[4])
+redis.call('HINCRBY', KEYS[3], 'attempts', 1)
+redis.call('HSET', KEYS[3], 'status', 'running', 'locked_by', ARGV[3], 'locked_until', ARGV[2], 'updated_at', ARGV[1])
+return 1
+`)
+
+// redisClaimTries bounds how many candidates Claim tries when other workers take them first
+const redisClaimTries = 3
+
+// releaseScript moves a held job into the due set
+var releaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'status') ~= 'held' then
+	return 0
+end
+redis.call('HSET', KEYS[2], 'status', 'pending', 'run_at', ARGV[1], 'updated_at', ARGV[2])
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[3])
+return 1
+`)
+
+// requeueScript moves a dead job into the due set with its attempts reset
+var requeueScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], 'status') ~= 'dead' then
+	return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[2])
+redis.call('HSET', KEYS[3], 'status', 'pending', 'attempts', 0, 'run_at', ARGV[1], 'finished_at', '', 'updated_at', ARGV[1])
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// discardScript deletes a dead job
+var discardScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'status') ~= 'dead' then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('DEL', KEYS[2])
+return 1
+`)
+
+// completeScript marks a job as succeeded if the calling worker still holds it; succeeded
+// jobs expire on their own instead of being purged. This and the other transition scripts
+// return 0 when the lease was lost.
+var completeScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'locked_by') ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[2], 'status', 'succeeded', 'locked_by', '', 'locked_until', '', 'last_error', '',
+	'finished_at', ARGV[3], 'updated_at', ARGV[3])
+redis.call('EXPIRE', KEYS[2], ARGV[4])
+return 1
+`)
+
+// retryScript moves a job back into the due set with its attempt counted
+var retryScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], 'locked_by') ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HSET', KEYS[3], 'status', 'failed', 'run_at', ARGV[3], 'locked_by', '', 'locked_until', '',
+	'last_error', ARGV[4], 'updated_at', ARGV[5])
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// parkScript moves a job back into the due set without counting the attempt; attempts never
+// drop below 0
+var parkScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], 'locked_by') ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+local attempts = math.max((tonumber(redis.call('HGET', KEYS[3], 'attempts')) or 0) - 1, 0)
+redis.call('HSET', KEYS[3], 'status', 'paused', 'run_at', ARGV[3], 'attempts', attempts, 'locked_by', '', 'locked_until', '',
+	'last_error', ARGV[4], 'updated_at', ARGV[5])
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// killScript moves a job into the dead set
+var killScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], 'locked_by') ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HSET', KEYS[3], 'status', 'dead', 'locked_by', '', 'locked_until', '',
+	'last_error', ARGV[4], 'finished_at', ARGV[3], 'updated_at', ARGV[3])
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// redisDeadPage is how many dead job IDs ListDead reads at a time
+const redisDeadPage = 100
+
+// RedisQueue stores dispatcher jobs in Redis so several backend replicas can share work.
+// Delayed jobs wait in a sorted set scored by run_at; claiming moves a job into a running
+// set scored by its visibility deadline, from which expired jobs are reclaimed.
+type RedisQueue struct {
+	client *redis.Client
+}
+
+func NewRedisQueue(redisURL string) (*RedisQueue, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisQueue{client: client}, nil
+}
+
+// redisJobData holds the fields of a job set when it is pushed; only Reassign changes the account
+type redisJobData struct {
+	ScheduleID     uuid.UUID  `json:"schedule_id"`
+	AccountID      uuid.UUID  `json:"account_id"`
+	TemplateID     uuid.UUID  `json:"template_id"`
+	ChannelID      uuid.UUID  `json:"channel_id"`
+	Message        string     `json:"message"`
+	IdempotencyKey string     `json:"idempotency_key"`
+	NextJobID      *uuid.UUID `json:"next_job_id,omitempty"`
+}
+
+func (q *RedisQueue) Push(ctx context.Context, job *models.DispatchJob) error {
+	data, err := json.Marshal(redisJobData{
+		ScheduleID:     job.ScheduleID,
+		AccountID:      job.AccountID,
+		TemplateID:     job.TemplateID,
+		ChannelID:      job.ChannelID,
+		Message:        job.Message,
+		IdempotencyKey: job.IdempotencyKey,
+		NextJobID:      job.NextJobID,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	job.ID = uuid.New()
+	if job.Status != "held" {
+		job.Status = "pending"
+	}
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	id := job.ID.String()
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, redisJobPrefix+id,
+			"data", data,
+			"status", job.Status,
+			"attempts", 0,
+			"run_at", millis(job.RunAt),
+			"created_at", millis(now),
+			"updated_at", millis(now))
+		if job.Status == "pending" {
+			pipe.ZAdd(ctx, redisDueKey, redis.Z{Score: float64(millis(job.RunAt)), Member: id})
+		}
+		return nil
+	})
+	return err
+}
+
+func (q *RedisQueue) Release(ctx context.Context, id uuid.UUID, runAt time.Time) error {
+	return releaseScript.Run(ctx, q.client,
+		[]string{redisDueKey, redisJobPrefix + id.String()},
+		millis(runAt), millis(time.Now()), id.String()).Err()
+}
+
+// Claim picks a candidate job outside the script, since a script may only touch keys it is
+// given, and lets claimScript take it if it is still available
+func (q *RedisQueue) Claim(ctx context.Context, workerID string, visibility time.Duration) (*models.DispatchJob, error) {
+	for try := 0; try < redisClaimTries; try++ {
+		now := time.Now()
+		id, err := q.claimCandidate(ctx, now)
+		if err != nil || id == "" {
+			return nil, err
+		}
+
+		key := redisJobPrefix + id
+		claimed, err := claimScript.Run(ctx, q.client,
+			[]string{redisDueKey, redisRunningKey, key},
+			millis(now), millis(now.Add(visibility)), workerID, id).Int()
+		if err != nil {
+			return nil, err
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		fields, err := q.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		return parseRedisJob(id, fields)
+	}
+	return nil, nil
+}
+
+// claimCandidate returns the running job whose visibility timeout expired first, else the
+// oldest due job, or "" when there is none
+func (q *RedisQueue) claimCandidate(ctx context.Context, now time.Time) (string, error) {
+	until := strconv.FormatInt(millis(now), 10)
+	for _, key := range []string{redisRunningKey, redisDueKey} {
+		ids, err := q.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "-inf", Max: until, Count: 1}).Result()
+		if err != nil {
+			return "", err
+		}
+		if len(ids) > 0 {
+			return ids[0], nil
+		}
+	}
+	return "", nil
+}
+
+func (q *RedisQueue) Complete(ctx context.Context, id uuid.UUID, workerID string) error {
+	return q.runFenced(ctx, completeScript,
+		[]string{redisRunningKey, redisJobPrefix + id.String()},
+		id.String(), workerID, millis(time.Now()), int64(dispatchRetention/time.Second))
+}
+
+func (q *RedisQueue) Retry(ctx context.Context, id uuid.UUID, workerID string, runAt time.Time, lastError string) error {
+	return q.runFenced(ctx, retryScript,
+		[]string{redisDueKey, redisRunningKey, redisJobPrefix + id.String()},
+		id.String(), workerID, millis(runAt), lastError, millis(time.Now()))
+}
+
+func (q *RedisQueue) Park(ctx context.Context, id uuid.UUID, workerID string, runAt time.Time, reason string) error {
+	return q.runFenced(ctx, parkScript,
+		[]string{redisDueKey, redisRunningKey, redisJobPrefix + id.String()},
+		id.String(), workerID, millis(runAt), reason, millis(time.Now()))
+}
+
+// Reassign rewrites the job's data; the job is claimed, so no other worker touches it
+func (q *RedisQueue) Reassign(ctx context.Context, id, accountID uuid.UUID) error {
+	key := redisJobPrefix + id.String()
+	raw, err := q.client.HGet(ctx, key, "data").Result()
+	if err != nil {
+		return err
+	}
+
+	var data redisJobData
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return fmt.Errorf("invalid job data for %s: %w", id, err)
+	}
+	data.AccountID = accountID
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return q.client.HSet(ctx, key, "data", encoded, "updated_at", millis(time.Now())).Err()
+}
+
+func (q *RedisQueue) Kill(ctx context.Context, id uuid.UUID, workerID string, lastError string) error {
+	return q.runFenced(ctx, killScript,
+		[]string{redisDeadKey, redisRunningKey, redisJobPrefix + id.String()},
+		id.String(), workerID, millis(time.Now()), lastError)
+}
+
+// runFenced runs a transition script of a claimed job and returns ErrLeaseLost when the
+// worker no longer held it
+func (q *RedisQueue) runFenced(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) error {
+	applied, err := script.Run(ctx, q.client, keys, args...).Int()
+	if err != nil {
+		return err
+	}
+	if applied == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ListDead walks the dead set from the newest entry; filtering by schedule happens on the
+// job hashes, so it reads further than limit when few jobs match
+func (q *RedisQueue) ListDead(ctx context.Context, scheduleID *uuid.UUID, limit int) ([]models.DispatchJob, error) {
+	jobs := []models.DispatchJob{}
+	for start := int64(0); len(jobs) < limit; start += redisDeadPage {
+		ids, err := q.client.ZRevRange(ctx, redisDeadKey, start, start+redisDeadPage-1).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			fields, err := q.client.HGetAll(ctx, redisJobPrefix+id).Result()
+			if err != nil {
+				return nil, err
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			job, err := parseRedisJob(id, fields)
+			if err != nil {
+				return nil, err
+			}
+			if scheduleID != nil && job.ScheduleID != *scheduleID {
+				continue
+			}
+			jobs = append(jobs, *job)
+			if len(jobs) == limit {
+				break
+			}
+		}
+		if len(ids) < redisDeadPage {
+			break
+		}
+	}
+	return jobs, nil
+}
+
+func (q *RedisQueue) Requeue(ctx context.Context, id uuid.UUID) (*models.DispatchJob, error) {
+	key := redisJobPrefix + id.String()
+	moved, err := requeueScript.Run(ctx, q.client,
+		[]string{redisDueKey, redisDeadKey, key},
+		millis(time.Now()), id.String()).Int()
+	if err != nil {
+		return nil, err
+	}
+	if moved == 0 {
+		return nil, nil
+	}
+
+	fields, err := q.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseRedisJob(id.String(), fields)
+}
+
+func (q *RedisQueue) Discard(ctx context.Context, id uuid.UUID) (bool, error) {
+	deleted, err := discardScript.Run(ctx, q.client,
+		[]string{redisDeadKey, redisJobPrefix + id.String()}, id.String()).Int()
+	return deleted == 1, err
+}
+
+// Purge is a no-op; succeeded jobs carry a TTL of dispatchRetention
+func (q *RedisQueue) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (q *RedisQueue) Close() error {
+	return q.client.Close()
+}
+
+// parseRedisJob rebuilds a job from its hash fields
+func parseRedisJob(id string, fields map[string]string) (*models.DispatchJob, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job id %q: %w", id, err)
+	}
+
+	var data redisJobData
+	if err := json.Unmarshal([]byte(fields["data"]), &data); err != nil {
+		return nil, fmt.Errorf("invalid job data for %s: %w", id, err)
+	}
+
+	attempts, _ := strconv.Atoi(fields["attempts"])
+	job := &models.DispatchJob{
+		ID:             jobID,
+		ScheduleID:     data.ScheduleID,
+		AccountID:      data.AccountID,
+		TemplateID:     data.TemplateID,
+		ChannelID:      data.ChannelID,
+		Message:        data.Message,
+		IdempotencyKey: data.IdempotencyKey,
+		NextJobID:      data.NextJobID,
+		Status:         fields["status"],
+		Attempts:       attempts,
+		RunAt:          fromMillis(fields["run_at"]),
+		CreatedAt:      fromMillis(fields["created_at"]),
+		UpdatedAt:      fromMillis(fields["updated_at"]),
+	}
+	if v := fields["locked_by"]; v != "" {
+		job.LockedBy = models.NewNullString(v)
+	}
+	if v := fields["locked_until"]; v != "" {
+		job.LockedUntil = models.NewNullTime(fromMillis(v))
+	}
+	if v := fields["last_error"]; v != "" {
+		job.LastError = models.NewNullString(v)
+	}
+	if v := fields["finished_at"]; v != "" {
+		job.FinishedAt = models.NewNullTime(fromMillis(v))
+	}
+	return job, nil
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+func fromMillis(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,209 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// openTestRedisQueue connects to the empty Redis database in REDIS_URL and removes the
+// queue's keys when the test ends, or skips the test when none is configured
+func openTestRedisQueue(t *testing.T) *RedisQueue {
+	t.Helper()
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		t.Skip("REDIS_URL is not set")
+	}
+	q, err := NewRedisQueue(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	ctx := context.Background()
+	if size, err := q.client.DBSize(ctx).Result(); err != nil || size > 0 {
+		q.Close()
+		t.Skipf("REDIS_URL must point at an empty database (size %d, err %v)", size, err)
+	}
+	t.Cleanup(func() {
+		keys, err := q.client.Keys(ctx, redisKeyPrefix+"*").Result()
+		if err == nil && len(keys) > 0 {
+			q.client.Del(ctx, keys...)
+		}
+		q.Close()
+	})
+	return q
+}
+
+func testDispatchJob(status string, runAt time.Time) *models.DispatchJob {
+	return &models.DispatchJob{
+		ScheduleID:     uuid.New(),
+		AccountID:      uuid.New(),
+		TemplateID:     uuid.New(),
+		ChannelID:      uuid.New(),
+		Message:        "hello",
+		IdempotencyKey: uuid.NewString(),
+		Status:         status,
+		RunAt:          runAt,
+	}
+}
+
+func TestRedisQueueClaim(t *testing.T) {
+	q := openTestRedisQueue(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	due := testDispatchJob("pending", now.Add(-time.Second))
+	later := testDispatchJob("pending", now.Add(time.Hour))
+	held := testDispatchJob("held", now)
+	for _, job := range []*models.DispatchJob{due, later, held} {
+		if err := q.Push(ctx, job); err != nil {
+			t.Fatalf("push: %v", err)
+		}
+	}
+
+	claimed, err := q.Claim(ctx, "worker-1", time.Minute)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if claimed == nil || claimed.ID != due.ID {
+		t.Fatalf("claimed %v, want the due job %s", claimed, due.ID)
+	}
+	if claimed.Status != "running" || claimed.Attempts != 1 || claimed.LockedBy.String != "worker-1" {
+		t.Errorf("claimed job not locked: status %s, attempts %d, locked by %q",
+			claimed.Status, claimed.Attempts, claimed.LockedBy.String)
+	}
+	if claimed.IdempotencyKey != due.IdempotencyKey || claimed.Message != due.Message {
+		t.Errorf("claimed job lost its data: %+v", claimed)
+	}
+
+	if next, err := q.Claim(ctx, "worker-2", time.Minute); err != nil || next != nil {
+		t.Fatalf("claimed %v, %v while no other job is due", next, err)
+	}
+
+	if err := q.Release(ctx, held.ID, now); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	released, err := q.Claim(ctx, "worker-2", time.Minute)
+	if err != nil || released == nil || released.ID != held.ID {
+		t.Fatalf("claimed %v, %v, want the released job %s", released, err, held.ID)
+	}
+}
+
+func TestRedisQueueReclaimsExpiredJob(t *testing.T) {
+	q := openTestRedisQueue(t)
+	ctx := context.Background()
+
+	job := testDispatchJob("pending", time.Now().Add(-time.Second))
+	if err := q.Push(ctx, job); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if claimed, err := q.Claim(ctx, "worker-1", -time.Second); err != nil || claimed == nil {
+		t.Fatalf("claim: %v, %v", claimed, err)
+	}
+
+	reclaimed, err := q.Claim(ctx, "worker-2", time.Minute)
+	if err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	if reclaimed == nil || reclaimed.ID != job.ID || reclaimed.Attempts != 2 || reclaimed.LockedBy.String != "worker-2" {
+		t.Fatalf("job with an expired visibility timeout was not reclaimed: %+v", reclaimed)
+	}
+}
+
+func TestRedisQueueFencesTransitionsOnLease(t *testing.T) {
+	q := openTestRedisQueue(t)
+	ctx := context.Background()
+
+	job := testDispatchJob("pending", time.Now().Add(-time.Second))
+	if err := q.Push(ctx, job); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if claimed, err := q.Claim(ctx, "worker-1", -time.Second); err != nil || claimed == nil {
+		t.Fatalf("claim: %v, %v", claimed, err)
+	}
+	if reclaimed, err := q.Claim(ctx, "worker-2", time.Minute); err != nil || reclaimed == nil {
+		t.Fatalf("reclaim: %v, %v", reclaimed, err)
+	}
+
+	if err := q.Complete(ctx, job.ID, "worker-1"); err != ErrLeaseLost {
+		t.Errorf("complete by the worker that lost the lease: got %v, want ErrLeaseLost", err)
+	}
+	if err := q.Kill(ctx, job.ID, "worker-1", "boom"); err != ErrLeaseLost {
+		t.Errorf("kill by the worker that lost the lease: got %v, want ErrLeaseLost", err)
+	}
+	if err := q.Complete(ctx, job.ID, "worker-2"); err != nil {
+		t.Fatalf("complete by the lease holder: %v", err)
+	}
+	if err := q.Retry(ctx, job.ID, "worker-2", time.Now(), "boom"); err != ErrLeaseLost {
+		t.Errorf("retry of a completed job: got %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestRedisQueueParkKeepsAttemptsNonNegative(t *testing.T) {
+	q := openTestRedisQueue(t)
+	ctx := context.Background()
+
+	job := testDispatchJob("pending", time.Now().Add(-time.Second))
+	if err := q.Push(ctx, job); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if _, err := q.Claim(ctx, "worker-1", time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	// A job whose attempt was already given back, as Postgres would clamp it
+	if err := q.client.HSet(ctx, redisJobPrefix+job.ID.String(), "attempts", 0).Err(); err != nil {
+		t.Fatalf("reset attempts: %v", err)
+	}
+	if err := q.Park(ctx, job.ID, "worker-1", time.Now().Add(-time.Second), "paused"); err != nil {
+		t.Fatalf("park: %v", err)
+	}
+
+	claimed, err := q.Claim(ctx, "worker-1", time.Minute)
+	if err != nil || claimed == nil {
+		t.Fatalf("claim parked job: %v, %v", claimed, err)
+	}
+	if claimed.Attempts != 1 {
+		t.Errorf("attempts after park and claim = %d, want 1", claimed.Attempts)
+	}
+}
+
+func TestRedisQueueDeadLetter(t *testing.T) {
+	q := openTestRedisQueue(t)
+	ctx := context.Background()
+
+	job := testDispatchJob("pending", time.Now().Add(-time.Second))
+	if err := q.Push(ctx, job); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if _, err := q.Claim(ctx, "worker-1", time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if err := q.Kill(ctx, job.ID, "worker-1", "boom"); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+
+	dead, err := q.ListDead(ctx, &job.ScheduleID, 10)
+	if err != nil || len(dead) != 1 || dead[0].LastError.String != "boom" {
+		t.Fatalf("dead jobs %+v, %v", dead, err)
+	}
+
+	requeued, err := q.Requeue(ctx, job.ID)
+	if err != nil || requeued == nil || requeued.Status != "pending" || requeued.Attempts != 0 {
+		t.Fatalf("requeue: %+v, %v", requeued, err)
+	}
+	if claimed, err := q.Claim(ctx, "worker-1", time.Minute); err != nil || claimed == nil || claimed.ID != job.ID {
+		t.Fatalf("requeued job was not claimable: %v, %v", claimed, err)
+	}
+	if err := q.Kill(ctx, job.ID, "worker-1", "boom again"); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+	if discarded, err := q.Discard(ctx, job.ID); err != nil || !discarded {
+		t.Fatalf("discard: %v, %v", discarded, err)
+	}
+	if dead, err := q.ListDead(ctx, nil, 10); err != nil || len(dead) != 0 {
+		t.Fatalf("dead jobs after discard %+v, %v", dead, err)
+	}
+}
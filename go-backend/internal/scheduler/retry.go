@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/GezzyDax/timelith/go-backend/internal/telegram"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Retry policy used when a schedule leaves its own settings unset
+const (
+	defaultRetryMaxAttempts = 4
+	defaultRetryBaseDelay   = 2 * time.Second
+	defaultRetryMaxDelay    = 5 * time.Minute
+)
+
+// ErrNotDead is returned for dead-letter operations on jobs that are not dead
+var ErrNotDead = errors.New("job is not in the dead-letter queue")
+
+// retryPolicy decides how often and how late a failed delivery is attempted again
+type retryPolicy struct {
+	maxAttempts int // Includes the first attempt
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func scheduleRetryPolicy(schedule *models.Schedule) retryPolicy {
+	policy := retryPolicy{
+		maxAttempts: defaultRetryMaxAttempts,
+		baseDelay:   defaultRetryBaseDelay,
+		maxDelay:    defaultRetryMaxDelay,
+	}
+	if schedule == nil {
+		return policy
+	}
+	if schedule.RetryMaxAttempts > 0 {
+		policy.maxAttempts = schedule.RetryMaxAttempts
+	}
+	if schedule.RetryBaseDelaySeconds > 0 {
+		policy.baseDelay = time.Duration(schedule.RetryBaseDelaySeconds) * time.Second
+	}
+	if schedule.RetryMaxDelaySeconds > 0 {
+		policy.maxDelay = time.Duration(schedule.RetryMaxDelaySeconds) * time.Second
+	}
+	if policy.maxDelay < policy.baseDelay {
+		policy.maxDelay = policy.baseDelay
+	}
+	return policy
+}
+
+// retries reports whether a job that failed on the given attempt is tried again
+func (p retryPolicy) retries(attempt int) bool {
+	return attempt < p.maxAttempts
+}
+
+// delay is the backoff before the retry following the given attempt: baseDelay doubled
+// for every earlier retry and capped at maxDelay, of which a random half is jitter so
+// deliveries that failed together do not retry together. A flood wait from Telegram
+// takes precedence, even over maxDelay, since sending earlier only fails again.
+func (p retryPolicy) delay(attempt int, cause error) time.Duration {
+	backoff := p.maxDelay
+	if shift := attempt - 1; shift < 32 {
+		if d := p.baseDelay << shift; d > 0 && d < p.maxDelay {
+			backoff = d
+		}
+	}
+	half := backoff / 2
+	backoff = half + time.Duration(rand.Int63n(int64(half)+1))
+
+	if wait, ok := telegram.FloodWait(cause); ok && wait > backoff {
+		return wait
+	}
+	return backoff
+}
+
+// DeadLetter is a job that exhausted its attempts, with the delivery it was making
+type DeadLetter struct {
+	Job      models.DispatchJob `json:"job"`
+	Delivery *models.Delivery   `json:"delivery,omitempty"`
+}
+
+// DeadLetters lists dead jobs, most recently failed first, optionally of one schedule only
+func (s *Scheduler) DeadLetters(ctx context.Context, scheduleID *uuid.UUID, limit int) ([]DeadLetter, error) {
+	jobs, err := s.dispatcher.queue.ListDead(ctx, scheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]DeadLetter, 0, len(jobs))
+	for _, job := range jobs {
+		letter := DeadLetter{Job: job}
+		if job.IdempotencyKey != "" {
+			if delivery, err := s.db.GetDeliveryByKey(job.IdempotencyKey); err == nil {
+				letter.Delivery = delivery
+			}
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+// RetryDeadLetter queues a dead job again with a fresh set of attempts. Its delivery and
+// run are reopened so the run is not reported finished while the job is pending.
+func (s *Scheduler) RetryDeadLetter(ctx context.Context, id uuid.UUID) error {
+	if s.stopping.Load() {
+		return ErrShuttingDown
+	}
+	job, err := s.dispatcher.queue.Requeue(ctx, id)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return ErrNotDead
+	}
+
+	if job.IdempotencyKey != "" {
+		delivery, err := s.db.ReopenDelivery(job.IdempotencyKey)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Log.Error("Failed to reopen delivery",
+				zap.String("idempotency_key", job.IdempotencyKey),
+				zap.Error(err))
+		}
+		if delivery != nil {
+			s.dispatcher.refreshRun(delivery)
+		}
+	}
+
+	logger.Log.Info("Dead-letter job queued again",
+		zap.String("job_id", id.String()),
+		zap.String("schedule_id", job.ScheduleID.String()))
+
+	select {
+	case s.dispatcher.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// DiscardDeadLetter deletes a dead job; its delivery stays failed
+func (s *Scheduler) DiscardDeadLetter(ctx context.Context, id uuid.UUID) error {
+	discarded, err := s.dispatcher.queue.Discard(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !discarded {
+		return ErrNotDead
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/database"
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/GezzyDax/timelith/go-backend/internal/telegram"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DefaultRotationStrategy is used by load-balanced schedules that do not name a strategy
+const DefaultRotationStrategy = "least_used"
+
+// membershipTTL is how long an account that failed to post in a channel is kept away from
+// it before rotation tries it there again
+const membershipTTL = 24 * time.Hour
+
+// rotationStrategy picks the account for a delivery to channelID among the eligible
+// candidates, of which there is at least one
+type rotationStrategy func(r *accountRotation, eligible []*rotationCandidate, channelID uuid.UUID) *rotationCandidate
+
+// rotationStrategies are the strategies a load-balanced schedule can choose from, by name
+var rotationStrategies = map[string]rotationStrategy{
+	"least_used":          pickLeastUsed,
+	"least_recently_used": pickLeastRecentlyUsed,
+	"round_robin":         pickRoundRobin,
+	"weighted":            pickWeighted,
+	"sticky":              pickSticky,
+	"random":              pickRandom,
+}
+
+// ValidRotationStrategy reports whether name is a known rotation strategy
+func ValidRotationStrategy(name string) bool {
+	_, ok := rotationStrategies[name]
+	return ok
+}
+
+// rotationCandidate is an account of the pool along with what this run assigned to it
+type rotationCandidate struct {
+	account  *models.Account
+	usage    models.AccountUsage
+	index    int // Position in the pool
+	picks    int // Deliveries assigned during this run
+	newChats int // Deliveries assigned during this run to chats it never posted in
+	lastPick int // Sequence number of its latest assignment during this run, 0 if none
+}
+
+// rotates reports whether the schedule picks an account per delivery instead of always
+// sending through its own account
+func rotates(schedule *models.Schedule) bool {
+	return schedule.AccountPoolID != nil || schedule.LoadBalance
+}
+
+// accountRotation assigns accounts to the deliveries of one run. Schedules without a pool
+// or load balancing always get their own account. Otherwise candidates are the accounts of
+// the schedule's pool, or every account under plain load balancing, that are active, not
+// paused and not restricted by Telegram; per channel, accounts that recently failed to
+// post there are left out too. Membership is not checked up front: an account is only
+// known not to be able to post in a channel once a send there failed or it was marked so.
+// Accounts within their send quotas are preferred.
+type accountRotation struct {
+	fixed      *models.Account
+	strategy   rotationStrategy
+	candidates []*rotationCandidate
+	nonMembers map[uuid.UUID]map[uuid.UUID]bool // channel -> accounts that could not post there
+	members    map[uuid.UUID]map[uuid.UUID]bool // channel -> accounts that posted there
+	senders    map[uuid.UUID]uuid.UUID          // channel -> account that last delivered there
+	last       *uuid.UUID                       // Account of the previous delivery
+	seq        int
+}
+
+// newRotation loads the accounts a run of the schedule can use
+func newRotation(db *database.DB, schedule *models.Schedule, pauses *pauseSet) (*accountRotation, error) {
+	if !rotates(schedule) {
+		if schedule.AccountID == nil {
+			return nil, fmt.Errorf("schedule has neither an account nor an account pool")
+		}
+		account, err := db.GetAccount(*schedule.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("account not found: %w", err)
+		}
+		return &accountRotation{fixed: account}, nil
+	}
+
+	name := schedule.RotationStrategy
+	if name == "" {
+		name = DefaultRotationStrategy
+	}
+	strategy, ok := rotationStrategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rotation strategy: %s", name)
+	}
+	r := &accountRotation{strategy: strategy}
+
+	accounts, err := db.ListAccounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load accounts: %w", err)
+	}
+	position := make(map[uuid.UUID]int)
+	if schedule.AccountPoolID != nil {
+		members, err := db.ListAccountPoolMembers(*schedule.AccountPoolID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load account pool: %w", err)
+		}
+		for i, id := range members {
+			position[id] = i
+		}
+	} else {
+		// Without a pool every account takes part, oldest first
+		sort.Slice(accounts, func(i, j int) bool { return accounts[i].CreatedAt.Before(accounts[j].CreatedAt) })
+		for i := range accounts {
+			position[accounts[i].ID] = i
+		}
+	}
+
+	now := time.Now()
+	for i := range accounts {
+		account := &accounts[i]
+		index, inPool := position[account.ID]
+		switch {
+		case !inPool, account.Status != "active", pauses.accounts[account.ID] != nil:
+			continue
+		case account.RestrictedUntil.Valid && account.RestrictedUntil.Time.After(now):
+			continue
+		}
+		r.candidates = append(r.candidates, &rotationCandidate{account: account, index: index})
+	}
+	if len(r.candidates) == 0 {
+		return nil, fmt.Errorf("no eligible account: every account of the pool is inactive, paused or restricted")
+	}
+	sort.Slice(r.candidates, func(i, j int) bool { return r.candidates[i].index < r.candidates[j].index })
+
+	nonMembers, err := db.ListNonMembers(now.Add(-membershipTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load channel memberships: %w", err)
+	}
+	r.nonMembers = channelAccounts(nonMembers)
+	members, err := db.ListMembers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load channel memberships: %w", err)
+	}
+	r.members = channelAccounts(members)
+
+	usage, err := db.ListAccountUsage(now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load account usage: %w", err)
+	}
+	byAccount := make(map[uuid.UUID]models.AccountUsage, len(usage))
+	for _, u := range usage {
+		byAccount[u.AccountID] = u
+	}
+	for _, c := range r.candidates {
+		c.usage = byAccount[c.account.ID]
+	}
+
+	if r.last, err = db.GetLastDeliveryAccount(schedule.ID); err != nil {
+		return nil, fmt.Errorf("failed to load the last delivery: %w", err)
+	}
+	if name == "sticky" {
+		if r.senders, err = db.GetChannelSenders(schedule.ID); err != nil {
+			return nil, fmt.Errorf("failed to load channel senders: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// channelAccounts indexes membership records by channel and account
+func channelAccounts(records []models.AccountChannel) map[uuid.UUID]map[uuid.UUID]bool {
+	index := make(map[uuid.UUID]map[uuid.UUID]bool)
+	for _, record := range records {
+		if index[record.ChannelID] == nil {
+			index[record.ChannelID] = make(map[uuid.UUID]bool)
+		}
+		index[record.ChannelID][record.AccountID] = true
+	}
+	return index
+}
+
+// pick assigns the account that delivers to the channel. When every eligible account is
+// over one of its quotas, one is assigned anyway and the dispatcher defers the send.
+func (r *accountRotation) pick(channelID uuid.UUID) (*models.Account, error) {
+	if r.fixed != nil {
+		return r.fixed, nil
+	}
+
+	eligible := r.eligible(channelID, uuid.Nil)
+	if len(eligible) == 0 {
+		return nil, fmt.Errorf("no eligible account: none of the pool's active accounts can post in the channel")
+	}
+	if within := r.withinQuota(eligible, channelID); len(within) > 0 {
+		eligible = within
+	}
+	return r.assign(r.strategy(r, eligible, channelID), channelID), nil
+}
+
+// pickWithin assigns another account than exclude that can post in the channel within its
+// quotas, or returns nil when there is none
+func (r *accountRotation) pickWithin(channelID, exclude uuid.UUID) *models.Account {
+	if r.fixed != nil {
+		return nil
+	}
+	eligible := r.withinQuota(r.eligible(channelID, exclude), channelID)
+	if len(eligible) == 0 {
+		return nil
+	}
+	return r.assign(r.strategy(r, eligible, channelID), channelID)
+}
+
+// eligible lists the candidates other than exclude not known to be unable to post in the channel
+func (r *accountRotation) eligible(channelID, exclude uuid.UUID) []*rotationCandidate {
+	eligible := make([]*rotationCandidate, 0, len(r.candidates))
+	for _, c := range r.candidates {
+		if c.account.ID != exclude && !r.nonMembers[channelID][c.account.ID] {
+			eligible = append(eligible, c)
+		}
+	}
+	return eligible
+}
+
+// withinQuota keeps the candidates that can send one more message to the channel, counting
+// what this run already assigned to them
+func (r *accountRotation) withinQuota(candidates []*rotationCandidate, channelID uuid.UUID) []*rotationCandidate {
+	now := time.Now()
+	within := make([]*rotationCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		load := quotaLoad{
+			usage:    c.usage,
+			planned:  c.picks,
+			newChats: c.newChats,
+			newChat:  !r.members[channelID][c.account.ID],
+		}
+		if checkQuota(c.account, load, now) == nil {
+			within = append(within, c)
+		}
+	}
+	return within
+}
+
+func (r *accountRotation) assign(c *rotationCandidate, channelID uuid.UUID) *models.Account {
+	r.seq++
+	c.picks++
+	if !r.members[channelID][c.account.ID] {
+		c.newChats++
+	}
+	c.lastPick = r.seq
+	id := c.account.ID
+	r.last = &id
+	if r.senders != nil {
+		r.senders[channelID] = id
+	}
+	return c.account
+}
+
+// pickLeastUsed prefers the account with the fewest messages sent, counting this run's
+// assignments, then the one idle the longest
+func pickLeastUsed(r *accountRotation, eligible []*rotationCandidate, channelID uuid.UUID) *rotationCandidate {
+	best := eligible[0]
+	for _, c := range eligible[1:] {
+		used, bestUsed := c.account.MessagesSent+c.picks, best.account.MessagesSent+best.picks
+		if used < bestUsed || used == bestUsed && usedBefore(c, best) {
+			best = c
+		}
+	}
+	return best
+}
+
+// pickLeastRecentlyUsed prefers the account idle the longest
+func pickLeastRecentlyUsed(r *accountRotation, eligible []*rotationCandidate, channelID uuid.UUID) *rotationCandidate {
+	best := eligible[0]
+	for _, c := range eligible[1:] {
+		if usedBefore(c, best) {
+			best = c
+		}
+	}
+	return best
+}
+
+// usedBefore reports whether a was last used before b. Assignments during this run count
+// as the most recent use; accounts never used come first.
+func usedBefore(a, b *rotationCandidate) bool {
+	if a.lastPick > 0 || b.lastPick > 0 {
+		return a.lastPick < b.lastPick
+	}
+	if !a.account.LastUsedAt.Valid || !b.account.LastUsedAt.Valid {
+		return !a.account.LastUsedAt.Valid && b.account.LastUsedAt.Valid
+	}
+	return a.account.LastUsedAt.Time.Before(b.account.LastUsedAt.Time)
+}
+
+// pickRoundRobin takes the eligible account that follows the previous delivery's account
+// in pool order, wrapping around at the end
+func pickRoundRobin(r *accountRotation, eligible []*rotationCandidate, channelID uuid.UUID) *rotationCandidate {
+	lastIndex := -1
+	if r.last != nil {
+		for _, c := range r.candidates {
+			if c.account.ID == *r.last {
+				lastIndex = c.index
+				break
+			}
+		}
+	}
+	for _, c := range eligible {
+		if c.index > lastIndex {
+			return c
+		}
+	}
+	return eligible[0]
+}
+
+// pickWeighted picks at random in proportion to the accounts' rotation weights. Accounts
+// with weight 0 are only used when no other account is eligible.
+func pickWeighted(r *accountRotation, eligible []*rotationCandidate, channelID uuid.UUID) *rotationCandidate {
+	total := 0
+	for _, c := range eligible {
+		total += max(c.account.RotationWeight, 0)
+	}
+	if total == 0 {
+		return pickRandom(r, eligible, channelID)
+	}
+
+	n := rand.Intn(total)
+	for _, c := range eligible {
+		n -= max(c.account.RotationWeight, 0)
+		if n < 0 {
+			return c
+		}
+	}
+	return eligible[len(eligible)-1]
+}
+
+// pickSticky keeps the account that last delivered to the channel while it stays eligible
+// and otherwise falls back to the least used account, which then sticks
+func pickSticky(r *accountRotation, eligible []*rotationCandidate, channelID uuid.UUID) *rotationCandidate {
+	if id, ok := r.senders[channelID]; ok {
+		for _, c := range eligible {
+			if c.account.ID == id {
+				return c
+			}
+		}
+	}
+	return pickLeastUsed(r, eligible, channelID)
+}
+
+func pickRandom(r *accountRotation, eligible []*rotationCandidate, channelID uuid.UUID) *rotationCandidate {
+	return eligible[rand.Intn(len(eligible))]
+}
+
+// learnFromSend records what a send told about the account: whether it can post in the
+// channel, and whether Telegram restricted it, so rotation steers around it
+func (d *Dispatcher) learnFromSend(job *MessageJob, sendErr error) {
+	var err error
+	switch {
+	case sendErr == nil:
+		err = d.db.SetAccountChannel(job.Account.ID, job.Channel.ID, true, "")
+	case telegram.IsNotMember(sendErr):
+		err = d.db.SetAccountChannel(job.Account.ID, job.Channel.ID, false, telegram.ErrorType(sendErr))
+	default:
+		if period, ok := telegram.Restriction(sendErr); ok {
+			logger.Log.Warn("Telegram restricted account",
+				zap.String("account", job.Account.Phone),
+				zap.Duration("period", period),
+				zap.Error(sendErr))
+			err = d.db.RestrictAccount(job.Account.ID, time.Now().Add(period), telegram.ErrorType(sendErr))
+		}
+	}
+	if err != nil {
+		logger.Log.Error("Failed to record account state",
+			zap.String("account_id", job.Account.ID.String()),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,369 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RunOptions adjust a run triggered through the API
+type RunOptions struct {
+	ChannelIDs []uuid.UUID // Limits the run to these channels of the schedule; all when empty
+	DryRun     bool        // Resolve everything the run needs without sending
+}
+
+// RunReport describes what a triggered run sent or, for a dry run, would send
+type RunReport struct {
+	ScheduleID uuid.UUID   `json:"schedule_id"`
+	RunID      *uuid.UUID  `json:"run_id,omitempty"`
+	RunAt      time.Time   `json:"run_at"`
+	DryRun     bool        `json:"dry_run"`
+	AccountID  *uuid.UUID  `json:"account_id,omitempty"` // Set unless accounts rotate per channel
+	Account    string      `json:"account,omitempty"`
+	TemplateID uuid.UUID   `json:"template_id"`
+	Template   string      `json:"template"`
+	Targets    []RunTarget `json:"targets"`
+	Failed     int         `json:"failed"`
+}
+
+// RunTarget is the delivery of a run to one channel
+type RunTarget struct {
+	ChannelID  uuid.UUID  `json:"channel_id"`
+	Channel    string     `json:"channel,omitempty"`
+	ChatID     string     `json:"chat_id,omitempty"`
+	AccountID  *uuid.UUID `json:"account_id,omitempty"` // Account chosen to send it
+	Account    string     `json:"account,omitempty"`
+	Message    string     `json:"message,omitempty"`
+	Unresolved []string   `json:"unresolved_variables,omitempty"`
+	DeferredTo *time.Time `json:"deferred_to,omitempty"` // Held back by a blackout window
+	Status     string     `json:"status"`                // ok, queued, skipped, failed
+	Error      string     `json:"error,omitempty"`
+
+	channel *models.Channel
+	account *models.Account
+}
+
+// runPlan is everything a run of a schedule needs before messages are enqueued
+type runPlan struct {
+	account  *models.Account // The schedule's account unless accounts rotate per channel
+	template *models.Template
+	targets  []RunTarget
+}
+
+// planRun resolves the template and channels of a run, assigns the account of each
+// delivery and renders the message for each channel. Channels that cannot be loaded or
+// that no account can serve are returned as failed targets, channels in a blackout window
+// as skipped or deferred targets and channels beyond the pool's daily limit as skipped.
+func (s *Scheduler) planRun(schedule *models.Schedule, runAt time.Time, channelIDs []uuid.UUID, blackouts *blackoutSet, pauses *pauseSet) (*runPlan, error) {
+	rotation, err := newRotation(s.db, schedule, pauses)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := s.loadPoolBudget(schedule, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := s.db.GetTemplate(schedule.TemplateID)
+	if err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
+	}
+
+	var channelUUIDs []uuid.UUID
+	for _, cidStr := range schedule.ChannelIDs {
+		if cid, err := uuid.Parse(cidStr); err == nil {
+			channelUUIDs = append(channelUUIDs, cid)
+		}
+	}
+
+	if len(channelIDs) > 0 {
+		configured := make(map[uuid.UUID]bool, len(channelUUIDs))
+		for _, cid := range channelUUIDs {
+			configured[cid] = true
+		}
+		for _, cid := range channelIDs {
+			if !configured[cid] {
+				return nil, fmt.Errorf("channel %s is not part of the schedule", cid)
+			}
+		}
+		channelUUIDs = channelIDs
+	}
+
+	if len(channelUUIDs) == 0 {
+		return nil, fmt.Errorf("no channels configured")
+	}
+	if schedule.ShuffleChannels {
+		channelUUIDs = shuffled(channelUUIDs)
+	}
+
+	plan := &runPlan{account: rotation.fixed, template: template}
+	for _, channelID := range channelUUIDs {
+		target := RunTarget{ChannelID: channelID}
+
+		channel, err := s.db.GetChannel(channelID)
+		if err != nil {
+			target.Status = "failed"
+			target.Error = fmt.Sprintf("channel not found: %v", err)
+			plan.targets = append(plan.targets, target)
+			continue
+		}
+
+		target.channel = channel
+		target.Channel = channel.Name
+		target.ChatID = channel.ChatID
+		target.Message, target.Unresolved = renderMessage(template.Content, messageVariables(schedule, channel, runAt))
+		if pause := pauses.forChannel(channelID); pause != nil {
+			target.Status = "skipped"
+			target.Error = describePause(pause)
+			plan.targets = append(plan.targets, target)
+			continue
+		}
+
+		account, err := rotation.pick(channelID)
+		if err != nil {
+			target.Status = "failed"
+			target.Error = err.Error()
+			plan.targets = append(plan.targets, target)
+			continue
+		}
+		target.account = account
+		target.AccountID = &account.ID
+		target.Account = account.Phone
+
+		if pause := pauses.accounts[account.ID]; pause != nil {
+			target.Status = "skipped"
+			target.Error = describePause(pause)
+		} else if b := blackouts.forChannel(channelID, runAt); b != nil {
+			if b.skip {
+				target.Status = "skipped"
+				target.Error = fmt.Sprintf("blackout window %q", b.window.Name)
+			} else {
+				until := b.until
+				target.DeferredTo = &until
+			}
+		}
+		if target.Status == "" && !budget.take() {
+			target.Status = "skipped"
+			target.Error = budget.describe()
+		}
+		plan.targets = append(plan.targets, target)
+	}
+	return plan, nil
+}
+
+// enqueuePlan records a schedule run, queues a message for every resolved target of the
+// plan and records the outcome on the target. It returns the run ID, or uuid.Nil when the
+// run could not be recorded.
+func (s *Scheduler) enqueuePlan(schedule *models.Schedule, runAt time.Time, trigger string, plan *runPlan, idempotencyKey func(channelID uuid.UUID) string) uuid.UUID {
+	run := &models.ScheduleRun{
+		ScheduleID: schedule.ID,
+		Trigger:    trigger,
+		RunAt:      runAt,
+		Total:      len(plan.targets),
+	}
+	if err := s.db.CreateScheduleRun(run); err != nil {
+		// Deliveries still go out, they are just not grouped into a run
+		logger.Log.Error("Failed to record schedule run",
+			zap.String("schedule_id", schedule.ID.String()),
+			zap.Error(err))
+		run.ID = uuid.Nil
+	}
+
+	// Jobs go out one after another in plan order, paced by the dispatcher. Channels
+	// deferred by a blackout window form their own chain starting when it ends.
+	type chain struct {
+		jobs    []*MessageJob
+		targets []*RunTarget
+	}
+	chains := make(map[time.Time]*chain)
+	var starts []time.Time
+
+	var queued, skipped int
+	for i := range plan.targets {
+		target := &plan.targets[i]
+		if target.Status == "failed" {
+			logger.Log.Error("Channel cannot be delivered to",
+				zap.String("channel_id", target.ChannelID.String()),
+				zap.String("error", target.Error))
+			continue
+		}
+		if target.Status == "skipped" {
+			logger.Log.Info("Channel skipped",
+				zap.String("schedule_id", schedule.ID.String()),
+				zap.String("channel_id", target.ChannelID.String()),
+				zap.String("reason", target.Error))
+			skipped++
+			continue
+		}
+
+		job := &MessageJob{
+			ScheduleID: schedule.ID,
+			RunID:      run.ID,
+			RunAt:      runAt,
+			Account:    target.account,
+			Template:   plan.template,
+			Channel:    target.channel,
+			Message:    target.Message,
+		}
+		if idempotencyKey != nil {
+			job.IdempotencyKey = idempotencyKey(target.ChannelID)
+		}
+
+		var startAt time.Time
+		if target.DeferredTo != nil {
+			startAt = *target.DeferredTo
+		}
+		c, ok := chains[startAt]
+		if !ok {
+			c = &chain{}
+			chains[startAt] = c
+			starts = append(starts, startAt)
+		}
+		c.jobs = append(c.jobs, job)
+		c.targets = append(c.targets, target)
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	for _, startAt := range starts {
+		c := chains[startAt]
+		for i, err := range s.dispatcher.EnqueueRun(c.jobs, startAt) {
+			target := c.targets[i]
+			switch {
+			case err != nil:
+				logger.Log.Error("Failed to enqueue message job",
+					zap.String("schedule_id", schedule.ID.String()),
+					zap.String("channel_id", target.ChannelID.String()),
+					zap.Error(err))
+				s.logJobExecution(schedule.ID, "failed", "", fmt.Sprintf("Failed to queue message for %s: %v", target.Channel, err))
+				target.Status = "failed"
+				target.Error = err.Error()
+			case c.jobs[i].ID == uuid.Nil:
+				target.Status = "skipped"
+				target.Error = "already enqueued by an earlier run"
+				skipped++
+			default:
+				target.Status = "queued"
+				queued++
+			}
+		}
+	}
+
+	if run.ID != uuid.Nil {
+		if err := s.db.SetScheduleRunQueued(run.ID, queued, skipped); err != nil {
+			logger.Log.Error("Failed to update schedule run",
+				zap.String("run_id", run.ID.String()),
+				zap.Error(err))
+		}
+	}
+	return run.ID
+}
+
+// RunNow runs a schedule immediately, outside its cron and regardless of its status and
+// day filter; blackout windows still apply. It does not count toward max_runs or move last_run_at. A dry run loads the
+// account's session and resolves every channel peer but sends nothing.
+func (s *Scheduler) RunNow(ctx context.Context, schedule *models.Schedule, opts RunOptions) (*RunReport, error) {
+	if s.stopping.Load() && !opts.DryRun {
+		return nil, ErrShuttingDown
+	}
+	runAt := time.Now()
+	pauses, err := loadPauses(s.db)
+	if err != nil {
+		return nil, err
+	}
+	if pause := pauses.forRun(schedule); pause != nil && !opts.DryRun {
+		return nil, fmt.Errorf("cannot run: %s", describePause(pause))
+	}
+	blackouts, err := s.loadBlackouts(schedule)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := s.planRun(schedule, runAt, opts.ChannelIDs, blackouts, pauses)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RunReport{
+		ScheduleID: schedule.ID,
+		RunAt:      runAt,
+		DryRun:     opts.DryRun,
+		TemplateID: plan.template.ID,
+		Template:   plan.template.Name,
+	}
+	if plan.account != nil {
+		report.AccountID = &plan.account.ID
+		report.Account = plan.account.Phone
+	}
+
+	if opts.DryRun {
+		s.checkPlan(ctx, plan)
+	} else {
+		// Each manual run is a distinct delivery, even when it shares a second with a cron run
+		keyID := uuid.New()
+		runID := s.enqueuePlan(schedule, runAt, "manual", plan, func(channelID uuid.UUID) string {
+			return manualDeliveryKey(keyID, channelID)
+		})
+		if runID != uuid.Nil {
+			report.RunID = &runID
+		}
+	}
+
+	report.Targets = plan.targets
+	for _, target := range report.Targets {
+		if target.Status == "failed" {
+			report.Failed++
+		}
+	}
+
+	if !opts.DryRun {
+		s.logJobExecution(schedule.ID, "manual",
+			fmt.Sprintf("Manual run queued %d of %d message(s)", len(report.Targets)-report.Failed, len(report.Targets)), "")
+	}
+	return report, nil
+}
+
+// checkPlan marks each target of a dry run ok or failed depending on whether its account
+// can reach it
+func (s *Scheduler) checkPlan(ctx context.Context, plan *runPlan) {
+	sessionErrs := make(map[uuid.UUID]error)
+	for i := range plan.targets {
+		target := &plan.targets[i]
+		if target.account == nil || target.Status == "skipped" || target.Status == "failed" {
+			continue
+		}
+
+		sessionErr, checked := sessionErrs[target.account.ID]
+		if !checked {
+			if target.account.Status != "active" {
+				sessionErr = fmt.Errorf("account is %s", target.account.Status)
+			} else if err := s.sessionManager.LoadSession(ctx, target.account); err != nil {
+				sessionErr = fmt.Errorf("failed to load session: %w", err)
+			}
+			sessionErrs[target.account.ID] = sessionErr
+		}
+		if sessionErr != nil {
+			target.Status = "failed"
+			target.Error = sessionErr.Error()
+			continue
+		}
+		if err := s.sessionManager.CheckPeer(ctx, target.account.Phone, target.ChatID); err != nil {
+			target.Status = "failed"
+			target.Error = err.Error()
+			continue
+		}
+		target.Status = "ok"
+	}
+}
+
+// manualDeliveryKey identifies the delivery of a manually triggered run to one channel
+func manualDeliveryKey(keyID, channelID uuid.UUID) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("manual|%s|%s", keyID, channelID)))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,7 +2,10 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/GezzyDax/timelith/go-backend/internal/database"
@@ -19,67 +22,203 @@ type Scheduler struct {
 	db             *database.DB
 	sessionManager *telegram.SessionManager
 	jobs           map[uuid.UUID]cron.EntryID
+	versions       map[uuid.UUID]time.Time // updated_at of every schedule registered by the leader
+	jobsMu         sync.Mutex
+	nativeMu       sync.Mutex // Serializes Telegram scheduled-delivery syncs
+	calendars      map[uuid.UUID]cachedCalendar
+	calendarMu     sync.Mutex
 	dispatcher     *Dispatcher
+	instanceID     string
+	leader         atomic.Bool
+	electionMu     sync.Mutex
+	stopping       atomic.Bool // Set once shutdown begins; no new runs are started
+	stopCh         chan struct{}
 }
 
-func NewScheduler(db *database.DB, sessionManager *telegram.SessionManager) *Scheduler {
+// ErrShuttingDown is returned for work refused because the scheduler is shutting down
+var ErrShuttingDown = errors.New("scheduler is shutting down")
+
+func NewScheduler(db *database.DB, sessionManager *telegram.SessionManager, queue Queue) *Scheduler {
 	return &Scheduler{
-		cron:           cron.New(cron.WithSeconds()),
+		cron:           cron.New(cron.WithSeconds(), cron.WithLocation(time.UTC)),
 		db:             db,
 		sessionManager: sessionManager,
 		jobs:           make(map[uuid.UUID]cron.EntryID),
-		dispatcher:     NewDispatcher(db, sessionManager),
+		versions:       make(map[uuid.UUID]time.Time),
+		calendars:      make(map[uuid.UUID]cachedCalendar),
+		dispatcher:     NewDispatcher(db, sessionManager, queue),
+		instanceID:     newInstanceID(),
+		stopCh:         make(chan struct{}),
 	}
 }
 
 func (s *Scheduler) Start(ctx context.Context) error {
-	logger.Log.Info("Starting scheduler")
+	logger.Log.Info("Starting scheduler",
+		zap.String("instance_id", s.instanceID))
 
-	// Load all active schedules
-	schedules, err := s.db.ListActiveSchedules()
-	if err != nil {
-		return fmt.Errorf("failed to load schedules: %w", err)
-	}
-
-	// Add each schedule to cron
-	for _, schedule := range schedules {
-		// Create a copy to avoid implicit memory aliasing
-		sched := schedule
-		if err := s.AddSchedule(&sched); err != nil {
-			logger.Log.Error("Failed to add schedule",
-				zap.String("schedule_id", sched.ID.String()),
-				zap.Error(err))
-			continue
-		}
+	// Keep Telegram scheduled deliveries topped up and reconciled
+	if _, err := s.cron.AddFunc(nativeSyncSpec, s.syncNativeSchedules); err != nil {
+		return fmt.Errorf("failed to register Telegram sync: %w", err)
+	}
+
+	// Let warming-up accounts read their dialogs now and then
+	if _, err := s.cron.AddFunc(warmupActivitySpec, s.runWarmupActivity); err != nil {
+		return fmt.Errorf("failed to register warm-up activity: %w", err)
 	}
 
-	// Start cron
+	// Start cron; schedules are only registered while this instance holds the lease
 	s.cron.Start()
+	go s.runElection(ctx)
 
-	logger.Log.Info("Scheduler started",
-		zap.Int("schedules_loaded", len(schedules)))
+	logger.Log.Info("Scheduler started")
 
-	// Run dispatcher in background
-	go s.dispatcher.Run(ctx)
+	// Run dispatcher in background on every instance
+	s.dispatcher.Start(ctx)
 
 	return nil
 }
 
-func (s *Scheduler) AddSchedule(schedule *models.Schedule) error {
+// parseSchedule builds the fire-time schedule of a schedule: its cron expression, one-time
+// run or interval, evaluated in the schedule's timezone and limited to its run window
+func parseSchedule(schedule *models.Schedule) (cron.Schedule, *time.Location, error) {
 	// Parse timezone
-	loc, err := time.LoadLocation(schedule.Timezone)
+	if err := ValidateTimezone(schedule.Timezone); err != nil {
+		return nil, nil, err
+	}
+	loc, _ := time.LoadLocation(schedule.Timezone)
+
+	base, err := parseBaseSchedule(schedule, loc)
 	if err != nil {
-		return fmt.Errorf("invalid timezone: %w", err)
+		return nil, nil, err
+	}
+
+	if schedule.StartsAt.Valid || schedule.EndsAt.Valid {
+		if schedule.StartsAt.Valid && schedule.EndsAt.Valid && !schedule.EndsAt.Time.After(schedule.StartsAt.Time) {
+			return nil, nil, fmt.Errorf("ends_at must be after starts_at")
+		}
+		base = windowSchedule{
+			inner:    base,
+			startsAt: schedule.StartsAt,
+			endsAt:   schedule.EndsAt,
+		}
+	}
+
+	return base, loc, nil
+}
+
+// parseBaseSchedule builds the fire times of a schedule's kind, ignoring its run window
+func parseBaseSchedule(schedule *models.Schedule, loc *time.Location) (cron.Schedule, error) {
+	switch schedule.Kind {
+	case "", "cron":
+		return parseCronExpr(schedule.CronExpr, loc)
+	case "once":
+		if !schedule.OnceAt.Valid {
+			return nil, fmt.Errorf("once_at is required for once schedules")
+		}
+		return onceSchedule{at: schedule.OnceAt.Time}, nil
+	case "interval":
+		if schedule.IntervalSeconds <= 0 {
+			return nil, fmt.Errorf("interval_seconds must be positive for interval schedules")
+		}
+		return intervalSchedule{
+			anchor: intervalAnchor(schedule),
+			every:  time.Duration(schedule.IntervalSeconds) * time.Second,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported schedule kind: %s", schedule.Kind)
+	}
+}
+
+// parseCronExpr parses a cron expression with seconds so that it fires on the wall clock of loc
+func parseCronExpr(expr string, loc *time.Location) (cron.Schedule, error) {
+	if hasZonePrefix(expr) {
+		return nil, fmt.Errorf("invalid cron expression: set the timezone field instead of a CRON_TZ prefix")
 	}
 
 	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	cronSchedule, err := parser.Parse(schedule.CronExpr)
+	cronSchedule, err := parser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	// Field-based specs follow the wall clock of the schedule's timezone
+	if spec, ok := cronSchedule.(*cron.SpecSchedule); ok {
+		spec.Location = time.UTC
+		return zonedSchedule{spec: spec, loc: loc}, nil
+	}
+
+	return cronSchedule, nil
+}
+
+// ValidateSchedule checks that a schedule's timing settings and timezone can be evaluated
+// and that its settings are consistent with each other
+func ValidateSchedule(schedule *models.Schedule) error {
+	if _, _, err := parseSchedule(schedule); err != nil {
+		return err
+	}
+
+	// Settings that depend on each other are checked on the merged schedule, as an update
+	// may change only one of them
+	if schedule.MaxRuns > 0 && schedule.DeliveryMode == "telegram" {
+		return fmt.Errorf("max_runs is not supported with telegram delivery")
+	}
+	if schedule.DelayMinSeconds > schedule.DelayMaxSeconds {
+		return fmt.Errorf("delay_min_seconds must not exceed delay_max_seconds")
+	}
+	if schedule.RetryBaseDelaySeconds > schedule.RetryMaxDelaySeconds {
+		return fmt.Errorf("retry_base_delay_seconds must not exceed retry_max_delay_seconds")
+	}
+	return nil
+}
+
+// AddSchedule registers the schedule with cron, replacing any existing entry for it.
+// On instances that are not the leader it only validates; the leader picks the change
+// up from the database within leaseRenewInterval.
+func (s *Scheduler) AddSchedule(schedule *models.Schedule) error {
+	if !s.IsLeader() {
+		return ValidateSchedule(schedule)
+	}
+	return s.register(schedule)
+}
+
+func (s *Scheduler) register(schedule *models.Schedule) error {
+	// Telegram uploads are not counted toward max_runs, so a row saved with both before
+	// this was validated is not registered rather than uploading without a limit
+	if err := ValidateSchedule(schedule); err != nil {
+		return err
+	}
+
+	cronSchedule, loc, err := parseSchedule(schedule)
 	if err != nil {
-		return fmt.Errorf("invalid cron expression: %w", err)
+		return err
+	}
+
+	// A one-time run in the past, an ended window or a used-up run limit leaves nothing to do
+	if reason := exhaustedReason(schedule, cronSchedule.Next(time.Now().In(loc))); reason != "" {
+		s.completeSchedule(schedule.ID, reason)
+		return nil
+	}
+
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	s.removeEntryLocked(schedule.ID)
+	s.versions[schedule.ID] = schedule.UpdatedAt
+
+	// Telegram delivers these itself; the periodic sync uploads upcoming runs
+	if schedule.DeliveryMode == "telegram" {
+		go s.syncNativeSchedule(context.Background(), schedule)
+
+		logger.Log.Info("Added schedule for Telegram scheduled delivery",
+			zap.String("schedule_id", schedule.ID.String()),
+			zap.String("kind", schedule.Kind),
+			zap.Int("schedule_ahead_hours", schedule.ScheduleAheadHours))
+		return nil
 	}
 
 	job := cron.NewChain().Then(cron.FuncJob(func() {
-		s.executeSchedule(schedule.ID)
+		// Cron fires on whole seconds, so this is the scheduled run time
+		s.executeSchedule(schedule.ID, time.Now().Truncate(time.Second), "cron")
 	}))
 
 	entryID := s.cron.Schedule(cronSchedule, job)
@@ -88,8 +227,8 @@ func (s *Scheduler) AddSchedule(schedule *models.Schedule) error {
 
 	// Update next run time
 	nextRun := cronSchedule.Next(time.Now().In(loc))
-	schedule.NextRunAt = models.NewNullTime(nextRun)
-	if err := s.db.UpdateSchedule(schedule); err != nil {
+	schedule.NextRunAt = nextRunTime(nextRun)
+	if err := s.db.SetScheduleNextRun(schedule.ID, schedule.NextRunAt); err != nil {
 		logger.Log.Error("Failed to update schedule next_run_at",
 			zap.String("schedule_id", schedule.ID.String()),
 			zap.Error(err))
@@ -97,13 +236,37 @@ func (s *Scheduler) AddSchedule(schedule *models.Schedule) error {
 
 	logger.Log.Info("Added schedule to cron",
 		zap.String("schedule_id", schedule.ID.String()),
+		zap.String("kind", schedule.Kind),
 		zap.String("cron_expr", schedule.CronExpr),
 		zap.Time("next_run", nextRun))
 
 	return nil
 }
 
+// RemoveSchedule unregisters the schedule from cron and clears its next run time
 func (s *Scheduler) RemoveSchedule(scheduleID uuid.UUID) {
+	if err := s.db.SetScheduleNextRun(scheduleID, models.NullTime{}); err != nil {
+		logger.Log.Error("Failed to clear schedule next_run_at",
+			zap.String("schedule_id", scheduleID.String()),
+			zap.Error(err))
+	}
+
+	// Other instances leave this to the leader's next reconcile
+	if !s.IsLeader() {
+		return
+	}
+
+	s.jobsMu.Lock()
+	s.removeEntryLocked(scheduleID)
+	delete(s.versions, scheduleID)
+	s.jobsMu.Unlock()
+
+	// Pending Telegram scheduled deliveries of the schedule are cancelled right away
+	go s.cancelOrphanedNativeMessages(context.Background())
+}
+
+// removeEntryLocked drops the cron entry of a schedule; callers must hold jobsMu
+func (s *Scheduler) removeEntryLocked(scheduleID uuid.UUID) {
 	if entryID, exists := s.jobs[scheduleID]; exists {
 		s.cron.Remove(entryID)
 		delete(s.jobs, scheduleID)
@@ -113,7 +276,48 @@ func (s *Scheduler) RemoveSchedule(scheduleID uuid.UUID) {
 	}
 }
 
-func (s *Scheduler) executeSchedule(scheduleID uuid.UUID) {
+// updateNextRun recomputes next_run_at after a run
+// and returns it; ok is false if the schedule could not be evaluated
+func (s *Scheduler) updateNextRun(schedule *models.Schedule) (nextRun time.Time, ok bool) {
+	cronSchedule, loc, err := parseSchedule(schedule)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	nextRun = cronSchedule.Next(time.Now().In(loc))
+	if err := s.db.SetScheduleNextRun(schedule.ID, nextRunTime(nextRun)); err != nil {
+		logger.Log.Error("Failed to update schedule next_run_at",
+			zap.String("schedule_id", schedule.ID.String()),
+			zap.Error(err))
+	}
+	return nextRun, true
+}
+
+// nextRunTime converts a fire time to next_run_at, which is NULL when there is no next run
+func nextRunTime(t time.Time) models.NullTime {
+	if t.IsZero() {
+		return models.NullTime{}
+	}
+	return models.NewNullTime(t)
+}
+
+// setLastRun records that the run due at runAt was handled. Runs skipped on purpose count
+// too, so that misfire catch-up after a restart does not replay them as missed.
+func (s *Scheduler) setLastRun(scheduleID uuid.UUID, runAt time.Time) {
+	if err := s.db.SetScheduleLastRun(scheduleID, runAt); err != nil {
+		logger.Log.Error("Failed to update schedule last_run_at",
+			zap.String("schedule_id", scheduleID.String()),
+			zap.Error(err))
+	}
+}
+
+// executeSchedule enqueues the deliveries of the run of a schedule due at runAt;
+// trigger tells whether cron or misfire catch-up started it
+func (s *Scheduler) executeSchedule(scheduleID uuid.UUID, runAt time.Time, trigger string) {
+	if !s.IsLeader() || s.stopping.Load() {
+		return
+	}
+
 	logger.Log.Info("Executing schedule",
 		zap.String("schedule_id", scheduleID.String()))
 
@@ -126,86 +330,165 @@ func (s *Scheduler) executeSchedule(scheduleID uuid.UUID) {
 		return
 	}
 
-	// Check day filter
-	if !s.shouldRunToday(schedule) {
-		logger.Log.Info("Schedule skipped due to day filter",
+	// Check day filter and holiday calendars
+	if reason := s.dayReason(schedule, runAt); reason != "" {
+		logger.Log.Info("Schedule skipped on this day",
 			zap.String("schedule_id", scheduleID.String()),
+			zap.String("reason", reason),
 			zap.String("day_filter", schedule.DayFilter.String))
+		s.finishRun(schedule)
 		return
 	}
 
-	// Get account (with load balancing if enabled)
-	account, err := s.getAccountForSchedule(schedule)
+	pauses, err := loadPauses(s.db)
 	if err != nil {
-		logger.Log.Error("Failed to get account",
-			zap.String("account_id", schedule.AccountID.String()),
+		logger.Log.Error("Failed to prepare schedule run",
+			zap.String("schedule_id", scheduleID.String()),
 			zap.Error(err))
-		s.logJobExecution(scheduleID, "failed", "", fmt.Sprintf("Account not found: %v", err))
+		s.logJobExecution(scheduleID, "failed", "", err.Error())
 		return
 	}
 
-	// Get template
-	template, err := s.db.GetTemplate(schedule.TemplateID)
+	// A paused run is skipped rather than queued, so resuming does not release a backlog
+	if pause := pauses.forRun(schedule); pause != nil {
+		logger.Log.Info("Schedule skipped while sending is paused",
+			zap.String("schedule_id", scheduleID.String()),
+			zap.String("scope", pause.Scope))
+		s.logJobExecution(scheduleID, "paused",
+			fmt.Sprintf("Skipped run due at %s: %s", formatRunTime(runAt), describePause(pause)), "")
+		s.setLastRun(scheduleID, runAt)
+		s.finishRun(schedule)
+		return
+	}
+
+	blackouts, err := s.loadBlackouts(schedule)
 	if err != nil {
-		logger.Log.Error("Failed to get template",
-			zap.String("template_id", schedule.TemplateID.String()),
+		logger.Log.Error("Failed to prepare schedule run",
+			zap.String("schedule_id", scheduleID.String()),
 			zap.Error(err))
-		s.logJobExecution(scheduleID, "failed", "", fmt.Sprintf("Template not found: %v", err))
+		s.logJobExecution(scheduleID, "failed", "", err.Error())
 		return
 	}
 
-	// Get all channels for this schedule
-	var channelUUIDs []uuid.UUID
-	for _, cidStr := range schedule.ChannelIDs {
-		if cid, err := uuid.Parse(cidStr); err == nil {
-			channelUUIDs = append(channelUUIDs, cid)
-		}
+	// Check blackout windows of the whole run
+	if b := blackouts.forRun(runAt); b != nil && b.skip {
+		logger.Log.Info("Schedule skipped due to blackout window",
+			zap.String("schedule_id", scheduleID.String()),
+			zap.String("window", b.window.Name))
+		s.logJobExecution(scheduleID, "blackout",
+			fmt.Sprintf("Skipped run due at %s: blackout window %q", formatRunTime(runAt), b.window.Name), "")
+		s.setLastRun(scheduleID, runAt)
+		s.finishRun(schedule)
+		return
 	}
 
-	if len(channelUUIDs) == 0 {
-		logger.Log.Error("No valid channels in schedule",
-			zap.String("schedule_id", scheduleID.String()))
-		s.logJobExecution(scheduleID, "failed", "", "No channels configured")
+	plan, err := s.planRun(schedule, runAt, nil, blackouts, pauses)
+	if err != nil {
+		logger.Log.Error("Failed to prepare schedule run",
+			zap.String("schedule_id", scheduleID.String()),
+			zap.Error(err))
+		s.logJobExecution(scheduleID, "failed", "", err.Error())
 		return
 	}
 
 	// Queue messages for each channel with delays
-	for i, channelID := range channelUUIDs {
-		channel, err := s.db.GetChannel(channelID)
-		if err != nil {
-			logger.Log.Error("Failed to get channel",
-				zap.String("channel_id", channelID.String()),
-				zap.Error(err))
-			continue
-		}
+	s.enqueuePlan(schedule, runAt, trigger, plan, nil)
 
-		// Calculate delay for this message
-		delay := s.calculateDelay(schedule, i)
+	s.setLastRun(scheduleID, runAt)
 
-		job := &MessageJob{
-			ScheduleID: scheduleID,
-			Account:    account,
-			Template:   template,
-			Channel:    channel,
-			Message:    template.Content, // TODO: Process variables
-			Delay:      delay,
-		}
+	// Count the run toward max_runs
+	if count, err := s.db.IncrementScheduleRunCount(scheduleID); err != nil {
+		logger.Log.Error("Failed to update schedule run_count",
+			zap.String("schedule_id", scheduleID.String()),
+			zap.Error(err))
+	} else {
+		schedule.RunCount = count
+	}
+	s.finishRun(schedule)
+}
 
-		s.dispatcher.Enqueue(job)
+// finishRun moves next_run_at forward after a run, completing the schedule when no runs are left
+func (s *Scheduler) finishRun(schedule *models.Schedule) {
+	nextRun, ok := s.updateNextRun(schedule)
+	if !ok {
+		return
 	}
+	if reason := exhaustedReason(schedule, nextRun); reason != "" {
+		s.completeSchedule(schedule.ID, reason)
+	}
+}
 
-	// Update last run time
-	schedule.LastRunAt = models.NewNullTime(time.Now())
-	if err := s.db.UpdateSchedule(schedule); err != nil {
-		logger.Log.Error("Failed to update schedule last_run_at",
+// exhaustedReason explains why a schedule has no runs left, or returns "" if it has.
+// nextRun is the schedule's next fire time, zero if there is none.
+func exhaustedReason(schedule *models.Schedule, nextRun time.Time) string {
+	switch {
+	case schedule.MaxRuns > 0 && schedule.RunCount >= schedule.MaxRuns:
+		return fmt.Sprintf("reached max_runs (%d)", schedule.MaxRuns)
+	case nextRun.IsZero() && schedule.Kind == "once":
+		return "one-time run is over"
+	case nextRun.IsZero() && schedule.EndsAt.Valid:
+		return "ends_at has passed"
+	case nextRun.IsZero():
+		return "no further runs"
+	}
+	return ""
+}
+
+// completeSchedule marks a schedule completed and unregisters it
+func (s *Scheduler) completeSchedule(scheduleID uuid.UUID, reason string) {
+	if err := s.db.CompleteSchedule(scheduleID); err != nil {
+		logger.Log.Error("Failed to complete schedule",
 			zap.String("schedule_id", scheduleID.String()),
 			zap.Error(err))
+		return
+	}
+
+	s.jobsMu.Lock()
+	s.removeEntryLocked(scheduleID)
+	delete(s.versions, scheduleID)
+	s.jobsMu.Unlock()
+
+	logger.Log.Info("Schedule completed",
+		zap.String("schedule_id", scheduleID.String()),
+		zap.String("reason", reason))
+	s.logJobExecution(scheduleID, "completed", fmt.Sprintf("Schedule completed: %s", reason), "")
+}
+
+// shouldRunOn reports whether the schedule's day filter and holiday calendars allow a run
+// at t, judged by the calendar day in the schedule's timezone
+func (s *Scheduler) shouldRunOn(schedule *models.Schedule, t time.Time) bool {
+	return s.dayReason(schedule, t) == ""
+}
+
+// dayReason explains why the schedule does not run on the day of t: "calendar" when the
+// date is in an exclude calendar, "day_filter" when the day filter rejects it and no include
+// calendar lists it. It is empty when the schedule runs that day.
+func (s *Scheduler) dayReason(schedule *models.Schedule, t time.Time) string {
+	// The run of a one-time schedule is picked explicitly
+	if schedule.Kind == "once" {
+		return ""
+	}
+
+	if loc, err := time.LoadLocation(schedule.Timezone); err == nil {
+		t = t.In(loc)
+	}
+	date := t.Format("2006-01-02")
+
+	if len(schedule.ExcludeCalendarIDs) > 0 && s.inCalendars(schedule.ExcludeCalendarIDs, date) {
+		return "calendar"
 	}
+	if dayFilterAllows(schedule, t) {
+		return ""
+	}
+	if len(schedule.IncludeCalendarIDs) > 0 && s.inCalendars(schedule.IncludeCalendarIDs, date) {
+		return ""
+	}
+	return "day_filter"
 }
 
-func (s *Scheduler) shouldRunToday(schedule *models.Schedule) bool {
-	now := time.Now()
-	weekday := int(now.Weekday())
+// dayFilterAllows applies day_filter and custom_days to the weekday of t
+func dayFilterAllows(schedule *models.Schedule, t time.Time) bool {
+	weekday := int(t.Weekday())
 
 	if !schedule.DayFilter.Valid || schedule.DayFilter.String == "all" {
 		return true
@@ -228,33 +511,6 @@ func (s *Scheduler) shouldRunToday(schedule *models.Schedule) bool {
 	return true
 }
 
-func (s *Scheduler) getAccountForSchedule(schedule *models.Schedule) (*models.Account, error) {
-	// If load balancing is not enabled, use the specified account
-	if !schedule.LoadBalance {
-		return s.db.GetAccount(schedule.AccountID)
-	}
-
-	// Get least used active account
-	return s.db.GetLeastUsedAccount()
-}
-
-func (s *Scheduler) calculateDelay(schedule *models.Schedule, index int) time.Duration {
-	if index == 0 || schedule.DelayMaxSeconds == 0 {
-		return 0
-	}
-
-	minDelay := schedule.DelayMinSeconds
-	maxDelay := schedule.DelayMaxSeconds
-
-	if minDelay >= maxDelay {
-		return time.Duration(minDelay) * time.Second
-	}
-
-	// Random delay between min and max
-	randomSeconds := minDelay + (maxDelay-minDelay)*index/10 // Simple distribution
-	return time.Duration(randomSeconds) * time.Second
-}
-
 func (s *Scheduler) logJobExecution(scheduleID uuid.UUID, status, message, errorMsg string) {
 	log := &models.JobLog{
 		ScheduleID: scheduleID,
@@ -276,8 +532,44 @@ func (s *Scheduler) logJobExecution(scheduleID uuid.UUID, status, message, error
 	}
 }
 
-func (s *Scheduler) Stop() {
+// ShuttingDown reports whether shutdown has begun
+func (s *Scheduler) ShuttingDown() bool {
+	return s.stopping.Load()
+}
+
+// Shutdown stops the scheduler in order: cron stops firing and the runs it already started
+// finish queueing, the lease is handed over to another instance, Telegram scheduled-delivery
+// syncs finish and the dispatcher drains. Everything queued but not sent stays in the queue
+// for the next start. ctx bounds the whole shutdown; whatever is left when it expires is
+// interrupted and the deadline error returned.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	if !s.stopping.CompareAndSwap(false, true) {
+		return nil
+	}
 	logger.Log.Info("Stopping scheduler")
-	s.cron.Stop()
-	s.dispatcher.Stop()
+	close(s.stopCh)
+
+	select {
+	case <-s.cron.Stop().Done():
+	case <-ctx.Done():
+		logger.Log.Warn("Schedule runs still queueing at shutdown")
+	}
+
+	s.electionMu.Lock()
+	s.releaseLease()
+	s.electionMu.Unlock()
+
+	synced := make(chan struct{})
+	go func() {
+		s.nativeMu.Lock()
+		s.nativeMu.Unlock()
+		close(synced)
+	}()
+	select {
+	case <-synced:
+	case <-ctx.Done():
+		logger.Log.Warn("Telegram scheduled-delivery sync still running at shutdown")
+	}
+
+	return s.dispatcher.Shutdown(ctx)
 }
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"os"
+	"testing"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/database"
+	"github.com/google/uuid"
+)
+
+// openTestDB connects to the database in TEST_DATABASE_URL and migrates it, or skips the
+// test when none is configured
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := database.Connect(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testFixture is an account, template, channel and schedule created for one test
+type testFixture struct {
+	accountID  uuid.UUID
+	templateID uuid.UUID
+	channelID  uuid.UUID
+	scheduleID uuid.UUID
+}
+
+// seedSchedule creates the rows a schedule needs and removes them when the test ends
+func seedSchedule(t *testing.T, db *database.DB, timezone string) testFixture {
+	t.Helper()
+	f := testFixture{accountID: uuid.New(), templateID: uuid.New(), channelID: uuid.New(), scheduleID: uuid.New()}
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO accounts (id, phone, status) VALUES ($1, $2, 'active')`,
+			[]interface{}{f.accountID, "+test" + f.accountID.String()[:8]}},
+		{`INSERT INTO templates (id, name, content) VALUES ($1, 'test', 'test')`,
+			[]interface{}{f.templateID}},
+		{`INSERT INTO channels (id, name, chat_id, type) VALUES ($1, 'test', '@test', 'channel')`,
+			[]interface{}{f.channelID}},
+		{`INSERT INTO schedules (id, name, account_id, template_id, cron_expr, timezone)
+		  VALUES ($1, 'test', $2, $3, '0 9 * * *', $4)`,
+			[]interface{}{f.scheduleID, f.accountID, f.templateID, timezone}},
+	}
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM schedules WHERE id = $1`, f.scheduleID)
+		db.Exec(`DELETE FROM accounts WHERE id = $1`, f.accountID)
+		db.Exec(`DELETE FROM templates WHERE id = $1`, f.templateID)
+		db.Exec(`DELETE FROM channels WHERE id = $1`, f.channelID)
+	})
+	for _, s := range statements {
+		if _, err := db.Exec(s.query, s.args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	return f
+}
@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxWallSteps bounds how many wall-clock candidates Next skips while resolving DST overlaps
+const maxWallSteps = 1000
+
+// maxDSTGap is the longest clock jump of a DST transition
+const maxDSTGap = 2 * time.Hour
+
+// zonedSchedule evaluates a cron spec against wall-clock time in the schedule's location.
+//
+// DST transitions are handled as follows:
+//   - A wall-clock time inside a spring-forward gap (e.g. 02:30 when clocks jump from
+//     02:00 to 03:00) fires once, shifted forward by the length of the gap (03:30).
+//     Further gap times that would resolve to an instant already passed are skipped.
+//   - A wall-clock time that occurs twice on a fall-back day (e.g. 01:30 when clocks
+//     go from 02:00 back to 01:00) fires once, at its first occurrence.
+type zonedSchedule struct {
+	spec cron.Schedule // Evaluated in UTC, which has no DST, on wall-clock values
+	loc  *time.Location
+}
+
+// Next returns the first fire time strictly after t
+func (z zonedSchedule) Next(t time.Time) time.Time {
+	wall := toWall(t.In(z.loc))
+	for i := 0; i < maxWallSteps; i++ {
+		wall = z.spec.Next(wall)
+		if wall.IsZero() {
+			return time.Time{}
+		}
+
+		next := fromWall(wall, z.loc)
+		if next.After(t) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// toWall keeps the wall-clock fields of t and drops its zone
+func toWall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// fromWall resolves a wall-clock time to an instant in loc using the DST policy of zonedSchedule
+func fromWall(wall time.Time, loc *time.Location) time.Time {
+	// Offsets a day either side are those before and after any transition at this wall time
+	_, before := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	early := wall.Add(-time.Duration(before) * time.Second).In(loc)
+	late := wall.Add(-time.Duration(after) * time.Second).In(loc)
+
+	earlyValid := toWall(early).Equal(wall)
+	lateValid := toWall(late).Equal(wall)
+
+	switch {
+	case earlyValid && lateValid:
+		// Regular time, or an overlap where the first occurrence wins
+		if late.Before(early) {
+			return late
+		}
+		return early
+	case lateValid:
+		return late
+	default:
+		// Gap: interpreting the time with the pre-transition offset moves it past the gap
+		return early
+	}
+}
+
+// ValidateTimezone checks that name is an IANA timezone usable for schedules
+func ValidateTimezone(name string) error {
+	if name == "" {
+		return fmt.Errorf("timezone is required")
+	}
+	if name == "Local" {
+		return fmt.Errorf("timezone must be an IANA name such as Europe/Moscow, not Local")
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return nil
+}
+
+// hasZonePrefix reports whether a cron expression carries its own CRON_TZ or TZ prefix
+func hasZonePrefix(expr string) bool {
+	expr = strings.TrimSpace(expr)
+	return strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=")
+}
@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+)
+
+// firesOn lists the fire times of a cron expression during a local calendar day
+func firesOn(t *testing.T, expr, zone string, year int, month time.Month, day int) []time.Time {
+	t.Helper()
+	schedule := &models.Schedule{Kind: "cron", CronExpr: expr, Timezone: zone}
+	cronSchedule, loc, err := parseSchedule(schedule)
+	if err != nil {
+		t.Fatalf("parseSchedule: %v", err)
+	}
+
+	start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	end := time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	var fires []time.Time
+	for next := cronSchedule.Next(start.Add(-time.Nanosecond)); !next.IsZero() && next.Before(end); next = cronSchedule.Next(next) {
+		fires = append(fires, next)
+	}
+	return fires
+}
+
+func TestZonedScheduleDST(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		zone  string
+		year  int
+		month time.Month
+		day   int
+		want  []time.Time // Expected fire instants in UTC
+	}{
+		{
+			name: "Berlin spring forward: 02:30 fires once at 03:30 CEST",
+			expr: "0 30 2 * * *", zone: "Europe/Berlin", year: 2026, month: time.March, day: 29,
+			want: []time.Time{time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "Berlin fall back: repeated 02:30 fires once, at its first occurrence in CEST",
+			expr: "0 30 2 * * *", zone: "Europe/Berlin", year: 2026, month: time.October, day: 25,
+			want: []time.Time{time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "New York spring forward: 02:30 fires once at 03:30 EDT",
+			expr: "0 30 2 * * *", zone: "America/New_York", year: 2026, month: time.March, day: 8,
+			want: []time.Time{time.Date(2026, 3, 8, 7, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "New York fall back: repeated 01:30 fires once, at its first occurrence in EDT",
+			expr: "0 30 1 * * *", zone: "America/New_York", year: 2026, month: time.November, day: 1,
+			want: []time.Time{time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "New York fall back: 02:30 is not repeated and fires once in EST",
+			expr: "0 30 2 * * *", zone: "America/New_York", year: 2026, month: time.November, day: 1,
+			want: []time.Time{time.Date(2026, 11, 1, 7, 30, 0, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := firesOn(t, tt.expr, tt.zone, tt.year, tt.month, tt.day)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d fire times %v, want %v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("fire %d at %s, want %s", i, got[i].UTC(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestZonedScheduleDSTHourlyCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  string
+		year  int
+		month time.Month
+		day   int
+		want  int
+	}{
+		// The 02:30 of the gap resolves to 03:30, which then fires only once
+		{"Berlin spring forward", "Europe/Berlin", 2026, time.March, 29, 23},
+		// Each wall-clock time fires once, so the repeated hour is not run twice
+		{"Berlin fall back", "Europe/Berlin", 2026, time.October, 25, 24},
+		{"New York spring forward", "America/New_York", 2026, time.March, 8, 23},
+		{"New York fall back", "America/New_York", 2026, time.November, 1, 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := firesOn(t, "0 30 * * * *", tt.zone, tt.year, tt.month, tt.day)
+			if len(got) != tt.want {
+				t.Errorf("hourly schedule fired %d times, want %d: %v", len(got), tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateTimezone(t *testing.T) {
+	tests := []struct {
+		zone    string
+		wantErr bool
+	}{
+		{"Europe/Berlin", false},
+		{"America/New_York", false},
+		{"UTC", false},
+		{"", true},
+		{"Local", true},
+		{"Mars/Olympus_Mons", true},
+		{"Europe/Berlinn", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			err := ValidateTimezone(tt.zone)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTimezone(%q) error = %v, want error %v", tt.zone, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleTimesWithOffsetAreStoredInUTC(t *testing.T) {
+	db := openTestDB(t)
+	f := seedSchedule(t, db, "Europe/Moscow")
+
+	schedule, err := db.GetSchedule(f.scheduleID)
+	if err != nil {
+		t.Fatalf("get schedule: %v", err)
+	}
+	moscow := time.FixedZone("+03:00", 3*3600)
+	onceAt := time.Date(2026, 12, 31, 23, 59, 0, 0, moscow)
+	schedule.Kind = "once"
+	schedule.OnceAt = models.NewNullTime(onceAt)
+	schedule.StartsAt = models.NewNullTime(onceAt.Add(-time.Hour))
+	schedule.EndsAt = models.NewNullTime(onceAt.Add(time.Hour))
+	if err := db.UpdateSchedule(schedule); err != nil {
+		t.Fatalf("update schedule: %v", err)
+	}
+
+	stored, err := db.GetSchedule(f.scheduleID)
+	if err != nil {
+		t.Fatalf("get schedule: %v", err)
+	}
+	if !stored.OnceAt.Time.Equal(onceAt) {
+		t.Errorf("once_at read back as %v, want %v", stored.OnceAt.Time, onceAt.UTC())
+	}
+	if !stored.StartsAt.Time.Equal(onceAt.Add(-time.Hour)) || !stored.EndsAt.Time.Equal(onceAt.Add(time.Hour)) {
+		t.Errorf("window read back as %v - %v, want %v - %v",
+			stored.StartsAt.Time, stored.EndsAt.Time, onceAt.Add(-time.Hour).UTC(), onceAt.Add(time.Hour).UTC())
+	}
+	if !stored.UpdatedAt.Equal(schedule.UpdatedAt) {
+		t.Errorf("updated_at after update is %v, stored %v", schedule.UpdatedAt, stored.UpdatedAt)
+	}
+}
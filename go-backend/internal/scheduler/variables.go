@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+)
+
+// variablePattern matches {{name}} placeholders in template content
+var variablePattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+
+// messageVariables returns the built-in template variables for a delivery. Dates and
+// times are given in the schedule's timezone.
+func messageVariables(schedule *models.Schedule, channel *models.Channel, runAt time.Time) map[string]string {
+	if loc, err := time.LoadLocation(schedule.Timezone); err == nil {
+		runAt = runAt.In(loc)
+	}
+
+	return map[string]string{
+		"date":     runAt.Format("2006-01-02"),
+		"time":     runAt.Format("15:04"),
+		"datetime": runAt.Format("2006-01-02 15:04"),
+		"weekday":  runAt.Weekday().String(),
+		"schedule": schedule.Name,
+		"channel":  channel.Name,
+	}
+}
+
+// renderMessage substitutes {{name}} placeholders in content. Placeholders without a value
+// are left as they are and returned sorted, without duplicates.
+func renderMessage(content string, vars map[string]string) (string, []string) {
+	seen := make(map[string]bool)
+	var unresolved []string
+
+	message := variablePattern.ReplaceAllStringFunc(content, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if !seen[name] {
+			seen[name] = true
+			unresolved = append(unresolved, name)
+		}
+		return match
+	})
+
+	sort.Strings(unresolved)
+	return message, unresolved
+}
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/logger"
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// warmupActivitySpec is how often warming-up accounts are checked for due benign activity
+	warmupActivitySpec = "@every 10m"
+	// Benign activity of an account happens at a random gap within these bounds
+	warmupActivityMinGap = time.Hour
+	warmupActivityMaxGap = 4 * time.Hour
+)
+
+// warmupBlock returns the warm-up cap the account's next message would exceed, or nil when
+// the account is not warming up or is within today's cap. The cap frees up when old sends
+// age out or when the next warm-up day raises it, whichever comes first.
+func warmupBlock(account *models.Account, load quotaLoad, now time.Time) *quotaBlock {
+	warmup := account.WarmupAt(now)
+	if warmup == nil || load.usage.DailySent+load.planned < warmup.DailyCap {
+		return nil
+	}
+
+	until := freesAt(load.usage.OldestDaily, quotaDayWindow, now)
+	if next := account.WarmupStartedAt.Time.Add(time.Duration(warmup.Day) * 24 * time.Hour); next.Before(until) {
+		until = next
+	}
+	return &quotaBlock{
+		reason: fmt.Sprintf("warm-up cap of %d messages on day %d of %d reached", warmup.DailyCap, warmup.Day, warmup.Days),
+		until:  until,
+	}
+}
+
+// nativeWarmupBlock checks an upload Telegram sends at the given time against the account's
+// warm-up cap, counting the uploads already recorded for the day before it
+func (s *Scheduler) nativeWarmupBlock(account *models.Account, sendAt time.Time) (*quotaBlock, error) {
+	if account.WarmupAt(sendAt) == nil {
+		return nil, nil
+	}
+	usage, err := s.db.GetAccountUsage(account.ID, sendAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load account usage: %w", err)
+	}
+	return warmupBlock(account, quotaLoad{usage: *usage}, sendAt), nil
+}
+
+// runWarmupActivity has warming-up accounts that opted in go online and read their dialogs,
+// each at a random time so they do not all show up together
+func (s *Scheduler) runWarmupActivity() {
+	if !s.IsLeader() || s.stopping.Load() {
+		return
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	accounts, err := s.db.ListWarmupActivityDue(now)
+	if err != nil {
+		logger.Log.Error("Failed to load accounts due for warm-up activity", zap.Error(err))
+		return
+	}
+
+	for i := range accounts {
+		account := &accounts[i]
+
+		// Scheduled first so an account that keeps failing is not retried on every check
+		gap := warmupActivityMinGap + time.Duration(rand.Int63n(int64(warmupActivityMaxGap-warmupActivityMinGap)))
+		if err := s.db.SetWarmupNextActivity(account.ID, now.Add(gap)); err != nil {
+			logger.Log.Error("Failed to schedule warm-up activity",
+				zap.String("account_id", account.ID.String()),
+				zap.Error(err))
+			continue
+		}
+
+		if err := s.sessionManager.LoadSession(ctx, account); err != nil {
+			logger.Log.Warn("Skipping warm-up activity: failed to load session",
+				zap.String("account", account.Phone),
+				zap.Error(err))
+			continue
+		}
+		if err := s.sessionManager.BenignActivity(ctx, account.Phone); err != nil {
+			logger.Log.Warn("Warm-up activity failed",
+				zap.String("account", account.Phone),
+				zap.Error(err))
+			continue
+		}
+		logger.Log.Info("Performed warm-up activity", zap.String("account", account.Phone))
+	}
+}
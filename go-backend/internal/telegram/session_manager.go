@@ -5,19 +5,32 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	mathrand "math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/GezzyDax/timelith/go-backend/internal/config"
+	"github.com/GezzyDax/timelith/go-backend/internal/encryption"
 	"github.com/GezzyDax/timelith/go-backend/internal/logger"
 	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
 	"github.com/gotd/td/session"
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/telegram/dcs"
 	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
 	"go.uber.org/zap"
+	"golang.org/x/net/proxy"
 )
 
 type SessionManager struct {
@@ -25,8 +38,13 @@ type SessionManager struct {
 	activeClients map[string]*clientEntry
 	mu            sync.RWMutex
 	gcm           cipher.AEAD
+	poolProxy     PoolProxyLookup
 }
 
+// PoolProxyLookup returns the account pool whose proxy an account without a proxy of its
+// own connects through, or nil when none of its pools has one
+type PoolProxyLookup func(accountID uuid.UUID) (*models.AccountPool, error)
+
 type clientEntry struct {
 	client  *telegram.Client
 	storage *session.StorageMemory
@@ -69,7 +87,14 @@ func (sm *SessionManager) getEntry(phone string) (*clientEntry, error) {
 	return entry, nil
 }
 
-func (sm *SessionManager) newClientWithSession(ctx context.Context, sessionBytes []byte) (*session.StorageMemory, *telegram.Client, error) {
+// SetPoolProxyLookup makes loaded sessions fall back to the proxy of their account's pool
+func (sm *SessionManager) SetPoolProxyLookup(lookup PoolProxyLookup) {
+	sm.poolProxy = lookup
+}
+
+// newClientWithSession creates a client from session bytes. A nil resolver connects to
+// Telegram directly.
+func (sm *SessionManager) newClientWithSession(ctx context.Context, sessionBytes []byte, resolver dcs.Resolver) (*session.StorageMemory, *telegram.Client, error) {
 	storage := &session.StorageMemory{}
 	if len(sessionBytes) > 0 {
 		if err := storage.StoreSession(ctx, sessionBytes); err != nil {
@@ -79,6 +104,7 @@ func (sm *SessionManager) newClientWithSession(ctx context.Context, sessionBytes
 
 	client := telegram.NewClient(sm.cfg.TelegramAppID, sm.cfg.TelegramAppHash, telegram.Options{
 		SessionStorage: storage,
+		Resolver:       resolver,
 	})
 
 	return storage, client, nil
@@ -122,7 +148,7 @@ func (sm *SessionManager) DecryptSession(data []byte) ([]byte, error) {
 
 // AuthenticatePhone initiates phone authentication
 func (sm *SessionManager) AuthenticatePhone(ctx context.Context, phone string) (string, []byte, error) {
-	storage, client, err := sm.newClientWithSession(ctx, nil)
+	storage, client, err := sm.newClientWithSession(ctx, nil, nil)
 	if err != nil {
 		return "", nil, err
 	}
@@ -158,7 +184,7 @@ func (sm *SessionManager) AuthenticatePhone(ctx context.Context, phone string) (
 
 // VerifyCode verifies the authentication code and completes login if no password is required.
 func (sm *SessionManager) VerifyCode(ctx context.Context, phone, code, phoneCodeHash string, pendingSession []byte) (finalSession []byte, nextPending []byte, requiresPassword bool, passwordHint string, err error) {
-	storage, client, err := sm.newClientWithSession(ctx, pendingSession)
+	storage, client, err := sm.newClientWithSession(ctx, pendingSession, nil)
 	if err != nil {
 		return nil, nil, false, "", err
 	}
@@ -205,7 +231,7 @@ func (sm *SessionManager) VerifyPassword(ctx context.Context, phone string, pend
 		return nil, fmt.Errorf("failed to decrypt pending session: %w", err)
 	}
 
-	storage, client, err := sm.newClientWithSession(ctx, rawPending)
+	storage, client, err := sm.newClientWithSession(ctx, rawPending, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -249,7 +275,12 @@ func (sm *SessionManager) LoadSession(ctx context.Context, account *models.Accou
 		return fmt.Errorf("failed to decrypt session: %w", err)
 	}
 
-	storage, client, err := sm.newClientWithSession(ctx, sessionData)
+	resolver, err := sm.accountResolver(account)
+	if err != nil {
+		return err
+	}
+
+	storage, client, err := sm.newClientWithSession(ctx, sessionData, resolver)
 	if err != nil {
 		return err
 	}
@@ -266,6 +297,71 @@ func (sm *SessionManager) LoadSession(ctx context.Context, account *models.Accou
 	return nil
 }
 
+// proxySettings is a SOCKS5 proxy a client connects to Telegram through
+type proxySettings struct {
+	host     string
+	port     int64
+	username string
+	password string // Encrypted
+}
+
+// accountResolver returns how the account's client reaches Telegram: through the account's
+// own proxy, else through the proxy of one of its pools, else directly (nil)
+func (sm *SessionManager) accountResolver(account *models.Account) (dcs.Resolver, error) {
+	var settings *proxySettings
+	if account.ProxyEnabled {
+		settings = &proxySettings{
+			host:     account.ProxyHost.String,
+			port:     account.ProxyPort.Int64,
+			username: account.ProxyUsername.String,
+			password: account.ProxyPassword.String,
+		}
+	} else if sm.poolProxy != nil {
+		pool, err := sm.poolProxy(account.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load account pool proxy: %w", err)
+		}
+		if pool != nil {
+			settings = &proxySettings{
+				host:     pool.ProxyHost.String,
+				port:     pool.ProxyPort.Int64,
+				username: pool.ProxyUsername.String,
+				password: pool.ProxyPassword.String,
+			}
+		}
+	}
+	if settings == nil {
+		return nil, nil
+	}
+	return settings.resolver()
+}
+
+// resolver dials Telegram data centers through the proxy
+func (p *proxySettings) resolver() (dcs.Resolver, error) {
+	var proxyAuth *proxy.Auth
+	if p.username != "" {
+		proxyAuth = &proxy.Auth{User: p.username}
+		if p.password != "" {
+			password, err := encryption.Decrypt(p.password)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt proxy password: %w", err)
+			}
+			proxyAuth.Password = password
+		}
+	}
+
+	address := net.JoinHostPort(p.host, strconv.FormatInt(p.port, 10))
+	dialer, err := proxy.SOCKS5("tcp", address, proxyAuth, proxy.Direct)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure proxy %s: %w", address, err)
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("proxy %s does not support dialing with a context", address)
+	}
+	return dcs.Plain(dcs.PlainOptions{Dial: contextDialer.DialContext}), nil
+}
+
 // GetClient returns an existing client
 func (sm *SessionManager) GetClient(phone string) (*telegram.Client, error) {
 	entry, err := sm.getEntry(phone)
@@ -275,14 +371,139 @@ func (sm *SessionManager) GetClient(phone string) (*telegram.Client, error) {
 	return entry.client, nil
 }
 
-// SendMessage sends a message to a chat
-func (sm *SessionManager) SendMessage(ctx context.Context, phone, chatID, message string) error {
+// SendOptions adjusts how a message is delivered
+type SendOptions struct {
+	ScheduleDate time.Time // Upload into Telegram's scheduled queue instead of sending now
+	// IdempotencyKey derives deterministic random_ids, so Telegram rejects a repeated
+	// send of the same delivery with RANDOM_ID_DUPLICATE instead of posting it twice
+	IdempotencyKey string
+}
+
+// randomID returns the random_id for the i-th message of a send
+func (o SendOptions) randomID(i int) (int64, error) {
+	if o.IdempotencyKey == "" {
+		return randomID()
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s#%d", o.IdempotencyKey, i)))
+	return int64(binary.LittleEndian.Uint64(sum[:8])), nil
+}
+
+// IsDuplicateSend reports whether Telegram refused a send because a message with the
+// same random_id was already delivered
+func IsDuplicateSend(err error) bool {
+	return tgerr.Is(err, "RANDOM_ID_DUPLICATE")
+}
+
+// FloodWait returns how long Telegram asked to wait before the next request, if it did
+func FloodWait(err error) (time.Duration, bool) {
+	return tgerr.AsFloodWait(err)
+}
+
+// accountRestrictionPeriod is how long an account is considered limited after Telegram
+// reports a spam restriction without saying for how long
+const accountRestrictionPeriod = 24 * time.Hour
+
+// Restriction reports how long Telegram limits the whole account after a send error: the
+// duration of a flood wait, or a fixed period for spam limits
+func Restriction(err error) (time.Duration, bool) {
+	if wait, ok := tgerr.AsFloodWait(err); ok {
+		return wait, true
+	}
+	if tgerr.Is(err, "PEER_FLOOD", "USER_RESTRICTED") {
+		return accountRestrictionPeriod, true
+	}
+	return 0, false
+}
+
+// IsNotMember reports whether a send failed because the account cannot post in the chat:
+// it is not a participant, was banned there or lacks the rights to post
+func IsNotMember(err error) bool {
+	rpcErr, ok := tgerr.As(err)
+	if !ok {
+		return false
+	}
+	switch rpcErr.Type {
+	case "CHANNEL_PRIVATE", "USER_NOT_PARTICIPANT", "CHAT_WRITE_FORBIDDEN", "CHAT_ADMIN_REQUIRED",
+		"USER_BANNED_IN_CHANNEL", "CHAT_GUEST_SEND_FORBIDDEN":
+		return true
+	}
+	return strings.HasPrefix(rpcErr.Type, "CHAT_SEND_") && strings.HasSuffix(rpcErr.Type, "_FORBIDDEN")
+}
+
+// ErrorType returns the Telegram error type of err, such as CHAT_WRITE_FORBIDDEN, or the
+// error text for errors that did not come from Telegram
+func ErrorType(err error) string {
+	if rpcErr, ok := tgerr.As(err); ok {
+		return rpcErr.Type
+	}
+	return err.Error()
+}
+
+// ErrorCategory groups a send error for reporting: flood_wait, peer, permission, session,
+// network or internal
+func ErrorCategory(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return "network"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return "network"
+	}
+
+	rpcErr, ok := tgerr.As(err)
+	if !ok {
+		if strings.Contains(err.Error(), "failed to resolve peer") {
+			return "peer"
+		}
+		if strings.Contains(err.Error(), "session") {
+			return "session"
+		}
+		return "internal"
+	}
+
+	switch {
+	case rpcErr.Code == 420 || strings.HasPrefix(rpcErr.Type, "FLOOD_"):
+		return "flood_wait"
+	case rpcErr.Code == 401 || strings.HasPrefix(rpcErr.Type, "AUTH_KEY") || strings.HasPrefix(rpcErr.Type, "SESSION_"):
+		return "session"
+	case rpcErr.Code == 403 || strings.HasSuffix(rpcErr.Type, "_FORBIDDEN") || strings.HasSuffix(rpcErr.Type, "_REQUIRED") ||
+		strings.Contains(rpcErr.Type, "BANNED"):
+		return "permission"
+	case strings.HasPrefix(rpcErr.Type, "PEER_") || strings.HasPrefix(rpcErr.Type, "USERNAME_") ||
+		strings.HasPrefix(rpcErr.Type, "CHANNEL_") || strings.HasPrefix(rpcErr.Type, "CHAT_ID_"):
+		return "peer"
+	default:
+		return "internal"
+	}
+}
+
+func (o SendOptions) scheduleDate() int {
+	if o.ScheduleDate.IsZero() {
+		return 0
+	}
+	return int(o.ScheduleDate.Unix())
+}
+
+// postDate returns when the message appears in the chat
+func (o SendOptions) postDate() time.Time {
+	if o.ScheduleDate.IsZero() {
+		return time.Now()
+	}
+	return o.ScheduleDate
+}
+
+// SendMessage sends a message to a chat and returns the IDs of the sent messages
+func (sm *SessionManager) SendMessage(ctx context.Context, phone, chatID, message string, opts SendOptions) ([]int, error) {
 	client, err := sm.GetClient(phone)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return client.Run(ctx, func(ctx context.Context) error {
+	var ids []int
+	err = client.Run(ctx, func(ctx context.Context) error {
 		api := client.API()
 
 		// Resolve peer (can be username, phone, or chat ID)
@@ -291,16 +512,219 @@ func (sm *SessionManager) SendMessage(ctx context.Context, phone, chatID, messag
 			return fmt.Errorf("failed to resolve peer: %w", err)
 		}
 
+		id, err := opts.randomID(0)
+		if err != nil {
+			return err
+		}
+
 		// Send message
-		_, err = api.MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
-			Peer:    peer,
-			Message: message,
+		updates, err := api.MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
+			Peer:         peer,
+			Message:      message,
+			RandomID:     id,
+			ScheduleDate: opts.scheduleDate(),
+		})
+		if err != nil {
+			return err
+		}
+
+		ids = sentMessageIDs(updates)
+		return nil
+	})
+
+	return ids, err
+}
+
+// DeleteScheduledMessages removes messages from a chat's Telegram scheduled queue
+func (sm *SessionManager) DeleteScheduledMessages(ctx context.Context, phone, chatID string, messageIDs []int) error {
+	client, err := sm.GetClient(phone)
+	if err != nil {
+		return err
+	}
+
+	return client.Run(ctx, func(ctx context.Context) error {
+		api := client.API()
+
+		peer, err := sm.resolvePeer(ctx, api, chatID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve peer: %w", err)
+		}
+
+		_, err = api.MessagesDeleteScheduledMessages(ctx, &tg.MessagesDeleteScheduledMessagesRequest{
+			Peer: peer,
+			ID:   messageIDs,
 		})
+		return err
+	})
+}
+
+// sentMessageIDs extracts the IDs assigned to sent messages from a send response
+func sentMessageIDs(updates tg.UpdatesClass) []int {
+	var list []tg.UpdateClass
+	switch u := updates.(type) {
+	case *tg.UpdateShortSentMessage:
+		return []int{u.ID}
+	case *tg.Updates:
+		list = u.Updates
+	case *tg.UpdatesCombined:
+		list = u.Updates
+	}
+
+	var ids []int
+	for _, update := range list {
+		if u, ok := update.(*tg.UpdateMessageID); ok {
+			ids = append(ids, u.ID)
+		}
+	}
+	if len(ids) > 0 {
+		return ids
+	}
+
+	// Scheduled sends may only report the new scheduled message
+	for _, update := range list {
+		if u, ok := update.(*tg.UpdateNewScheduledMessage); ok {
+			ids = append(ids, u.Message.GetID())
+		}
+	}
+	return ids
+}
+
+// CheckPeer verifies that the account can resolve a chat ID/username without sending anything
+func (sm *SessionManager) CheckPeer(ctx context.Context, phone, chatID string) error {
+	client, err := sm.GetClient(phone)
+	if err != nil {
+		return err
+	}
+
+	return client.Run(ctx, func(ctx context.Context) error {
+		if _, err := sm.resolvePeer(ctx, client.API(), chatID); err != nil {
+			return fmt.Errorf("failed to resolve peer: %w", err)
+		}
+		return nil
+	})
+}
+
+// SendTyping shows the account typing in a chat for the given duration
+func (sm *SessionManager) SendTyping(ctx context.Context, phone, chatID string, duration time.Duration) error {
+	client, err := sm.GetClient(phone)
+	if err != nil {
+		return err
+	}
+
+	return client.Run(ctx, func(ctx context.Context) error {
+		api := client.API()
+		peer, err := sm.resolvePeer(ctx, api, chatID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve peer: %w", err)
+		}
+
+		if _, err := api.MessagesSetTyping(ctx, &tg.MessagesSetTypingRequest{
+			Peer:   peer,
+			Action: &tg.SendMessageTypingAction{},
+		}); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
 
+// benignDialogLimit is how many recent dialogs BenignActivity looks through
+const benignDialogLimit = 20
+
+// BenignActivity makes the account look like a person opening Telegram: it goes online,
+// loads its recent dialogs, reads the unread ones and goes offline again. It sends nothing.
+func (sm *SessionManager) BenignActivity(ctx context.Context, phone string) error {
+	client, err := sm.GetClient(phone)
+	if err != nil {
 		return err
+	}
+
+	return client.Run(ctx, func(ctx context.Context) error {
+		api := client.API()
+		if _, err := api.AccountUpdateStatus(ctx, false); err != nil {
+			return fmt.Errorf("failed to go online: %w", err)
+		}
+
+		result, err := api.MessagesGetDialogs(ctx, &tg.MessagesGetDialogsRequest{
+			OffsetPeer: &tg.InputPeerEmpty{},
+			Limit:      benignDialogLimit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load dialogs: %w", err)
+		}
+		if dialogs, ok := result.AsModified(); ok {
+			if err := readDialogs(ctx, api, dialogs); err != nil {
+				return err
+			}
+		}
+
+		if _, err := api.AccountUpdateStatus(ctx, true); err != nil {
+			return fmt.Errorf("failed to go offline: %w", err)
+		}
+		return nil
 	})
 }
 
+// readDialogs marks the unread dialogs as read, pausing between them like a reader would
+func readDialogs(ctx context.Context, api *tg.Client, dialogs tg.ModifiedMessagesDialogs) error {
+	channels := make(map[int64]int64)
+	for _, chat := range dialogs.GetChats() {
+		if channel, ok := chat.(*tg.Channel); ok {
+			channels[channel.ID] = channel.AccessHash
+		}
+	}
+	users := make(map[int64]int64)
+	for _, u := range dialogs.GetUsers() {
+		if user, ok := u.(*tg.User); ok {
+			users[user.ID] = user.AccessHash
+		}
+	}
+
+	for _, d := range dialogs.GetDialogs() {
+		dialog, ok := d.(*tg.Dialog)
+		if !ok || dialog.UnreadCount == 0 {
+			continue
+		}
+
+		var err error
+		switch peer := dialog.Peer.(type) {
+		case *tg.PeerChannel:
+			_, err = api.ChannelsReadHistory(ctx, &tg.ChannelsReadHistoryRequest{
+				Channel: &tg.InputChannel{ChannelID: peer.ChannelID, AccessHash: channels[peer.ChannelID]},
+				MaxID:   dialog.TopMessage,
+			})
+		case *tg.PeerChat:
+			_, err = api.MessagesReadHistory(ctx, &tg.MessagesReadHistoryRequest{
+				Peer:  &tg.InputPeerChat{ChatID: peer.ChatID},
+				MaxID: dialog.TopMessage,
+			})
+		case *tg.PeerUser:
+			_, err = api.MessagesReadHistory(ctx, &tg.MessagesReadHistoryRequest{
+				Peer:  &tg.InputPeerUser{UserID: peer.UserID, AccessHash: users[peer.UserID]},
+				MaxID: dialog.TopMessage,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read dialog: %w", err)
+		}
+
+		select {
+		case <-time.After(time.Duration(1+mathrand.Intn(4)) * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
 // resolvePeer resolves a chat ID/username to a Telegram peer
 func (sm *SessionManager) resolvePeer(ctx context.Context, api *tg.Client, chatID string) (tg.InputPeerClass, error) {
 	// Try to resolve as username
@@ -310,6 +734,20 @@ func (sm *SessionManager) resolvePeer(ctx context.Context, api *tg.Client, chatI
 			return nil, err
 		}
 
+		switch peer := resolved.Peer.(type) {
+		case *tg.PeerChannel:
+			for _, chat := range resolved.Chats {
+				if channel, ok := chat.(*tg.Channel); ok && channel.ID == peer.ChannelID {
+					return &tg.InputPeerChannel{
+						ChannelID:  channel.ID,
+						AccessHash: channel.AccessHash,
+					}, nil
+				}
+			}
+		case *tg.PeerChat:
+			return &tg.InputPeerChat{ChatID: peer.ChatID}, nil
+		}
+
 		if len(resolved.Users) > 0 {
 			user := resolved.Users[0].(*tg.User)
 			return &tg.InputPeerUser{
@@ -344,14 +782,15 @@ func (sm *SessionManager) CloseClient(phone string) error {
 	return nil
 }
 
-// SendMediaMessage sends a message with media attachments
-func (sm *SessionManager) SendMediaMessage(ctx context.Context, phone, chatID string, template *models.Template) error {
+// SendMediaMessage sends a message with media attachments and returns the IDs of the sent messages
+func (sm *SessionManager) SendMediaMessage(ctx context.Context, phone, chatID string, template *models.Template, opts SendOptions) ([]int, error) {
 	client, err := sm.GetClient(phone)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return client.Run(ctx, func(ctx context.Context) error {
+	var ids []int
+	err = client.Run(ctx, func(ctx context.Context) error {
 		api := client.API()
 
 		peer, err := sm.resolvePeer(ctx, api, chatID)
@@ -367,10 +806,35 @@ func (sm *SessionManager) SendMediaMessage(ctx context.Context, phone, chatID st
 			return sm.sendVideo(ctx, api, peer, template)
 		case "album":
 			return sm.sendAlbum(ctx, api, peer, template)
+		case "poll", "location", "contact", "sticker":
+			media, err := sm.structuredMedia(ctx, api, template, opts.postDate())
+			if err != nil {
+				return err
+			}
+
+			id, err := opts.randomID(0)
+			if err != nil {
+				return err
+			}
+
+			updates, err := api.MessagesSendMedia(ctx, &tg.MessagesSendMediaRequest{
+				Peer:         peer,
+				Media:        media,
+				RandomID:     id,
+				ScheduleDate: opts.scheduleDate(),
+			})
+			if err != nil {
+				return err
+			}
+
+			ids = sentMessageIDs(updates)
+			return nil
 		default:
 			return fmt.Errorf("unsupported media type: %s", template.MediaType.String)
 		}
 	})
+
+	return ids, err
 }
 
 func (sm *SessionManager) sendPhoto(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, template *models.Template) error {
@@ -392,14 +856,155 @@ func (sm *SessionManager) sendAlbum(ctx context.Context, api *tg.Client, peer tg
 	return fmt.Errorf("album sending not implemented")
 }
 
-// ForwardMessage forwards a message from one chat to another
-func (sm *SessionManager) ForwardMessage(ctx context.Context, phone, toChatID, fromChatID string, messageID int) error {
+// structuredMedia builds the input media for poll, location, contact and sticker templates
+// of a message posted at postAt
+func (sm *SessionManager) structuredMedia(ctx context.Context, api *tg.Client, template *models.Template, postAt time.Time) (tg.InputMediaClass, error) {
+	payload := template.MediaPayload
+	if err := payload.Validate(template.MediaType.String); err != nil {
+		return nil, err
+	}
+
+	switch template.MediaType.String {
+	case "poll":
+		return pollMedia(payload.Poll, postAt)
+	case "location":
+		return locationMedia(payload.Location), nil
+	case "contact":
+		return &tg.InputMediaContact{
+			PhoneNumber: payload.Contact.PhoneNumber,
+			FirstName:   payload.Contact.FirstName,
+			LastName:    payload.Contact.LastName,
+			Vcard:       payload.Contact.VCard,
+		}, nil
+	case "sticker":
+		return sm.stickerMedia(ctx, api, payload.Sticker)
+	default:
+		return nil, fmt.Errorf("unsupported media type: %s", template.MediaType.String)
+	}
+}
+
+// pollMedia builds a new poll that closes ClosePeriod after postAt, so every run of a
+// schedule posts a poll that is open for the same time
+func pollMedia(p *models.PollPayload, postAt time.Time) (tg.InputMediaClass, error) {
+	pollID, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	poll := tg.Poll{
+		ID:             pollID,
+		Question:       p.Question,
+		Quiz:           p.Quiz,
+		PublicVoters:   !p.IsAnonymous(),
+		MultipleChoice: p.MultipleChoice,
+	}
+	if p.ClosePeriod > 0 {
+		poll.CloseDate = int(postAt.Add(time.Duration(p.ClosePeriod) * time.Second).Unix())
+	}
+
+	// Options are identified by their index, which keeps CorrectOption stable
+	for i, option := range p.Options {
+		poll.Answers = append(poll.Answers, tg.PollAnswer{
+			Text:   option,
+			Option: []byte{byte(i)},
+		})
+	}
+
+	media := &tg.InputMediaPoll{Poll: poll}
+	if p.Quiz {
+		media.CorrectAnswers = [][]byte{{byte(p.CorrectOption)}}
+		media.Solution = p.Solution
+	}
+	return media, nil
+}
+
+func locationMedia(l *models.LocationPayload) tg.InputMediaClass {
+	point := &tg.InputGeoPoint{
+		Lat:            l.Latitude,
+		Long:           l.Longitude,
+		AccuracyRadius: l.AccuracyRadius,
+	}
+
+	if !l.IsVenue() {
+		return &tg.InputMediaGeoPoint{GeoPoint: point}
+	}
+
+	return &tg.InputMediaVenue{
+		GeoPoint:  point,
+		Title:     l.Title,
+		Address:   l.Address,
+		Provider:  l.Provider,
+		VenueID:   l.VenueID,
+		VenueType: l.VenueType,
+	}
+}
+
+// stickerMedia looks up the sticker document in its set by emoji or index
+func (sm *SessionManager) stickerMedia(ctx context.Context, api *tg.Client, s *models.StickerPayload) (tg.InputMediaClass, error) {
+	result, err := api.MessagesGetStickerSet(ctx, &tg.MessagesGetStickerSetRequest{
+		Stickerset: &tg.InputStickerSetShortName{ShortName: s.SetName},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sticker set %s: %w", s.SetName, err)
+	}
+
+	set, ok := result.(*tg.MessagesStickerSet)
+	if !ok {
+		return nil, fmt.Errorf("sticker set %s not available", s.SetName)
+	}
+
+	docs := make(map[int64]*tg.Document, len(set.Documents))
+	ordered := make([]*tg.Document, 0, len(set.Documents))
+	for _, d := range set.Documents {
+		if doc, ok := d.(*tg.Document); ok {
+			docs[doc.ID] = doc
+			ordered = append(ordered, doc)
+		}
+	}
+
+	var doc *tg.Document
+	if s.Emoji != "" {
+		for _, pack := range set.Packs {
+			if pack.Emoticon == s.Emoji && len(pack.Documents) > 0 {
+				doc = docs[pack.Documents[0]]
+				break
+			}
+		}
+		if doc == nil {
+			return nil, fmt.Errorf("no sticker for %s in set %s", s.Emoji, s.SetName)
+		}
+	} else {
+		if s.Index >= len(ordered) {
+			return nil, fmt.Errorf("sticker index %d out of range for set %s", s.Index, s.SetName)
+		}
+		doc = ordered[s.Index]
+	}
+
+	return &tg.InputMediaDocument{
+		ID: &tg.InputDocument{
+			ID:            doc.ID,
+			AccessHash:    doc.AccessHash,
+			FileReference: doc.FileReference,
+		},
+	}, nil
+}
+
+// ForwardOptions controls how ForwardMessage and ResendMessage copy the source message
+type ForwardOptions struct {
+	DropAuthor        bool // Hide the "Forwarded from" header
+	DropMediaCaptions bool // Strip captions from forwarded media
+	Album             bool // Include every message of the source album
+}
+
+// ForwardMessage forwards a message from one chat to another and returns the IDs of the new messages
+func (sm *SessionManager) ForwardMessage(ctx context.Context, phone, toChatID, fromChatID string, messageID int, opts ForwardOptions, sendOpts SendOptions) ([]int, error) {
 	client, err := sm.GetClient(phone)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return client.Run(ctx, func(ctx context.Context) error {
+	var sentIDs []int
+	err = client.Run(ctx, func(ctx context.Context) error {
 		api := client.API()
 
 		// Resolve destination peer
@@ -414,15 +1019,350 @@ func (sm *SessionManager) ForwardMessage(ctx context.Context, phone, toChatID, f
 			return fmt.Errorf("failed to resolve source peer: %w", err)
 		}
 
+		ids := []int{messageID}
+		if opts.Album {
+			messages, err := sm.getSourceMessages(ctx, api, fromPeer, messageID, true)
+			if err != nil {
+				return err
+			}
+			ids = ids[:0]
+			for _, msg := range messages {
+				ids = append(ids, msg.ID)
+			}
+		}
+
+		randomIDs := make([]int64, len(ids))
+		for i := range randomIDs {
+			if randomIDs[i], err = sendOpts.randomID(i); err != nil {
+				return err
+			}
+		}
+
 		// Forward message
-		_, err = api.MessagesForwardMessages(ctx, &tg.MessagesForwardMessagesRequest{
-			FromPeer: fromPeer,
-			ToPeer:   toPeer,
-			ID:       []int{messageID},
+		updates, err := api.MessagesForwardMessages(ctx, &tg.MessagesForwardMessagesRequest{
+			FromPeer:          fromPeer,
+			ToPeer:            toPeer,
+			ID:                ids,
+			RandomID:          randomIDs,
+			DropAuthor:        opts.DropAuthor,
+			DropMediaCaptions: opts.DropMediaCaptions,
+			ScheduleDate:      sendOpts.scheduleDate(),
 		})
+		if err != nil {
+			return err
+		}
 
-		return err
+		sentIDs = sentMessageIDs(updates)
+		return nil
 	})
+
+	return sentIDs, err
+}
+
+// ResendMessage sends the text, entities and media of a source message as a new message
+func (sm *SessionManager) ResendMessage(ctx context.Context, phone, toChatID, fromChatID string, messageID int, opts ForwardOptions, sendOpts SendOptions) ([]int, error) {
+	client, err := sm.GetClient(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	var sentIDs []int
+	err = client.Run(ctx, func(ctx context.Context) error {
+		api := client.API()
+
+		toPeer, err := sm.resolvePeer(ctx, api, toChatID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve destination peer: %w", err)
+		}
+
+		fromPeer, err := sm.resolvePeer(ctx, api, fromChatID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve source peer: %w", err)
+		}
+
+		messages, err := sm.getSourceMessages(ctx, api, fromPeer, messageID, opts.Album)
+		if err != nil {
+			return err
+		}
+
+		// A single message goes out as plain text or one media item
+		if len(messages) == 1 {
+			msg := messages[0]
+			sourceMedia := msg.Media
+			if isTextMedia(sourceMedia) {
+				sourceMedia = nil
+			}
+
+			text, entities := msg.Message, msg.Entities
+			if opts.DropMediaCaptions && sourceMedia != nil {
+				text, entities = "", nil
+			}
+
+			id, err := sendOpts.randomID(0)
+			if err != nil {
+				return err
+			}
+
+			if sourceMedia == nil {
+				updates, err := api.MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
+					Peer:         toPeer,
+					Message:      text,
+					Entities:     entities,
+					RandomID:     id,
+					ScheduleDate: sendOpts.scheduleDate(),
+				})
+				if err != nil {
+					return err
+				}
+
+				sentIDs = sentMessageIDs(updates)
+				return nil
+			}
+
+			media, err := inputMediaFromMessage(sourceMedia)
+			if err != nil {
+				return err
+			}
+
+			updates, err := api.MessagesSendMedia(ctx, &tg.MessagesSendMediaRequest{
+				Peer:         toPeer,
+				Media:        media,
+				Message:      text,
+				Entities:     entities,
+				RandomID:     id,
+				ScheduleDate: sendOpts.scheduleDate(),
+			})
+			if err != nil {
+				return err
+			}
+
+			sentIDs = sentMessageIDs(updates)
+			return nil
+		}
+
+		multiMedia := make([]tg.InputSingleMedia, 0, len(messages))
+		for i, msg := range messages {
+			media, err := inputMediaFromMessage(msg.Media)
+			if err != nil {
+				return err
+			}
+
+			id, err := sendOpts.randomID(i)
+			if err != nil {
+				return err
+			}
+
+			item := tg.InputSingleMedia{Media: media, RandomID: id}
+			if !opts.DropMediaCaptions {
+				item.Message = msg.Message
+				item.Entities = msg.Entities
+			}
+			multiMedia = append(multiMedia, item)
+		}
+
+		updates, err := api.MessagesSendMultiMedia(ctx, &tg.MessagesSendMultiMediaRequest{
+			Peer:         toPeer,
+			MultiMedia:   multiMedia,
+			ScheduleDate: sendOpts.scheduleDate(),
+		})
+		if err != nil {
+			return err
+		}
+
+		sentIDs = sentMessageIDs(updates)
+		return nil
+	})
+
+	return sentIDs, err
+}
+
+// albumSearchWindow is how many IDs around the source message are scanned for album siblings.
+// Telegram limits albums to 10 items, so 9 on either side always covers the whole group.
+const albumSearchWindow = 9
+
+// getSourceMessages loads the source message and, if album is set, the rest of its media group
+func (sm *SessionManager) getSourceMessages(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, messageID int, album bool) ([]*tg.Message, error) {
+	messages, err := sm.getMessages(ctx, api, peer, []int{messageID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load source message: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("source message %d not found", messageID)
+	}
+
+	source := messages[0]
+	if !album || source.GroupedID == 0 {
+		return messages, nil
+	}
+
+	ids := make([]int, 0, albumSearchWindow*2+1)
+	for id := messageID - albumSearchWindow; id <= messageID+albumSearchWindow; id++ {
+		if id > 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	candidates, err := sm.getMessages(ctx, api, peer, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load album messages: %w", err)
+	}
+
+	group := make([]*tg.Message, 0, len(candidates))
+	for _, msg := range candidates {
+		if msg.GroupedID == source.GroupedID {
+			group = append(group, msg)
+		}
+	}
+	sort.Slice(group, func(i, j int) bool { return group[i].ID < group[j].ID })
+
+	return group, nil
+}
+
+// getMessages fetches messages by ID, using the channel API for channel peers
+func (sm *SessionManager) getMessages(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, ids []int) ([]*tg.Message, error) {
+	inputIDs := make([]tg.InputMessageClass, 0, len(ids))
+	for _, id := range ids {
+		inputIDs = append(inputIDs, &tg.InputMessageID{ID: id})
+	}
+
+	var (
+		result tg.MessagesMessagesClass
+		err    error
+	)
+	if channel, ok := peer.(*tg.InputPeerChannel); ok {
+		result, err = api.ChannelsGetMessages(ctx, &tg.ChannelsGetMessagesRequest{
+			Channel: &tg.InputChannel{ChannelID: channel.ChannelID, AccessHash: channel.AccessHash},
+			ID:      inputIDs,
+		})
+	} else {
+		result, err = api.MessagesGetMessages(ctx, inputIDs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	modified, ok := result.AsModified()
+	if !ok {
+		return nil, fmt.Errorf("unexpected messages response %T", result)
+	}
+
+	messages := make([]*tg.Message, 0, len(ids))
+	for _, m := range modified.GetMessages() {
+		if msg, ok := m.(*tg.Message); ok {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+// isTextMedia reports whether media carries nothing beyond the message text, such as a
+// link preview Telegram builds again by itself when the text is sent
+func isTextMedia(media tg.MessageMediaClass) bool {
+	switch media.(type) {
+	case *tg.MessageMediaWebPage, *tg.MessageMediaEmpty:
+		return true
+	default:
+		return false
+	}
+}
+
+// inputMediaFromMessage converts media of a received message into media that can be sent again
+func inputMediaFromMessage(media tg.MessageMediaClass) (tg.InputMediaClass, error) {
+	switch m := media.(type) {
+	case *tg.MessageMediaPhoto:
+		photo, ok := m.Photo.(*tg.Photo)
+		if !ok {
+			return nil, fmt.Errorf("source photo is empty")
+		}
+		return &tg.InputMediaPhoto{
+			Spoiler: m.Spoiler,
+			ID: &tg.InputPhoto{
+				ID:            photo.ID,
+				AccessHash:    photo.AccessHash,
+				FileReference: photo.FileReference,
+			},
+		}, nil
+	case *tg.MessageMediaDocument:
+		doc, ok := m.Document.(*tg.Document)
+		if !ok {
+			return nil, fmt.Errorf("source document is empty")
+		}
+		return &tg.InputMediaDocument{
+			Spoiler: m.Spoiler,
+			ID: &tg.InputDocument{
+				ID:            doc.ID,
+				AccessHash:    doc.AccessHash,
+				FileReference: doc.FileReference,
+			},
+		}, nil
+	case *tg.MessageMediaGeo:
+		point, ok := m.Geo.(*tg.GeoPoint)
+		if !ok {
+			return nil, fmt.Errorf("source location is empty")
+		}
+		return &tg.InputMediaGeoPoint{
+			GeoPoint: &tg.InputGeoPoint{Lat: point.Lat, Long: point.Long},
+		}, nil
+	case *tg.MessageMediaVenue:
+		point, ok := m.Geo.(*tg.GeoPoint)
+		if !ok {
+			return nil, fmt.Errorf("source venue location is empty")
+		}
+		return &tg.InputMediaVenue{
+			GeoPoint:  &tg.InputGeoPoint{Lat: point.Lat, Long: point.Long},
+			Title:     m.Title,
+			Address:   m.Address,
+			Provider:  m.Provider,
+			VenueID:   m.VenueID,
+			VenueType: m.VenueType,
+		}, nil
+	case *tg.MessageMediaContact:
+		return &tg.InputMediaContact{
+			PhoneNumber: m.PhoneNumber,
+			FirstName:   m.FirstName,
+			LastName:    m.LastName,
+			Vcard:       m.Vcard,
+		}, nil
+	case *tg.MessageMediaPoll:
+		return inputPollFromMessage(m)
+	default:
+		return nil, fmt.Errorf("unsupported media type %T", media)
+	}
+}
+
+// inputPollFromMessage rebuilds a received poll as a new open poll. A quiz can only be
+// resent when the account sees its correct answer.
+func inputPollFromMessage(m *tg.MessageMediaPoll) (tg.InputMediaClass, error) {
+	poll := m.Poll
+	poll.ID = 0
+	poll.Closed = false
+	poll.CloseDate = 0
+
+	input := &tg.InputMediaPoll{Poll: poll}
+	if !poll.Quiz {
+		return input, nil
+	}
+
+	for _, result := range m.Results.Results {
+		if result.Correct {
+			input.CorrectAnswers = append(input.CorrectAnswers, result.Option)
+		}
+	}
+	if len(input.CorrectAnswers) == 0 {
+		return nil, fmt.Errorf("correct answer of the source quiz is not visible to the account")
+	}
+	input.Solution = m.Results.Solution
+	input.SolutionEntities = m.Results.SolutionEntities
+	return input, nil
+}
+
+// randomID generates the random_id Telegram requires for every sent message
+func randomID() (int64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(rand.Reader, buf[:]); err != nil {
+		return 0, fmt.Errorf("failed to generate random id: %w", err)
+	}
+	return int64(binary.LittleEndian.Uint64(buf[:])), nil
 }
 
 // Close closes all clients
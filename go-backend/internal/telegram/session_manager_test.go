@@ -0,0 +1,125 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GezzyDax/timelith/go-backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/gotd/td/tg"
+)
+
+func TestIsTextMedia(t *testing.T) {
+	if !isTextMedia(&tg.MessageMediaWebPage{}) {
+		t.Error("link preview should be sent as plain text")
+	}
+	if isTextMedia(&tg.MessageMediaPhoto{}) {
+		t.Error("photo should not be sent as plain text")
+	}
+}
+
+func TestInputMediaFromMessage(t *testing.T) {
+	venue, err := inputMediaFromMessage(&tg.MessageMediaVenue{
+		Geo:   &tg.GeoPoint{Lat: 52.52, Long: 13.4},
+		Title: "Alexanderplatz",
+	})
+	if err != nil {
+		t.Fatalf("venue: %v", err)
+	}
+	if v, ok := venue.(*tg.InputMediaVenue); !ok || v.Title != "Alexanderplatz" {
+		t.Errorf("venue: got %#v", venue)
+	}
+
+	contact, err := inputMediaFromMessage(&tg.MessageMediaContact{PhoneNumber: "+100", FirstName: "Ann"})
+	if err != nil {
+		t.Fatalf("contact: %v", err)
+	}
+	if c, ok := contact.(*tg.InputMediaContact); !ok || c.PhoneNumber != "+100" {
+		t.Errorf("contact: got %#v", contact)
+	}
+
+	poll, err := inputMediaFromMessage(&tg.MessageMediaPoll{
+		Poll: tg.Poll{ID: 7, Closed: true, CloseDate: 1, Question: "Q", Answers: []tg.PollAnswer{
+			{Text: "A", Option: []byte{0}},
+			{Text: "B", Option: []byte{1}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if p, ok := poll.(*tg.InputMediaPoll); !ok || p.Poll.ID != 0 || p.Poll.Closed || p.Poll.CloseDate != 0 {
+		t.Errorf("poll should be resent as a new open poll, got %#v", poll)
+	}
+
+	_, err = inputMediaFromMessage(&tg.MessageMediaPoll{Poll: tg.Poll{Quiz: true, Question: "Q"}})
+	if err == nil {
+		t.Error("quiz without a visible correct answer should not be resent")
+	}
+
+	_, err = inputMediaFromMessage(&tg.MessageMediaDice{})
+	if err == nil || !strings.Contains(err.Error(), "MessageMediaDice") {
+		t.Errorf("unsupported media should name its type, got %v", err)
+	}
+}
+
+func TestAccountResolverUsesPoolProxy(t *testing.T) {
+	account := &models.Account{ID: uuid.New()}
+	sm := &SessionManager{}
+
+	resolver, err := sm.accountResolver(account)
+	if err != nil || resolver != nil {
+		t.Fatalf("account without any proxy should connect directly, got %v, %v", resolver, err)
+	}
+
+	var looked uuid.UUID
+	sm.SetPoolProxyLookup(func(accountID uuid.UUID) (*models.AccountPool, error) {
+		looked = accountID
+		return &models.AccountPool{
+			ProxyEnabled: true,
+			ProxyHost:    models.NewNullString("127.0.0.1"),
+			ProxyPort:    models.NewNullInt64(1080),
+		}, nil
+	})
+	resolver, err = sm.accountResolver(account)
+	if err != nil || resolver == nil {
+		t.Fatalf("account should connect through its pool's proxy, got %v, %v", resolver, err)
+	}
+	if looked != account.ID {
+		t.Errorf("looked up pool proxy of %s, want %s", looked, account.ID)
+	}
+}
+
+func TestPollMediaDefaultsAndClosePeriod(t *testing.T) {
+	payload := &models.PollPayload{Question: "Q", Options: []string{"A", "B"}, ClosePeriod: 3600}
+	if err := (&models.MediaPayload{Poll: payload}).Validate("poll"); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	for _, postAt := range []time.Time{
+		time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+	} {
+		media, err := pollMedia(payload, postAt)
+		if err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+		poll := media.(*tg.InputMediaPoll).Poll
+		if poll.PublicVoters {
+			t.Error("poll should be anonymous by default")
+		}
+		if want := int(postAt.Add(time.Hour).Unix()); poll.CloseDate != want {
+			t.Errorf("close date = %d, want %d", poll.CloseDate, want)
+		}
+	}
+
+	public := false
+	payload.Anonymous = &public
+	media, err := pollMedia(payload, time.Now())
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if !media.(*tg.InputMediaPoll).Poll.PublicVoters {
+		t.Error("poll with anonymous false should show its voters")
+	}
+}